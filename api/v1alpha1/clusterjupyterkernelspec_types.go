@@ -0,0 +1,53 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+
+// https://opensource.org/licenses/Apache-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Language",type=string,JSONPath=`.spec.language`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterJupyterKernelSpec is the Schema for the clusterjupyterkernelspecs API.
+// It is the cluster-scoped variant of JupyterKernelSpec, which can be
+// referenced by JupyterGateways in any namespace.
+type ClusterJupyterKernelSpec struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JupyterKernelSpecSpec   `json:"spec,omitempty"`
+	Status JupyterKernelSpecStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterJupyterKernelSpecList contains a list of ClusterJupyterKernelSpec
+type ClusterJupyterKernelSpecList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterJupyterKernelSpec `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterJupyterKernelSpec{}, &ClusterJupyterKernelSpecList{})
+}
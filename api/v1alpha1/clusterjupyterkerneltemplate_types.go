@@ -0,0 +1,54 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+
+// https://opensource.org/licenses/Apache-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClusterJupyterKernelTemplateKind is the kind of ClusterJupyterKernelTemplate.
+	// Kernel specs use it in spec.template.kind to reference a cluster-scoped template.
+	ClusterJupyterKernelTemplateKind = "ClusterJupyterKernelTemplate"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+
+// ClusterJupyterKernelTemplate is the Schema for the clusterjupyterkerneltemplates API.
+// It is the cluster-scoped variant of JupyterKernelTemplate.
+type ClusterJupyterKernelTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JupyterKernelTemplateSpec   `json:"spec,omitempty"`
+	Status JupyterKernelTemplateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterJupyterKernelTemplateList contains a list of ClusterJupyterKernelTemplate
+type ClusterJupyterKernelTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterJupyterKernelTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterJupyterKernelTemplate{}, &ClusterJupyterKernelTemplateList{})
+}
@@ -27,6 +27,9 @@ type JupyterGatewaySpec struct {
 	// Knernels defines the kernels in the gateway.
 	// We will add kernels at runtime, thus we do not make it a type.
 	Kernels []string `json:"kernels,omitempty"`
+	// ClusterKernels defines the cluster-scoped kernels (ClusterJupyterKernelSpec)
+	// in the gateway.
+	ClusterKernels []string `json:"clusterKernels,omitempty"`
 	// DefaultKernel defines the default kernel in the gateway.
 	DefaultKernel *string `json:"defaultKernel,omitempty"`
 	// Timeout (in seconds) after which a kernel is considered idle and
@@ -54,8 +57,40 @@ type JupyterGatewaySpec struct {
 	// +optional
 	Image string `json:"image,omitempty"`
 
-	// ClusterRole for the gateway, which is used to create the kernel pods in the cluster. Defaults to enterprise-gateway-controller (created at startup).
+	// ClusterRole for the gateway, which is used to create the kernel pods in the cluster. Defaults to enterprise-gateway-controller (created at startup), or gatewayClusterRole in the operator configuration.
 	ClusterRole *string `json:"clusterRole,omitempty"`
+
+	// The duration in seconds to wait for the kernels to shut down through
+	// the gateway when the gateway is deleted. The remaining kernels are
+	// deleted after it. Defaults to 30.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KernelShutdownTimeoutSeconds *int32 `json:"kernelShutdownTimeoutSeconds,omitempty"`
+
+	// Periodic probe of the gateway liveness.
+	// This field defaults to HTTP GET /api on the gateway port.
+	// +optional
+	LivenessProbe *v1.Probe `json:"livenessProbe,omitempty"`
+
+	// Periodic probe of the gateway readiness. The gateway is removed from
+	// the service endpoints if the probe fails.
+	// This field defaults to HTTP GET /api on the gateway port.
+	// +optional
+	ReadinessProbe *v1.Probe `json:"readinessProbe,omitempty"`
+
+	// KernelEnvAllowlist is the names of the env vars which the clients
+	// can set for the kernels, besides the ones starting with KERNEL_.
+	// They are set in the kernel container by the kernel launcher. The
+	// reserved names are ignored, i.e. PATH, HOME, HOSTNAME and the ones
+	// starting with EG_, KERNEL_ or KUBERNETES_.
+	// +optional
+	KernelEnvAllowlist []string `json:"kernelEnvAllowlist,omitempty"`
+
+	// MirrorWorkingDirs passes the working directories of the notebooks
+	// to the kernels in KERNEL_WORKING_DIR, which is required to mount
+	// the workspaces of the notebooks into the kernels.
+	// +optional
+	MirrorWorkingDirs bool `json:"mirrorWorkingDirs,omitempty"`
 }
 
 type LogLevel string
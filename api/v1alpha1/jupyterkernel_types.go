@@ -24,6 +24,43 @@ import (
 // JupyterKernelSpec defines the desired state of JupyterKernel
 type JupyterKernelCRDSpec struct {
 	Template v1.PodTemplateSpec `json:"template,omitempty"`
+
+	// Spark is copied from the kernel template. The operator creates the
+	// driver service and the Spark configuration if it is set.
+	// +optional
+	Spark *SparkTemplate `json:"spark,omitempty"`
+
+	// Cluster is the Dask or Ray cluster attached to the kernel, which is
+	// deleted with the kernel.
+	// +optional
+	Cluster *ComputeClusterTemplate `json:"cluster,omitempty"`
+
+	// ReplicaGroups are the groups of the pods besides the kernel pod,
+	// e.g. the workers of the distributed kernel. The kernel pod and the
+	// pods of the groups are scheduled together as a gang, and the kernel
+	// is running only when all of them are running.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ReplicaGroups []KernelReplicaGroup `json:"replicaGroups,omitempty"`
+
+	// PriorityTier is copied from the kernel template.
+	// +optional
+	PriorityTier KernelPriorityTier `json:"priorityTier,omitempty"`
+
+	// Resources overrides the resources of the kernel container in the
+	// template. The kernel is resized when it changes.
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// ResizePolicy is copied from the kernel template.
+	// +optional
+	ResizePolicy KernelResizePolicy `json:"resizePolicy,omitempty"`
+
+	// KernelLifetime is copied from the kernel template. The defaults of
+	// the namespace in the operator configuration are used if it is not
+	// set.
+	KernelLifetime `json:",inline"`
 }
 
 // JupyterKernelStatus defines the observed state of JupyterKernel
@@ -45,6 +82,31 @@ type JupyterKernelStatus struct {
 	// be set in happens-before order across separate operations.
 	// It is represented in RFC3339 form and is in UTC.
 	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// QueuePosition is the 1-based position of the kernel in the queue
+	// when the kernel is queued.
+	// +optional
+	QueuePosition *int32 `json:"queuePosition,omitempty"`
+
+	// Resources are the resources of the kernel container in the kernel
+	// pod, which differ from the spec until the kernel is resized.
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// PeakUsage is the peak CPU and memory usage of the kernel container
+	// observed by the recommender.
+	// +optional
+	PeakUsage v1.ResourceList `json:"peakUsage,omitempty"`
+
+	// ReplicaGroups is the readiness of the groups of the gang, including
+	// the kernel pod as the driver group, if the kernel has replica groups.
+	// +optional
+	ReplicaGroups []KernelReplicaGroupStatus `json:"replicaGroups,omitempty"`
+
+	// Preemption records the preemption of the kernel for the kernel with
+	// a higher priority.
+	// +optional
+	Preemption *KernelPreemption `json:"preemption,omitempty"`
 }
 
 type JupyterKernelCondition struct {
@@ -62,12 +124,40 @@ type JupyterKernelCondition struct {
 	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
+// KernelReplicaGroupStatus is the readiness of the replica group.
+type KernelReplicaGroupStatus struct {
+	// Name is the name of the group.
+	Name string `json:"name"`
+	// Replicas is the desired number of the pods.
+	Replicas int32 `json:"replicas"`
+	// Ready is the number of the running pods.
+	Ready int32 `json:"ready"`
+}
+
+// KernelPreemption is the preemption of the kernel.
+type KernelPreemption struct {
+	// Preemptor is the kernel, in the form of <namespace>/<name>, for
+	// which the kernel is preempted.
+	Preemptor string `json:"preemptor"`
+	// Time is the time when the kernel is preempted.
+	Time metav1.Time `json:"time"`
+}
+
 type JupyterKernelConditionType string
 
 const (
 	JupyterKernelRunning   JupyterKernelConditionType = "Running"
 	JupyterKernelFailed    JupyterKernelConditionType = "Failed"
 	JupyterKernelSucceeded JupyterKernelConditionType = "Succeeded"
+	// JupyterKernelQueued is true if the kernel waits in the queue to be
+	// admitted, and false once it is admitted.
+	JupyterKernelQueued JupyterKernelConditionType = "Queued"
+	// JupyterKernelRestartRequired is true if the kernel pod cannot be
+	// resized in place, and the resize policy does not allow the restart.
+	JupyterKernelRestartRequired JupyterKernelConditionType = "RestartRequired"
+	// JupyterKernelResizing is true while the kernel pod is being resized
+	// in place, with the reason InProgress, Deferred or Infeasible.
+	JupyterKernelResizing JupyterKernelConditionType = "Resizing"
 )
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,99 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+
+// https://opensource.org/licenses/Apache-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JupyterKernelQuotaSpec defines the limits of the kernels in the
+// namespace. The kernels which exceed any limit are rejected when they are
+// created. The kernels are not limited per user, since the user name of a
+// kernel (KERNEL_USERNAME) is set by the client and cannot be trusted. The
+// kernels of different users are limited separately only if the users have
+// their own namespaces.
+type JupyterKernelQuotaSpec struct {
+	// Hard is the limits of all the kernels in the namespace.
+	// +optional
+	Hard *KernelQuotaLimits `json:"hard,omitempty"`
+	// KernelSpecs are the limits of the kernels of the kernel specs, keyed
+	// by the name of the kernel spec (KERNEL_NAME).
+	// +optional
+	KernelSpecs map[string]KernelQuotaLimits `json:"kernelSpecs,omitempty"`
+}
+
+// KernelQuotaLimits defines the limits of a group of kernels.
+type KernelQuotaLimits struct {
+	// Kernels is the maximum number of the concurrent kernels.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Kernels *int32 `json:"kernels,omitempty"`
+	// Resources are the maximum total resources requested by the kernels,
+	// e.g. cpu, memory and nvidia.com/gpu. The limits of the containers
+	// are used if the requests are not set.
+	// +optional
+	Resources v1.ResourceList `json:"resources,omitempty"`
+}
+
+// JupyterKernelQuotaStatus defines the observed usage of the quota.
+type JupyterKernelQuotaStatus struct {
+	// Used is the usage of all the kernels in the namespace.
+	// +optional
+	Used *KernelQuotaUsage `json:"used,omitempty"`
+	// KernelSpecs is the usage of the kernels of the kernel specs in the
+	// quota.
+	// +optional
+	KernelSpecs map[string]KernelQuotaUsage `json:"kernelSpecs,omitempty"`
+}
+
+// KernelQuotaUsage is the usage of a group of kernels.
+type KernelQuotaUsage struct {
+	// Kernels is the number of the kernels.
+	Kernels int32 `json:"kernels"`
+	// Resources are the total resources requested by the kernels.
+	// +optional
+	Resources v1.ResourceList `json:"resources,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kernels",type=integer,JSONPath=`.status.used.kernels`
+// +kubebuilder:printcolumn:name="Limit",type=integer,JSONPath=`.spec.hard.kernels`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// JupyterKernelQuota is the Schema for the jupyterkernelquotas API
+type JupyterKernelQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JupyterKernelQuotaSpec   `json:"spec,omitempty"`
+	Status JupyterKernelQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JupyterKernelQuotaList contains a list of JupyterKernelQuota
+type JupyterKernelQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JupyterKernelQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JupyterKernelQuota{}, &JupyterKernelQuotaList{})
+}
@@ -19,29 +19,195 @@ package v1alpha1
 import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // JupyterKernelSpecSpec defines the desired state of JupyterKernelSpec
 type JupyterKernelSpecSpec struct {
-	Language    string      `json:"language,omitempty"`
-	DisplayName string      `json:"displayName,omitempty"`
-	Image       string      `json:"image,omitempty"`
-	Env         []v1.EnvVar `json:"env,omitempty"`
-	Command     []string    `json:"command,omitempty"`
-	ClassName   string      `json:"className,omitempty"`
+	Language    string `json:"language,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// Env is rendered into the env of the kernel.json. Only the value is
+	// supported, and the kernel spec with valueFrom is not rendered.
+	Env       []v1.EnvVar `json:"env,omitempty"`
+	Command   []string    `json:"command,omitempty"`
+	ClassName string      `json:"className,omitempty"`
 
+	// ProcessProxy is the process proxy which launches the kernel, one of
+	// Kubernetes, SparkOperator and CustomResource. Defaults to Kubernetes.
+	// ClassName overrides the class of the process proxy, e.g. to use a
+	// subclass of CustomResourceProcessProxy.
+	// +kubebuilder:validation:Enum=Kubernetes;SparkOperator;CustomResource
+	// +optional
+	ProcessProxy ProcessProxyType `json:"processProxy,omitempty"`
+	// CustomResource is the custom resource created by the process proxy
+	// to launch the kernel. It is required by the CustomResource process
+	// proxy, and defaults to the SparkApplication of the Spark operator for
+	// the SparkOperator process proxy. The gateway is bound to the
+	// jupytergateway-process-proxy ClusterRole, which should grant the
+	// permission to manage the custom resource.
+	// +optional
+	CustomResource *KernelCustomResource `json:"customResource,omitempty"`
+
+	// InterruptMode is the interrupt mode of the kernel, either signal or message.
+	// Ref https://jupyter-client.readthedocs.io/en/stable/kernels.html#kernel-specs
+	// +kubebuilder:validation:Enum=signal;message
+	// +optional
+	InterruptMode InterruptMode `json:"interruptMode,omitempty"`
+	// Debugger indicates whether the kernel supports the debugger protocol.
+	// It is rendered into metadata.debugger of the kernel.json.
+	// +optional
+	Debugger *bool `json:"debugger,omitempty"`
+	// ProcessProxyConfig is merged into metadata.process_proxy.config of the
+	// kernel.json, e.g. executor_image_name or port_range.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	ProcessProxyConfig *runtime.RawExtension `json:"processProxyConfig,omitempty"`
+	// Metadata is the free-form metadata of the kernel.json. The process_proxy
+	// and debugger keys are overridden by the fields above.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Metadata *runtime.RawExtension `json:"metadata,omitempty"`
+
+	// ResourceFiles are the files placed next to the kernel.json in the
+	// kernel directory, e.g. the logos and kernel.js.
+	// +optional
+	ResourceFiles *KernelResourceFiles `json:"resourceFiles,omitempty"`
+
+	// Template is the reference to the JupyterKernelTemplate. Set the kind to
+	// ClusterJupyterKernelTemplate to reference a cluster-scoped template.
 	Template *v1.ObjectReference `json:"template,omitempty"`
-	// TODO(gaocegege): Support resources and so on.
+
+	// PodTemplate is the inline kernel pod template. The operator creates a
+	// kernel template owned by the kernel spec from it (and the shortcut
+	// fields below), which is used by the kernels. It cannot be used
+	// together with Template. It is not validated in the kernel spec to
+	// keep the CRD small, but when the kernel template is created.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplate *v1.PodTemplateSpec `json:"podTemplate,omitempty"`
+	// Resources are the compute resources of the kernel container.
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector is merged into the node selector of the kernel pod.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are appended to the tolerations of the kernel pod.
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// Volumes are appended to the volumes of the kernel pod.
+	// +optional
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are appended to the volume mounts of the kernel container.
+	// +optional
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+	// ServiceAccountName is the service account of the kernel pod.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// KernelResourceFiles defines the resource files of the kernel, which are
+// shown by the launcher of JupyterLab.
+// Ref https://jupyter-client.readthedocs.io/en/stable/kernels.html#kernel-specs
+type KernelResourceFiles struct {
+	// Logo32x32 is the content of logo-32x32.png.
+	// +optional
+	Logo32x32 []byte `json:"logo32x32,omitempty"`
+	// Logo64x64 is the content of logo-64x64.png.
+	// +optional
+	Logo64x64 []byte `json:"logo64x64,omitempty"`
+	// LogoSVG is the content of logo-svg.svg.
+	// +optional
+	LogoSVG []byte `json:"logoSVG,omitempty"`
+	// BinaryData contains the extra files keyed by the file name, e.g. kernel.js.
+	// +optional
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+	// ConfigMap is the reference to an existing configmap whose keys are
+	// mounted as files in the kernel directory. It is looked up in the
+	// namespace of the gateway, and must not contain kernel.json or the
+	// inline resource files.
+	// +optional
+	ConfigMap *v1.LocalObjectReference `json:"configMap,omitempty"`
+}
+
+// KernelCustomResource is the custom resource created by the process proxy.
+type KernelCustomResource struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Plural  string `json:"plural"`
 }
 
+type ProcessProxyType string
+
+const (
+	// ProcessProxyKubernetes launches the kernel as a pod.
+	ProcessProxyKubernetes ProcessProxyType = "Kubernetes"
+	// ProcessProxySparkOperator launches the kernel as a SparkApplication
+	// of the Spark operator.
+	ProcessProxySparkOperator ProcessProxyType = "SparkOperator"
+	// ProcessProxyCustomResource launches the kernel as a custom resource.
+	ProcessProxyCustomResource ProcessProxyType = "CustomResource"
+)
+
+type InterruptMode string
+
+const (
+	InterruptModeSignal  InterruptMode = "signal"
+	InterruptModeMessage InterruptMode = "message"
+)
+
 // JupyterKernelSpecStatus defines the observed state of JupyterKernelSpec
 type JupyterKernelSpecStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the most recent generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ConfigMapHash is the hash of the content of the rendered configmap.
+	// +optional
+	ConfigMapHash string `json:"configMapHash,omitempty"`
+	// Template is the kernel template used by the kernel spec.
+	// +optional
+	Template *v1.ObjectReference `json:"template,omitempty"`
+	// Gateways are the gateways (in the form of namespace/name) which
+	// include the kernel spec.
+	// +optional
+	Gateways []string `json:"gateways,omitempty"`
+	// Conditions is an array of current observed kernel spec conditions.
+	// +optional
+	Conditions []JupyterKernelSpecCondition `json:"conditions,omitempty"`
 }
 
+type JupyterKernelSpecCondition struct {
+	// Type of kernel spec condition.
+	Type JupyterKernelSpecConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+type JupyterKernelSpecConditionType string
+
+const (
+	// JupyterKernelSpecReady means the configmap is current and the template is valid.
+	JupyterKernelSpecReady JupyterKernelSpecConditionType = "Ready"
+	// JupyterKernelSpecConfigMapCurrent means the rendered configmap is up to date with the spec.
+	JupyterKernelSpecConfigMapCurrent JupyterKernelSpecConditionType = "ConfigMapCurrent"
+	// JupyterKernelSpecTemplateValid means the referenced kernel template exists and is valid.
+	JupyterKernelSpecTemplateValid JupyterKernelSpecConditionType = "TemplateValid"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Language",type=string,JSONPath=`.spec.language`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // JupyterKernelSpec is the Schema for the jupyterkernelspecs API
 type JupyterKernelSpec struct {
@@ -17,19 +17,353 @@
 package v1alpha1
 
 import (
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// JupyterKernelTemplateKind is the kind of JupyterKernelTemplate.
+	JupyterKernelTemplateKind = "JupyterKernelTemplate"
+)
+
 // JupyterKernelTemplateSpec defines the desired state of JupyterKernelTemplate
 type JupyterKernelTemplateSpec struct {
 	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+
+	// Spark configures the kernel as a Spark driver in client mode. The
+	// executors are launched by the kernel on Kubernetes.
+	// +optional
+	Spark *SparkTemplate `json:"spark,omitempty"`
+
+	// Accelerator requests the accelerators, e.g. GPUs, for the kernel
+	// container.
+	// +optional
+	Accelerator *Accelerator `json:"accelerator,omitempty"`
+
+	// Identity runs the kernel as the identity of the notebook which
+	// launches it.
+	// +optional
+	Identity *KernelIdentity `json:"identity,omitempty"`
+
+	// Volumes is the catalog of the volumes which the users can mount into
+	// the kernel container by the names in KERNEL_VOLUMES.
+	// +optional
+	Volumes []KernelVolume `json:"volumes,omitempty"`
+
+	// Cluster attaches a Dask or Ray cluster to each kernel launched from
+	// the template.
+	// +optional
+	Cluster *ComputeClusterTemplate `json:"cluster,omitempty"`
+
+	// ReplicaGroups are copied to the kernels launched from the template.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ReplicaGroups []KernelReplicaGroup `json:"replicaGroups,omitempty"`
+
+	// PriorityTier is the priority tier of the kernels launched from the
+	// template, which is mapped to a PriorityClass by the operator. It is
+	// ignored if the pod template sets the priority class.
+	// +optional
+	PriorityTier KernelPriorityTier `json:"priorityTier,omitempty"`
+
+	// ResizePolicy is the policy to resize the kernels launched from the
+	// template when their resources change. Defaults to InPlace.
+	// +optional
+	ResizePolicy KernelResizePolicy `json:"resizePolicy,omitempty"`
+
+	// ResourcePolicy controls how the recommended resources of the kernels
+	// launched from the template are applied.
+	// +optional
+	ResourcePolicy *KernelResourcePolicy `json:"resourcePolicy,omitempty"`
+
+	// KernelLifetime is copied to the kernels launched from the template.
+	KernelLifetime `json:",inline"`
+}
+
+// KernelReplicaGroup is a group of the pods of the distributed kernel
+// besides the kernel pod, e.g. the workers of the kernel as the driver.
+type KernelReplicaGroup struct {
+	// Name is the name of the group, e.g. worker, which is unique in the
+	// groups. The name driver is reserved for the kernel pod.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Replicas is the number of the pods in the group. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Template is the pod template of the group. It is not validated by
+	// the API server to keep the CRD small.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template v1.PodTemplateSpec `json:"template"`
+}
+
+// KernelResizePolicy is the policy to resize the kernels.
+// +kubebuilder:validation:Enum=InPlace;Restart
+type KernelResizePolicy string
+
+const (
+	// KernelResizeInPlace only resizes the kernel pods in place, and
+	// reports that a restart is required if it is not supported.
+	KernelResizeInPlace KernelResizePolicy = "InPlace"
+	// KernelResizeRestart restarts the kernel pods with the new resources
+	// if they cannot be resized in place.
+	KernelResizeRestart KernelResizePolicy = "Restart"
+)
+
+// KernelPriorityTier is the priority tier of the kernels. The kernels of
+// the lower tiers may be preempted for the kernels of the higher tiers
+// when they are idle.
+// +kubebuilder:validation:Enum=interactive;batch;best-effort
+type KernelPriorityTier string
+
+const (
+	// KernelPriorityInteractive is the highest tier, for the kernels used
+	// by the users interactively.
+	KernelPriorityInteractive KernelPriorityTier = "interactive"
+	// KernelPriorityBatch is the tier of the kernels running the
+	// notebooks in batch.
+	KernelPriorityBatch KernelPriorityTier = "batch"
+	// KernelPriorityBestEffort is the lowest tier.
+	KernelPriorityBestEffort KernelPriorityTier = "best-effort"
+)
+
+// KernelLifetime limits the lifetime of the kernel, besides the idle
+// culling of the gateway. The kernel is deleted by the operator after the
+// deadline, even if it is busy.
+type KernelLifetime struct {
+	// MaxLifetime is the maximum duration of the kernel since it is
+	// created, e.g. 24h.
+	// +optional
+	MaxLifetime *metav1.Duration `json:"maxLifetime,omitempty"`
+	// TTLSecondsAfterFinished is the duration in seconds for which the
+	// kernel is kept after it fails, e.g. CrashLoopBackOff.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// KernelIdentity maps the notebook which launches the kernel to the POSIX
+// identity of the kernel pod. The notebook is verified by its token
+// (KERNEL_NOTEBOOK_TOKEN), while the user name of the kernel
+// (KERNEL_USERNAME) is sent by the client and not used. Either ConfigMap
+// or URL should be set.
+type KernelIdentity struct {
+	// ConfigMap in the namespace of the notebook maps the notebooks to the
+	// identities. The keys are the names of the notebooks and the values
+	// are in the form of <uid>[:<gid>].
+	// +optional
+	ConfigMap *v1.LocalObjectReference `json:"configMap,omitempty"`
+	// URL is the endpoint of the external lookup. The launcher requests
+	// <url>?namespace=<namespace>&notebook=<name> and expects
+	// {"uid": <uid>, "gid": <gid>} in the response, or 404 if the notebook
+	// is not found.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// Required rejects the kernel if it is not launched by a notebook, or
+	// the notebook cannot be mapped, instead of running it as the identity
+	// in the template.
+	// +optional
+	Required bool `json:"required,omitempty"`
+	// ServiceAccountName is the service account of the kernel per
+	// notebook, in which {notebook} is replaced with the name of the
+	// notebook. The service accounts are not created by the operator.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// Accelerator defines the accelerators of the kernel. The number of the
+// accelerators can be overridden by KERNEL_GPUS when the kernel is
+// launched, within the bound of MaxCount.
+type Accelerator struct {
+	// ResourceName is the extended resource of the accelerator, e.g.
+	// nvidia.com/gpu, or the shared resource when time-slicing is enabled.
+	// Defaults to nvidia.com/gpu. It is ignored if MIGProfile is set.
+	// +optional
+	ResourceName v1.ResourceName `json:"resourceName,omitempty"`
+	// Count is the default number of the accelerators. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+	// MaxCount is the maximum number of the accelerators which can be
+	// requested by KERNEL_GPUS. Defaults to Count.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCount *int32 `json:"maxCount,omitempty"`
+	// MIGProfile is the MIG profile of NVIDIA GPUs, e.g. 1g.5gb. The
+	// resource nvidia.com/mig-<profile> is requested if it is set.
+	// +optional
+	MIGProfile string `json:"migProfile,omitempty"`
+	// NodeSelector selects the nodes with the accelerators.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations tolerate the taints of the nodes with the accelerators.
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+}
+
+// KernelVolume is a volume approved by the administrators, which is
+// mounted into the kernel container if the user requests it.
+type KernelVolume struct {
+	// Name is the name requested in KERNEL_VOLUMES, which is also the name
+	// of the volume in the kernel pod.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// MountPath is the path in the kernel container to mount the volume.
+	MountPath string `json:"mountPath"`
+	// ReadOnly mounts the volume read-only.
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// KernelVolumeSource is the source of the volume, in which exactly one
+	// of the fields should be set.
+	KernelVolumeSource `json:",inline"`
+}
+
+// KernelVolumeSource is the source of the kernel volume. The volumes are
+// shared by all the users of the kernel template.
+type KernelVolumeSource struct {
+	// +optional
+	PersistentVolumeClaim *v1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+	// +optional
+	NFS *v1.NFSVolumeSource `json:"nfs,omitempty"`
+	// +optional
+	ConfigMap *v1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+	// CSI is the ephemeral volume of the CSI driver.
+	// +optional
+	CSI *v1.CSIVolumeSource `json:"csi,omitempty"`
+}
+
+// SparkTemplate defines the Spark driver and executors of the kernel.
+type SparkTemplate struct {
+	// ExecutorTemplate is the pod template of the executors, which is
+	// used as spark.kubernetes.executor.podTemplateFile. It is not
+	// validated by the API server to keep the CRD small.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	ExecutorTemplate *v1.PodTemplateSpec `json:"executorTemplate,omitempty"`
+	// Executors is the number of the executors (spark.executor.instances).
+	// +optional
+	Executors *int32 `json:"executors,omitempty"`
+	// Conf is the Spark configuration, e.g. spark.executor.memory. It
+	// overrides the configuration generated by the operator.
+	// +optional
+	Conf map[string]string `json:"conf,omitempty"`
+	// DriverPort is the port of the driver. Defaults to 7078.
+	// +optional
+	DriverPort *int32 `json:"driverPort,omitempty"`
+	// BlockManagerPort is the port of the block manager in the driver.
+	// Defaults to 7079.
+	// +optional
+	BlockManagerPort *int32 `json:"blockManagerPort,omitempty"`
+}
+
+// ComputeClusterType is the type of the compute cluster.
+// +kubebuilder:validation:Enum=dask;ray
+type ComputeClusterType string
+
+const (
+	// ComputeClusterDask is the Dask distributed cluster.
+	ComputeClusterDask ComputeClusterType = "dask"
+	// ComputeClusterRay is the Ray cluster.
+	ComputeClusterRay ComputeClusterType = "ray"
+)
+
+// ComputeClusterTemplate defines the compute cluster of the kernel, which
+// consists of a scheduler (the head of Ray) and the elastic workers. The
+// address of the scheduler is passed to the kernel in the env, i.e.
+// DASK_SCHEDULER_ADDRESS or RAY_ADDRESS.
+type ComputeClusterTemplate struct {
+	// Type is the type of the cluster, i.e. dask or ray.
+	Type ComputeClusterType `json:"type"`
+	// Scheduler is the pod template of the scheduler, or the head of Ray.
+	// The command of the first container defaults to the scheduler of the
+	// type, e.g. dask-scheduler. It is not validated by the API server to
+	// keep the CRD small.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Scheduler v1.PodTemplateSpec `json:"scheduler"`
+	// Worker is the pod template of the workers. The command of the first
+	// container defaults to the worker of the type connecting to the
+	// scheduler, e.g. dask-worker. It is not validated by the API server to
+	// keep the CRD small.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Worker v1.PodTemplateSpec `json:"worker"`
+	// MinWorkers is the minimum number of the workers. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinWorkers *int32 `json:"minWorkers,omitempty"`
+	// MaxWorkers is the maximum number of the workers. The workers are
+	// scaled between MinWorkers and MaxWorkers by a HorizontalPodAutoscaler
+	// if it is larger than MinWorkers. Defaults to MinWorkers, and it must
+	// not be less than MinWorkers.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxWorkers *int32 `json:"maxWorkers,omitempty"`
+	// Metrics are the signals to scale the workers, e.g. the CPU
+	// utilization or the external metric of the pending tasks. Defaults to
+	// 80% of the CPU utilization.
+	// +optional
+	Metrics []autoscalingv2beta2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// KernelResourcePolicy is the policy to apply the recommended resources to
+// the kernel template.
+type KernelResourcePolicy struct {
+	// AutoApply sets the recommended requests to the kernel container in
+	// the template, which are used by the kernels launched afterwards. The
+	// recommended limits are not applied.
+	// +optional
+	AutoApply bool `json:"autoApply,omitempty"`
+	// MinAllowed is the lower bound of the applied requests.
+	// +optional
+	MinAllowed v1.ResourceList `json:"minAllowed,omitempty"`
+	// MaxAllowed is the upper bound of the applied requests.
+	// +optional
+	MaxAllowed v1.ResourceList `json:"maxAllowed,omitempty"`
+}
+
+// KernelPeakUsage is the peak usage of a finished kernel.
+type KernelPeakUsage struct {
+	// Kernel is the kernel, in the form of <namespace>/<name>.
+	Kernel string `json:"kernel"`
+	// Usage is the peak CPU and memory usage of the kernel container.
+	Usage v1.ResourceList `json:"usage"`
+}
+
+// KernelResourceRecommendation is the recommended resources of the kernel
+// container.
+type KernelResourceRecommendation struct {
+	// Requests are the recommended requests.
+	Requests v1.ResourceList `json:"requests,omitempty"`
+	// Limits are the recommended limits.
+	Limits v1.ResourceList `json:"limits,omitempty"`
+	// Samples is the number of the finished kernels the recommendation is
+	// based on.
+	Samples int32 `json:"samples"`
+	// LastUpdateTime is the last time the recommendation was updated.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
 }
 
 // JupyterKernelTemplateStatus defines the observed state of JupyterKernelTemplate
 type JupyterKernelTemplateStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// PeakUsages are the peak usages of the last finished kernels launched
+	// from the template, which are recorded by the recommender.
+	// +optional
+	PeakUsages []KernelPeakUsage `json:"peakUsages,omitempty"`
+	// Recommendation is the recommended resources of the kernel container,
+	// which is set once there are enough finished kernels.
+	// +optional
+	Recommendation *KernelResourceRecommendation `json:"recommendation,omitempty"`
 }
 
 // +kubebuilder:object:root=true
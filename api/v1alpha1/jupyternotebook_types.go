@@ -30,6 +30,30 @@ type JupyterNotebookSpec struct {
 	Auth    *JupyterAuth        `json:"auth,omitempty"`
 
 	Template *v1.PodTemplateSpec `json:"template,omitempty"`
+
+	// KernelEnv is the env passed to the kernels launched through the
+	// gateway. The values can refer to the keys of the Secrets or the
+	// ConfigMaps in the namespace of the notebook. The names, except the
+	// ones starting with KERNEL_, need to be allowed by kernelEnvAllowlist
+	// of the gateway.
+	// +optional
+	KernelEnv []v1.EnvVar `json:"kernelEnv,omitempty"`
+
+	// Workspace mounts the workspace of the notebook into the kernels
+	// launched through the gateway, which requires mirrorWorkingDirs of
+	// the gateway.
+	// +optional
+	Workspace *JupyterNotebookWorkspace `json:"workspace,omitempty"`
+}
+
+// JupyterNotebookWorkspace is the workspace of the notebook, which is
+// mounted at the same path in the kernels.
+type JupyterNotebookWorkspace struct {
+	// Volume is the name of the volume in the template, which is a
+	// PersistentVolumeClaim mounted in the notebook container. The claim
+	// needs to be ReadWriteMany or ReadOnlyMany, since the kernels may
+	// run on the other nodes.
+	Volume string `json:"volume"`
 }
 
 // JupyterAuth defines how to deal with jupyter notebook tokens or passwords.
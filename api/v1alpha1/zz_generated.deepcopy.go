@@ -21,10 +21,203 @@
 package v1alpha1
 
 import (
+	"k8s.io/api/autoscaling/v2beta2"
 	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Accelerator) DeepCopyInto(out *Accelerator) {
+	*out = *in
+	if in.Count != nil {
+		in, out := &in.Count, &out.Count
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxCount != nil {
+		in, out := &in.MaxCount, &out.MaxCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Accelerator.
+func (in *Accelerator) DeepCopy() *Accelerator {
+	if in == nil {
+		return nil
+	}
+	out := new(Accelerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterJupyterKernelSpec) DeepCopyInto(out *ClusterJupyterKernelSpec) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterJupyterKernelSpec.
+func (in *ClusterJupyterKernelSpec) DeepCopy() *ClusterJupyterKernelSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterJupyterKernelSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterJupyterKernelSpec) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterJupyterKernelSpecList) DeepCopyInto(out *ClusterJupyterKernelSpecList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterJupyterKernelSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterJupyterKernelSpecList.
+func (in *ClusterJupyterKernelSpecList) DeepCopy() *ClusterJupyterKernelSpecList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterJupyterKernelSpecList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterJupyterKernelSpecList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterJupyterKernelTemplate) DeepCopyInto(out *ClusterJupyterKernelTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterJupyterKernelTemplate.
+func (in *ClusterJupyterKernelTemplate) DeepCopy() *ClusterJupyterKernelTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterJupyterKernelTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterJupyterKernelTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterJupyterKernelTemplateList) DeepCopyInto(out *ClusterJupyterKernelTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterJupyterKernelTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterJupyterKernelTemplateList.
+func (in *ClusterJupyterKernelTemplateList) DeepCopy() *ClusterJupyterKernelTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterJupyterKernelTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterJupyterKernelTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComputeClusterTemplate) DeepCopyInto(out *ComputeClusterTemplate) {
+	*out = *in
+	in.Scheduler.DeepCopyInto(&out.Scheduler)
+	in.Worker.DeepCopyInto(&out.Worker)
+	if in.MinWorkers != nil {
+		in, out := &in.MinWorkers, &out.MinWorkers
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxWorkers != nil {
+		in, out := &in.MaxWorkers, &out.MaxWorkers
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]v2beta2.MetricSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComputeClusterTemplate.
+func (in *ComputeClusterTemplate) DeepCopy() *ComputeClusterTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ComputeClusterTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JupyterAuth) DeepCopyInto(out *JupyterAuth) {
 	*out = *in
@@ -117,6 +310,11 @@ func (in *JupyterGatewaySpec) DeepCopyInto(out *JupyterGatewaySpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ClusterKernels != nil {
+		in, out := &in.ClusterKernels, &out.ClusterKernels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.DefaultKernel != nil {
 		in, out := &in.DefaultKernel, &out.DefaultKernel
 		*out = new(string)
@@ -147,6 +345,26 @@ func (in *JupyterGatewaySpec) DeepCopyInto(out *JupyterGatewaySpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.KernelShutdownTimeoutSeconds != nil {
+		in, out := &in.KernelShutdownTimeoutSeconds, &out.KernelShutdownTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KernelEnvAllowlist != nil {
+		in, out := &in.KernelEnvAllowlist, &out.KernelEnvAllowlist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterGatewaySpec.
@@ -206,6 +424,29 @@ func (in *JupyterKernel) DeepCopyObject() runtime.Object {
 func (in *JupyterKernelCRDSpec) DeepCopyInto(out *JupyterKernelCRDSpec) {
 	*out = *in
 	in.Template.DeepCopyInto(&out.Template)
+	if in.Spark != nil {
+		in, out := &in.Spark, &out.Spark
+		*out = new(SparkTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cluster != nil {
+		in, out := &in.Cluster, &out.Cluster
+		*out = new(ComputeClusterTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReplicaGroups != nil {
+		in, out := &in.ReplicaGroups, &out.ReplicaGroups
+		*out = make([]KernelReplicaGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	in.KernelLifetime.DeepCopyInto(&out.KernelLifetime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelCRDSpec.
@@ -268,26 +509,26 @@ func (in *JupyterKernelList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelSpec) DeepCopyInto(out *JupyterKernelSpec) {
+func (in *JupyterKernelQuota) DeepCopyInto(out *JupyterKernelQuota) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpec.
-func (in *JupyterKernelSpec) DeepCopy() *JupyterKernelSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelQuota.
+func (in *JupyterKernelQuota) DeepCopy() *JupyterKernelQuota {
 	if in == nil {
 		return nil
 	}
-	out := new(JupyterKernelSpec)
+	out := new(JupyterKernelQuota)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *JupyterKernelSpec) DeepCopyObject() runtime.Object {
+func (in *JupyterKernelQuota) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -295,31 +536,31 @@ func (in *JupyterKernelSpec) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelSpecList) DeepCopyInto(out *JupyterKernelSpecList) {
+func (in *JupyterKernelQuotaList) DeepCopyInto(out *JupyterKernelQuotaList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]JupyterKernelSpec, len(*in))
+		*out = make([]JupyterKernelQuota, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecList.
-func (in *JupyterKernelSpecList) DeepCopy() *JupyterKernelSpecList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelQuotaList.
+func (in *JupyterKernelQuotaList) DeepCopy() *JupyterKernelQuotaList {
 	if in == nil {
 		return nil
 	}
-	out := new(JupyterKernelSpecList)
+	out := new(JupyterKernelQuotaList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *JupyterKernelSpecList) DeepCopyObject() runtime.Object {
+func (in *JupyterKernelQuotaList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -327,107 +568,80 @@ func (in *JupyterKernelSpecList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelSpecSpec) DeepCopyInto(out *JupyterKernelSpecSpec) {
+func (in *JupyterKernelQuotaSpec) DeepCopyInto(out *JupyterKernelQuotaSpec) {
 	*out = *in
-	if in.Env != nil {
-		in, out := &in.Env, &out.Env
-		*out = make([]v1.EnvVar, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.Command != nil {
-		in, out := &in.Command, &out.Command
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = new(KernelQuotaLimits)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.Template != nil {
-		in, out := &in.Template, &out.Template
-		*out = new(v1.ObjectReference)
-		**out = **in
+	if in.KernelSpecs != nil {
+		in, out := &in.KernelSpecs, &out.KernelSpecs
+		*out = make(map[string]KernelQuotaLimits, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecSpec.
-func (in *JupyterKernelSpecSpec) DeepCopy() *JupyterKernelSpecSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelQuotaSpec.
+func (in *JupyterKernelQuotaSpec) DeepCopy() *JupyterKernelQuotaSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(JupyterKernelSpecSpec)
+	out := new(JupyterKernelQuotaSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelSpecStatus) DeepCopyInto(out *JupyterKernelSpecStatus) {
+func (in *JupyterKernelQuotaStatus) DeepCopyInto(out *JupyterKernelQuotaStatus) {
 	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecStatus.
-func (in *JupyterKernelSpecStatus) DeepCopy() *JupyterKernelSpecStatus {
-	if in == nil {
-		return nil
+	if in.Used != nil {
+		in, out := &in.Used, &out.Used
+		*out = new(KernelQuotaUsage)
+		(*in).DeepCopyInto(*out)
 	}
-	out := new(JupyterKernelSpecStatus)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelStatus) DeepCopyInto(out *JupyterKernelStatus) {
-	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]JupyterKernelCondition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	if in.KernelSpecs != nil {
+		in, out := &in.KernelSpecs, &out.KernelSpecs
+		*out = make(map[string]KernelQuotaUsage, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
-	if in.StartTime != nil {
-		in, out := &in.StartTime, &out.StartTime
-		*out = (*in).DeepCopy()
-	}
-	if in.CompletionTime != nil {
-		in, out := &in.CompletionTime, &out.CompletionTime
-		*out = (*in).DeepCopy()
-	}
-	if in.LastReconcileTime != nil {
-		in, out := &in.LastReconcileTime, &out.LastReconcileTime
-		*out = (*in).DeepCopy()
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelStatus.
-func (in *JupyterKernelStatus) DeepCopy() *JupyterKernelStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelQuotaStatus.
+func (in *JupyterKernelQuotaStatus) DeepCopy() *JupyterKernelQuotaStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(JupyterKernelStatus)
+	out := new(JupyterKernelQuotaStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelTemplate) DeepCopyInto(out *JupyterKernelTemplate) {
+func (in *JupyterKernelSpec) DeepCopyInto(out *JupyterKernelSpec) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelTemplate.
-func (in *JupyterKernelTemplate) DeepCopy() *JupyterKernelTemplate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpec.
+func (in *JupyterKernelSpec) DeepCopy() *JupyterKernelSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(JupyterKernelTemplate)
+	out := new(JupyterKernelSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *JupyterKernelTemplate) DeepCopyObject() runtime.Object {
+func (in *JupyterKernelSpec) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -435,15 +649,278 @@ func (in *JupyterKernelTemplate) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *JupyterKernelTemplateList) DeepCopyInto(out *JupyterKernelTemplateList) {
+func (in *JupyterKernelSpecCondition) DeepCopyInto(out *JupyterKernelSpecCondition) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]JupyterKernelTemplate, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecCondition.
+func (in *JupyterKernelSpecCondition) DeepCopy() *JupyterKernelSpecCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterKernelSpecCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterKernelSpecList) DeepCopyInto(out *JupyterKernelSpecList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JupyterKernelSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecList.
+func (in *JupyterKernelSpecList) DeepCopy() *JupyterKernelSpecList {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterKernelSpecList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JupyterKernelSpecList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterKernelSpecSpec) DeepCopyInto(out *JupyterKernelSpecSpec) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CustomResource != nil {
+		in, out := &in.CustomResource, &out.CustomResource
+		*out = new(KernelCustomResource)
+		**out = **in
+	}
+	if in.Debugger != nil {
+		in, out := &in.Debugger, &out.Debugger
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ProcessProxyConfig != nil {
+		in, out := &in.ProcessProxyConfig, &out.ProcessProxyConfig
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceFiles != nil {
+		in, out := &in.ResourceFiles, &out.ResourceFiles
+		*out = new(KernelResourceFiles)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(v1.PodTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecSpec.
+func (in *JupyterKernelSpecSpec) DeepCopy() *JupyterKernelSpecSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterKernelSpecSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterKernelSpecStatus) DeepCopyInto(out *JupyterKernelSpecStatus) {
+	*out = *in
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.Gateways != nil {
+		in, out := &in.Gateways, &out.Gateways
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]JupyterKernelSpecCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelSpecStatus.
+func (in *JupyterKernelSpecStatus) DeepCopy() *JupyterKernelSpecStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterKernelSpecStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterKernelStatus) DeepCopyInto(out *JupyterKernelStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]JupyterKernelCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.QueuePosition != nil {
+		in, out := &in.QueuePosition, &out.QueuePosition
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PeakUsage != nil {
+		in, out := &in.PeakUsage, &out.PeakUsage
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.ReplicaGroups != nil {
+		in, out := &in.ReplicaGroups, &out.ReplicaGroups
+		*out = make([]KernelReplicaGroupStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Preemption != nil {
+		in, out := &in.Preemption, &out.Preemption
+		*out = new(KernelPreemption)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelStatus.
+func (in *JupyterKernelStatus) DeepCopy() *JupyterKernelStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterKernelStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterKernelTemplate) DeepCopyInto(out *JupyterKernelTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelTemplate.
+func (in *JupyterKernelTemplate) DeepCopy() *JupyterKernelTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterKernelTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JupyterKernelTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterKernelTemplateList) DeepCopyInto(out *JupyterKernelTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JupyterKernelTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
@@ -474,6 +951,46 @@ func (in *JupyterKernelTemplateSpec) DeepCopyInto(out *JupyterKernelTemplateSpec
 		*out = new(v1.PodTemplateSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Spark != nil {
+		in, out := &in.Spark, &out.Spark
+		*out = new(SparkTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Accelerator != nil {
+		in, out := &in.Accelerator, &out.Accelerator
+		*out = new(Accelerator)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Identity != nil {
+		in, out := &in.Identity, &out.Identity
+		*out = new(KernelIdentity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]KernelVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Cluster != nil {
+		in, out := &in.Cluster, &out.Cluster
+		*out = new(ComputeClusterTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReplicaGroups != nil {
+		in, out := &in.ReplicaGroups, &out.ReplicaGroups
+		*out = make([]KernelReplicaGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResourcePolicy != nil {
+		in, out := &in.ResourcePolicy, &out.ResourcePolicy
+		*out = new(KernelResourcePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	in.KernelLifetime.DeepCopyInto(&out.KernelLifetime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelTemplateSpec.
@@ -489,6 +1006,18 @@ func (in *JupyterKernelTemplateSpec) DeepCopy() *JupyterKernelTemplateSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JupyterKernelTemplateStatus) DeepCopyInto(out *JupyterKernelTemplateStatus) {
 	*out = *in
+	if in.PeakUsages != nil {
+		in, out := &in.PeakUsages, &out.PeakUsages
+		*out = make([]KernelPeakUsage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Recommendation != nil {
+		in, out := &in.Recommendation, &out.Recommendation
+		*out = new(KernelResourceRecommendation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterKernelTemplateStatus.
@@ -578,6 +1107,18 @@ func (in *JupyterNotebookSpec) DeepCopyInto(out *JupyterNotebookSpec) {
 		*out = new(v1.PodTemplateSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.KernelEnv != nil {
+		in, out := &in.KernelEnv, &out.KernelEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workspace != nil {
+		in, out := &in.Workspace, &out.Workspace
+		*out = new(JupyterNotebookWorkspace)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterNotebookSpec.
@@ -604,3 +1145,403 @@ func (in *JupyterNotebookStatus) DeepCopy() *JupyterNotebookStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JupyterNotebookWorkspace) DeepCopyInto(out *JupyterNotebookWorkspace) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JupyterNotebookWorkspace.
+func (in *JupyterNotebookWorkspace) DeepCopy() *JupyterNotebookWorkspace {
+	if in == nil {
+		return nil
+	}
+	out := new(JupyterNotebookWorkspace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelCustomResource) DeepCopyInto(out *KernelCustomResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelCustomResource.
+func (in *KernelCustomResource) DeepCopy() *KernelCustomResource {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelCustomResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelIdentity) DeepCopyInto(out *KernelIdentity) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelIdentity.
+func (in *KernelIdentity) DeepCopy() *KernelIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelLifetime) DeepCopyInto(out *KernelLifetime) {
+	*out = *in
+	if in.MaxLifetime != nil {
+		in, out := &in.MaxLifetime, &out.MaxLifetime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelLifetime.
+func (in *KernelLifetime) DeepCopy() *KernelLifetime {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelLifetime)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelPeakUsage) DeepCopyInto(out *KernelPeakUsage) {
+	*out = *in
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelPeakUsage.
+func (in *KernelPeakUsage) DeepCopy() *KernelPeakUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelPeakUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelPreemption) DeepCopyInto(out *KernelPreemption) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelPreemption.
+func (in *KernelPreemption) DeepCopy() *KernelPreemption {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelPreemption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelQuotaLimits) DeepCopyInto(out *KernelQuotaLimits) {
+	*out = *in
+	if in.Kernels != nil {
+		in, out := &in.Kernels, &out.Kernels
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelQuotaLimits.
+func (in *KernelQuotaLimits) DeepCopy() *KernelQuotaLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelQuotaLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelQuotaUsage) DeepCopyInto(out *KernelQuotaUsage) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelQuotaUsage.
+func (in *KernelQuotaUsage) DeepCopy() *KernelQuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelReplicaGroup) DeepCopyInto(out *KernelReplicaGroup) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelReplicaGroup.
+func (in *KernelReplicaGroup) DeepCopy() *KernelReplicaGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelReplicaGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelReplicaGroupStatus) DeepCopyInto(out *KernelReplicaGroupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelReplicaGroupStatus.
+func (in *KernelReplicaGroupStatus) DeepCopy() *KernelReplicaGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelReplicaGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelResourceFiles) DeepCopyInto(out *KernelResourceFiles) {
+	*out = *in
+	if in.Logo32x32 != nil {
+		in, out := &in.Logo32x32, &out.Logo32x32
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.Logo64x64 != nil {
+		in, out := &in.Logo64x64, &out.Logo64x64
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogoSVG != nil {
+		in, out := &in.LogoSVG, &out.LogoSVG
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.BinaryData != nil {
+		in, out := &in.BinaryData, &out.BinaryData
+		*out = make(map[string][]byte, len(*in))
+		for key, val := range *in {
+			var outVal []byte
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]byte, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelResourceFiles.
+func (in *KernelResourceFiles) DeepCopy() *KernelResourceFiles {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelResourceFiles)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelResourcePolicy) DeepCopyInto(out *KernelResourcePolicy) {
+	*out = *in
+	if in.MinAllowed != nil {
+		in, out := &in.MinAllowed, &out.MinAllowed
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.MaxAllowed != nil {
+		in, out := &in.MaxAllowed, &out.MaxAllowed
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelResourcePolicy.
+func (in *KernelResourcePolicy) DeepCopy() *KernelResourcePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelResourcePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelResourceRecommendation) DeepCopyInto(out *KernelResourceRecommendation) {
+	*out = *in
+	if in.Requests != nil {
+		in, out := &in.Requests, &out.Requests
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelResourceRecommendation.
+func (in *KernelResourceRecommendation) DeepCopy() *KernelResourceRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelResourceRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelVolume) DeepCopyInto(out *KernelVolume) {
+	*out = *in
+	in.KernelVolumeSource.DeepCopyInto(&out.KernelVolumeSource)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelVolume.
+func (in *KernelVolume) DeepCopy() *KernelVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelVolumeSource) DeepCopyInto(out *KernelVolumeSource) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(v1.PersistentVolumeClaimVolumeSource)
+		**out = **in
+	}
+	if in.NFS != nil {
+		in, out := &in.NFS, &out.NFS
+		*out = new(v1.NFSVolumeSource)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(v1.ConfigMapVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(v1.CSIVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelVolumeSource.
+func (in *KernelVolumeSource) DeepCopy() *KernelVolumeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkTemplate) DeepCopyInto(out *SparkTemplate) {
+	*out = *in
+	if in.ExecutorTemplate != nil {
+		in, out := &in.ExecutorTemplate, &out.ExecutorTemplate
+		*out = new(v1.PodTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Executors != nil {
+		in, out := &in.Executors, &out.Executors
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Conf != nil {
+		in, out := &in.Conf, &out.Conf
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DriverPort != nil {
+		in, out := &in.DriverPort, &out.DriverPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BlockManagerPort != nil {
+		in, out := &in.BlockManagerPort, &out.BlockManagerPort
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkTemplate.
+func (in *SparkTemplate) DeepCopy() *SparkTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
@@ -26,10 +27,10 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
 )
 
 const (
@@ -57,8 +58,12 @@ var (
 	kernelTemplateName, kernelTemplateNamespace string
 	gatewayName string
 	verbose     bool
+	waitQueue   bool
 )
 
+// waitInterval is the interval to poll the kernel when waiting in the queue.
+const waitInterval = 2 * time.Second
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "kubeflow-launcher",
@@ -73,8 +78,8 @@ var rootCmd = &cobra.Command{
 		if gatewayName == "" || gatewayNamespace == "" {
 			panic(fmt.Errorf("failed to get the gateway name or namespace from the env var"))
 		}
-		if kernelTemplateName == "" || kernelTemplateNamespace == "" {
-			panic(fmt.Errorf("failed to get the template's name or namespace"))
+		if kernelTemplateName == "" {
+			panic(fmt.Errorf("failed to get the template's name"))
 		}
 
 		logger.Info("Launching the kernel",
@@ -101,18 +106,31 @@ var rootCmd = &cobra.Command{
 			panic(err)
 		}
 
-		kt := &v1alpha1.JupyterKernelTemplate{}
-		if err := cli.Get(context.TODO(), client.ObjectKey{
-			Namespace: kernelTemplateNamespace,
-			Name:      kernelTemplateName,
-		}, kt); err != nil {
+		ktSpec, err := getKernelTemplateSpec(cli)
+		if err != nil {
 			panic(err)
 		}
 
 		kernel := &v1alpha1.JupyterKernel{
-			ObjectMeta: kt.Spec.Template.ObjectMeta,
+			ObjectMeta: ktSpec.Template.ObjectMeta,
 			Spec: v1alpha1.JupyterKernelCRDSpec{
-				Template: *kt.Spec.Template,
+				Template: *ktSpec.Template,
+				// The operator creates the driver service and the
+				// Spark configuration for the kernel.
+				Spark: ktSpec.Spark.DeepCopy(),
+				// The operator creates the pods of the replica groups
+				// and schedules them with the kernel as a gang.
+				ReplicaGroups: ktSpec.ReplicaGroups,
+				// The operator creates the Dask or Ray cluster of the
+				// kernel.
+				Cluster: ktSpec.Cluster.DeepCopy(),
+				// The operator sets the PriorityClass of the tier.
+				PriorityTier: ktSpec.PriorityTier,
+				// The operator resizes the kernel with the policy when
+				// its resources change.
+				ResizePolicy: ktSpec.ResizePolicy,
+				// The operator deletes the kernel after the lifetime.
+				KernelLifetime: *ktSpec.KernelLifetime.DeepCopy(),
 			},
 		}
 
@@ -175,6 +193,43 @@ var rootCmd = &cobra.Command{
 			},
 		)
 
+		// The notebook which launches the kernel, which is verified by
+		// its token.
+		nb, err := launcher.VerifyNotebook(context.TODO(), cli, kernel,
+			os.Getenv(launcher.EnvKernelNotebook),
+			os.Getenv(launcher.EnvKernelNotebookToken))
+		if err != nil {
+			panic(err)
+		}
+		launcher.ApplyNotebook(kernel, nb)
+
+		if ktSpec.Identity != nil {
+			resolver, err := launcher.NewIdentityResolver(cli, ktSpec.Identity)
+			if err != nil {
+				panic(err)
+			}
+			if err := launcher.ApplyIdentity(context.TODO(), kernel,
+				ktSpec.Identity, resolver, nb); err != nil {
+				panic(err)
+			}
+		}
+
+		if err := launcher.ApplyAccelerator(&kernel.Spec.Template,
+			ktSpec.Accelerator, os.Getenv(launcher.EnvKernelGPUs)); err != nil {
+			panic(err)
+		}
+
+		if err := launcher.ApplyVolumes(&kernel.Spec.Template, ktSpec.Volumes,
+			os.Getenv(launcher.EnvKernelVolumes),
+			os.Getenv(launcher.EnvKernelVolumeMounts)); err != nil {
+			panic(err)
+		}
+
+		if err := launcher.ApplyWorkspace(context.TODO(), cli, kernel, nb,
+			os.Getenv(launcher.EnvKernelWorkingDir)); err != nil {
+			panic(err)
+		}
+
 		gateway := &v1alpha1.JupyterGateway{}
 		if err := cli.Get(context.TODO(), types.NamespacedName{
 			Name:      gatewayName,
@@ -183,18 +238,67 @@ var rootCmd = &cobra.Command{
 			panic(err)
 		}
 
-		if err := controllerutil.SetControllerReference(
-			gateway, kernel, scheme.Scheme); err != nil {
+		// Set the env from the clients, which is allowed by the gateway. It
+		// is prepended thus the env of the template and the launcher wins.
+		kernel.Spec.Template.Spec.Containers[0].Env = append(
+			launcher.AllowedEnv(gateway, os.LookupEnv),
+			kernel.Spec.Template.Spec.Containers[0].Env...)
+
+		if err := launcher.SetGateway(kernel, gateway, scheme.Scheme); err != nil {
 			panic(err)
 		}
+		launcher.SetTemplate(kernel, kernelTemplateName, kernelTemplateNamespace)
 
 		logger.Info("Creating the kernel", "kernel", kernel)
 		if err := cli.Create(context.TODO(), kernel); err != nil {
+			// Show the reason to the user instead of the stack, e.g. the
+			// kernel exceeds the quota.
+			if reason, ok := launcher.AdmissionDenied(err); ok {
+				fmt.Fprintf(os.Stderr, "Failed to launch the kernel: %s\n", reason)
+				os.Exit(1)
+			}
 			panic(err)
 		}
+
+		if waitQueue {
+			if err := launcher.WaitAdmitted(cli, types.NamespacedName{
+				Namespace: kernel.Namespace,
+				Name:      kernel.Name,
+			}, waitInterval, launcher.LaunchTimeout(os.Getenv(launcher.EnvKernelLaunchTimeout)),
+				func(position int32) {
+					fmt.Fprintf(os.Stderr, "The kernel is queued at position %d\n", position)
+				}); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to launch the kernel: %s\n", err)
+				os.Exit(1)
+			}
+		}
 	},
 }
 
+// getKernelTemplateSpec gets the spec of the kernel template. The
+// ClusterJupyterKernelTemplate is used if the namespace is not given.
+func getKernelTemplateSpec(cli client.Client) (
+	*v1alpha1.JupyterKernelTemplateSpec, error) {
+	if kernelTemplateNamespace == "" {
+		ckt := &v1alpha1.ClusterJupyterKernelTemplate{}
+		if err := cli.Get(context.TODO(), client.ObjectKey{
+			Name: kernelTemplateName,
+		}, ckt); err != nil {
+			return nil, err
+		}
+		return &ckt.Spec, nil
+	}
+
+	kt := &v1alpha1.JupyterKernelTemplate{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{
+		Namespace: kernelTemplateNamespace,
+		Name:      kernelTemplateName,
+	}, kt); err != nil {
+		return nil, err
+	}
+	return &kt.Spec, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -224,7 +328,10 @@ func init() {
 	rootCmd.Flags().StringVar(&kernelTemplateName,
 		"kernel-template-name", "", "kernel template CRD name")
 	rootCmd.Flags().StringVar(&kernelTemplateNamespace,
-		"kernel-template-namespace", "", "kernel template CRD namesapce")
+		"kernel-template-namespace", "",
+		"kernel template CRD namesapce, the cluster-scoped template is used if it is empty")
 
 	rootCmd.Flags().BoolVar(&verbose, "verbose", false, "Set verbose")
+	rootCmd.Flags().BoolVar(&waitQueue, "wait", false,
+		"Wait until the kernel is admitted by the queue, showing the position in the queue")
 }
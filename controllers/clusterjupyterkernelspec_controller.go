@@ -0,0 +1,140 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+
+// https://opensource.org/licenses/Apache-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernelspec"
+)
+
+// ClusterJupyterKernelSpecReconciler reconciles a ClusterJupyterKernelSpec object
+type ClusterJupyterKernelSpecReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles.
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=clusterjupyterkernelspecs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=clusterjupyterkernelspecs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=clusterjupyterkerneltemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkerneltemplates,verbs=get;list;watch
+
+func (r *ClusterJupyterKernelSpecReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	_ = context.Background()
+	_ = r.Log.WithValues("clusterjupyterkernelspec", req.NamespacedName)
+
+	original := &v1alpha1.ClusterJupyterKernelSpec{}
+
+	err := r.Get(context.TODO(), req.NamespacedName, original)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			// For additional cleanup logic use finalizers.
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		r.Log.Error(err, "Failed to get the object, requeuing the request")
+		return ctrl.Result{}, err
+	}
+	instance := original.DeepCopy()
+
+	gr, err := kernelspec.NewClusterReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := gr.Reconcile(); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *ClusterJupyterKernelSpecReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterJupyterKernelSpec{}).
+		Owns(&v1.ConfigMap{}).
+		Owns(&v1alpha1.ClusterJupyterKernelTemplate{}).
+		// Render the configmaps when the gateways start or stop
+		// referencing the cluster kernel specs.
+		Watches(&source.Kind{Type: &v1alpha1.JupyterGateway{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(clusterKernelsOfGateway),
+			}).
+		// Update the status when the templates change.
+		Watches(&source.Kind{Type: &v1alpha1.JupyterKernelTemplate{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.clusterKernelSpecsOfTemplate),
+			}).
+		Watches(&source.Kind{Type: &v1alpha1.ClusterJupyterKernelTemplate{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.clusterKernelSpecsOfTemplate),
+			}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func clusterKernelsOfGateway(o handler.MapObject) []reconcile.Request {
+	gw, ok := o.Object.(*v1alpha1.JupyterGateway)
+	if !ok {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, k := range gw.Spec.ClusterKernels {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: k},
+		})
+	}
+	return requests
+}
+
+// clusterKernelSpecsOfTemplate returns the cluster kernel specs which use
+// the template.
+func (r *ClusterJupyterKernelSpecReconciler) clusterKernelSpecsOfTemplate(o handler.MapObject) []reconcile.Request {
+	specs := &v1alpha1.ClusterJupyterKernelSpecList{}
+	if err := r.List(context.TODO(), specs); err != nil {
+		r.Log.Error(err, "Failed to list the cluster kernel specs")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, ks := range specs.Items {
+		if usesTemplate(ks.Status.Template, o) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: ks.Name},
+			})
+		}
+	}
+	return requests
+}
@@ -21,12 +21,16 @@ import (
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
@@ -40,6 +44,14 @@ type JupyterGatewayReconciler struct {
 	Log      logr.Logger
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles.
+	MaxConcurrentReconciles int
+	// Namespaced is true if the operator only watches some namespaces, in
+	// which case the cluster kernels are not supported.
+	Namespaced bool
+	// Options are the options of the gateways.
+	Options gateway.Options
 }
 
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupytergateways,verbs=get;list;watch;create;update;patch;delete
@@ -47,6 +59,10 @@ type JupyterGatewayReconciler struct {
 // +kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods;namespaces;services;serviceaccounts;configmaps;secrets;persistentvolumes;persistentvolumeclaims;events,verbs=get;list;watch;create;update;create;patch;delete
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=rolebindings,verbs=get;create;update;patch;list;watch;delete
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=clusterroles,verbs=bind,resourceNames=jupytergateway-process-proxy
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=clusterrolebindings,verbs=get;create;update;patch;list;watch;delete
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernelspecs;clusterjupyterkernelspecs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernels,verbs=get;list;watch;delete
 
 func (r *JupyterGatewayReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
@@ -67,10 +83,26 @@ func (r *JupyterGatewayReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	}
 	instance := original.DeepCopy()
 
-	gr, err := gateway.NewReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance)
+	if r.Namespaced && len(instance.Spec.ClusterKernels) != 0 {
+		r.Recorder.Event(instance, v1.EventTypeWarning, "ClusterKernelsUnsupported",
+			"The cluster kernels are ignored since the operator only watches some namespaces")
+		instance.Spec.ClusterKernels = nil
+	}
+
+	gr, err := gateway.NewReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance, r.Options)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if instance.DeletionTimestamp != nil {
+		requeueAfter, err := gr.Finalize()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	if err := gr.EnsureFinalizer(); err != nil {
+		return ctrl.Result{}, err
+	}
 	if err := gr.Reconcile(); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -78,12 +110,56 @@ func (r *JupyterGatewayReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 }
 
 func (r *JupyterGatewayReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&kubeflowtkestackiov1alpha1.JupyterGateway{}).
 		Watches(&source.Kind{Type: &appsv1.Deployment{}},
 			&handler.EnqueueRequestForOwner{
 				IsController: true,
 				OwnerType:    &v1alpha1.JupyterGateway{},
 			}).
+		// Regenerate the gateway when the process proxies of the kernels change.
+		Watches(&source.Kind{Type: &v1alpha1.JupyterKernelSpec{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.gatewaysOfKernelSpec),
+			})
+	if !r.Namespaced {
+		b = b.Watches(&source.Kind{Type: &v1alpha1.ClusterJupyterKernelSpec{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.gatewaysOfKernelSpec),
+			})
+	}
+	return b.WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
+
+// gatewaysOfKernelSpec returns the gateways which include the kernel spec
+// or the cluster kernel spec.
+func (r *JupyterGatewayReconciler) gatewaysOfKernelSpec(o handler.MapObject) []reconcile.Request {
+	_, cluster := o.Object.(*v1alpha1.ClusterJupyterKernelSpec)
+	gateways := &v1alpha1.JupyterGatewayList{}
+	opts := []client.ListOption{}
+	if !cluster {
+		opts = append(opts, client.InNamespace(o.Meta.GetNamespace()))
+	}
+	if err := r.List(context.TODO(), gateways, opts...); err != nil {
+		r.Log.Error(err, "Failed to list the gateways")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, gw := range gateways.Items {
+		kernels := gw.Spec.Kernels
+		if cluster {
+			kernels = gw.Spec.ClusterKernels
+		}
+		for _, k := range kernels {
+			if k == o.Meta.GetName() {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: gw.Namespace, Name: gw.Name},
+				})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -20,15 +20,28 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
 	kubeflowtkestackiov1alpha1 "github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
 	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/recommender"
 )
 
 // JupyterKernelReconciler reconciles a JupyterKernel object
@@ -37,10 +50,26 @@ type JupyterKernelReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Pods resizes the kernel pods by the pods/resize subresource, which
+	// is not supported by the client of controller-runtime.
+	Pods corev1client.PodsGetter
+
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles.
+	MaxConcurrentReconciles int
+	// Options are the options of the kernels.
+	Options kernel.Options
+	// Recommender records the peak usage of the deleted kernels in their
+	// templates. It is nil if the recommender is not enabled.
+	Recommender *recommender.Recommender
 }
 
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernels,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernels/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods;services;configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/resize,verbs=patch
+// +kubebuilder:rbac:groups="autoscaling",resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="scheduling.k8s.io",resources=priorityclasses,verbs=get;list;watch
 
 func (r *JupyterKernelReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
@@ -61,19 +90,113 @@ func (r *JupyterKernelReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 	}
 	instance := original.DeepCopy()
 
-	gr, err := kernel.NewReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance)
+	if instance.DeletionTimestamp != nil &&
+		controllerutil.ContainsFinalizer(instance, recommender.Finalizer) {
+		return ctrl.Result{}, r.finalize(instance)
+	}
+	if err := r.ensureFinalizer(instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	gr, err := kernel.NewReconciler(r.Client, r.Pods, r.Log, r.Recorder, r.Scheme, instance, r.Options)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	// Enforce the lifetime first, thus the expired kernels are not
+	// reconciled any more, and requeue at the deadline of the kernel.
+	requeueAfter, deleted, err := gr.ReconcileLifetime()
+	if err != nil || deleted {
+		return ctrl.Result{}, err
+	}
 	if err := gr.Reconcile(); err != nil {
 		return ctrl.Result{}, err
 	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
 
-	return ctrl.Result{}, nil
+// ensureFinalizer adds the finalizer of the recommender to the kernel
+// launched from a template, if the recommender is enabled.
+func (r *JupyterKernelReconciler) ensureFinalizer(k *v1alpha1.JupyterKernel) error {
+	if r.Recommender == nil || k.DeletionTimestamp != nil ||
+		controllerutil.ContainsFinalizer(k, recommender.Finalizer) {
+		return nil
+	}
+	if _, ok := recommender.Template(k); !ok {
+		return nil
+	}
+	controllerutil.AddFinalizer(k, recommender.Finalizer)
+	if err := r.Update(context.TODO(), k); err != nil {
+		r.Log.Error(err, "Failed to add the finalizer to the kernel")
+		return err
+	}
+	return nil
+}
+
+// finalize records the peak usage of the deleted kernel in its template,
+// and removes the finalizer. The finalizer is removed without recording if
+// the recommender is not enabled any more.
+func (r *JupyterKernelReconciler) finalize(k *v1alpha1.JupyterKernel) error {
+	if r.Recommender != nil {
+		if err := r.Recommender.Record(k); err != nil {
+			r.Log.Error(err, "Failed to record the peak usage of the kernel")
+			return err
+		}
+	}
+	controllerutil.RemoveFinalizer(k, recommender.Finalizer)
+	if err := r.Update(context.TODO(), k); err != nil {
+		r.Log.Error(err, "Failed to remove the finalizer from the kernel")
+		return err
+	}
+	return nil
 }
 
 func (r *JupyterKernelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kubeflowtkestackiov1alpha1.JupyterKernel{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&v1.Service{}).
+		Owns(&v1.ConfigMap{}).
+		// Update the status from the kernel pods, and adopt the Spark
+		// executors launched by the kernels.
+		Watches(&source.Kind{Type: &v1.Pod{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(kernelOfPod),
+			}).
+		// Record the kernels culled by the operator.
+		Watches(&source.Kind{Type: &v1alpha1.JupyterKernel{}},
+			handler.Funcs{DeleteFunc: kernelDeleted}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
+
+func kernelOfPod(o handler.MapObject) []reconcile.Request {
+	labels := o.Meta.GetLabels()
+	name, ok := labels[kernel.LabelSparkKernel]
+	if !ok {
+		name, ok = labels[kernel.LabelKernel]
+	}
+	if !ok {
+		name, ok = labels[kernel.LabelGang]
+	}
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Namespace: o.Meta.GetNamespace(),
+				Name:      name,
+			},
+		},
+	}
+}
+
+func kernelDeleted(e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+	k, ok := e.Object.(*v1alpha1.JupyterKernel)
+	if !ok {
+		return
+	}
+	if reason, ok := k.Annotations[kernel.AnnotationTerminationReason]; ok {
+		metrics.KernelCulled(k, reason)
+	}
+}
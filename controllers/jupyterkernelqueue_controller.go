@@ -0,0 +1,98 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+
+// https://opensource.org/licenses/Apache-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/queue"
+)
+
+// queueRequest is the only request of the queue controller, since the
+// kernels of the whole cluster are admitted together.
+var queueRequest = reconcile.Request{
+	NamespacedName: types.NamespacedName{Name: "jupyter-kernel-queue"},
+}
+
+// JupyterKernelQueueReconciler admits the queued kernels.
+type JupyterKernelQueueReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	// APIReader reads the pods of the whole cluster, which are not in the
+	// cache.
+	APIReader client.Reader
+
+	// Interval is the interval to retry the kernels which wait in the queue.
+	Interval time.Duration
+	// BatchPeriod is the delay of the retry after the kernels change, in
+	// which the changes are merged into one retry.
+	BatchPeriod time.Duration
+	// PriorityClasses maps the priority tiers of the kernels to the
+	// PriorityClasses.
+	PriorityClasses map[v1alpha1.KernelPriorityTier]string
+}
+
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernels,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernels/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes;pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="scheduling.k8s.io",resources=priorityclasses,verbs=get;list;watch
+
+func (r *JupyterKernelQueueReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	if err := queue.NewReconciler(r.Client, r.APIReader, r.Log, r.Recorder,
+		r.PriorityClasses).Reconcile(); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.Interval}, nil
+}
+
+func (r *JupyterKernelQueueReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	c, err := controller.New("jupyterkernelqueue", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: 1,
+	})
+	if err != nil {
+		return err
+	}
+	// Retry the queue when the kernels change. The pods and the nodes are
+	// not watched, which would cache all the pods of the cluster and retry
+	// the queue on every status update of them, thus the other changes of
+	// the free resources are retried every interval. Every retry lists all
+	// the pods from the API server, thus the changes of the kernels in the
+	// batch period are merged into one retry.
+	enqueue := func(q workqueue.RateLimitingInterface) {
+		q.AddAfter(queueRequest, r.BatchPeriod)
+	}
+	return c.Watch(&source.Kind{Type: &v1alpha1.JupyterKernel{}}, handler.Funcs{
+		CreateFunc: func(_ event.CreateEvent, q workqueue.RateLimitingInterface) { enqueue(q) },
+		UpdateFunc: func(_ event.UpdateEvent, q workqueue.RateLimitingInterface) { enqueue(q) },
+		DeleteFunc: func(_ event.DeleteEvent, q workqueue.RateLimitingInterface) { enqueue(q) },
+	})
+}
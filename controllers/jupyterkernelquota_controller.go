@@ -0,0 +1,102 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+
+// https://opensource.org/licenses/Apache-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/quota"
+)
+
+// JupyterKernelQuotaReconciler reconciles a JupyterKernelQuota object
+type JupyterKernelQuotaReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles.
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernelquotas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernelquotas/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernels,verbs=get;list;watch
+
+func (r *JupyterKernelQuotaReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	_ = context.Background()
+	_ = r.Log.WithValues("jupyterkernelquota", req.NamespacedName)
+
+	original := &v1alpha1.JupyterKernelQuota{}
+
+	err := r.Get(context.TODO(), req.NamespacedName, original)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		r.Log.Error(err, "Failed to get the object, requeuing the request")
+		return ctrl.Result{}, err
+	}
+	instance := original.DeepCopy()
+
+	if err := quota.NewReconciler(r.Client, r.Log, instance).Reconcile(); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *JupyterKernelQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.JupyterKernelQuota{}).
+		// Update the usage when the kernels are created or deleted.
+		Watches(&source.Kind{Type: &v1alpha1.JupyterKernel{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.quotasOfKernel),
+			}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func (r *JupyterKernelQuotaReconciler) quotasOfKernel(o handler.MapObject) []reconcile.Request {
+	quotas := &v1alpha1.JupyterKernelQuotaList{}
+	if err := r.List(context.TODO(), quotas,
+		client.InNamespace(o.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list the quotas")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(quotas.Items))
+	for _, q := range quotas.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: q.Namespace,
+				Name:      q.Name,
+			},
+		})
+	}
+	return requests
+}
@@ -20,11 +20,17 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
 	kubeflowtkestackiov1alpha1 "github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
@@ -37,10 +43,19 @@ type JupyterKernelSpecReconciler struct {
 	Log      logr.Logger
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles.
+	MaxConcurrentReconciles int
+	// Namespaced is true if the operator only watches some namespaces, in
+	// which case the cluster kernel templates are not supported.
+	Namespaced bool
 }
 
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernelspecs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkernelspecs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyterkerneltemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=clusterjupyterkerneltemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupytergateways,verbs=get;list;watch
 
 func (r *JupyterKernelSpecReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
@@ -61,6 +76,13 @@ func (r *JupyterKernelSpecReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 	}
 	instance := original.DeepCopy()
 
+	if r.Namespaced && instance.Spec.Template != nil &&
+		instance.Spec.Template.Kind == v1alpha1.ClusterJupyterKernelTemplateKind {
+		r.Recorder.Event(instance, v1.EventTypeWarning, "ClusterTemplateUnsupported",
+			"The cluster kernel template is not supported since the operator only watches some namespaces")
+		return ctrl.Result{}, nil
+	}
+
 	gr, err := kernelspec.NewReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -73,7 +95,67 @@ func (r *JupyterKernelSpecReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 }
 
 func (r *JupyterKernelSpecReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&kubeflowtkestackiov1alpha1.JupyterKernelSpec{}).
+		Owns(&kubeflowtkestackiov1alpha1.JupyterKernelTemplate{}).
+		// Update the status when the gateways or the templates change.
+		Watches(&source.Kind{Type: &v1alpha1.JupyterGateway{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(kernelsOfGateway),
+			}).
+		Watches(&source.Kind{Type: &v1alpha1.JupyterKernelTemplate{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.kernelSpecsOfTemplate),
+			})
+	if !r.Namespaced {
+		b = b.Watches(&source.Kind{Type: &v1alpha1.ClusterJupyterKernelTemplate{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.kernelSpecsOfTemplate),
+			})
+	}
+	return b.WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
+
+func kernelsOfGateway(o handler.MapObject) []reconcile.Request {
+	gw, ok := o.Object.(*v1alpha1.JupyterGateway)
+	if !ok {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, k := range gw.Spec.Kernels {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: gw.Namespace, Name: k},
+		})
+	}
+	return requests
+}
+
+// kernelSpecsOfTemplate returns the kernel specs which use the template.
+func (r *JupyterKernelSpecReconciler) kernelSpecsOfTemplate(o handler.MapObject) []reconcile.Request {
+	specs := &v1alpha1.JupyterKernelSpecList{}
+	if err := r.List(context.TODO(), specs); err != nil {
+		r.Log.Error(err, "Failed to list the kernel specs")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, ks := range specs.Items {
+		if usesTemplate(ks.Status.Template, o) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: ks.Namespace, Name: ks.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// usesTemplate returns true if the template reference in the kernel spec
+// status points to the given template.
+func usesTemplate(ref *v1.ObjectReference, o handler.MapObject) bool {
+	if ref == nil || ref.Name != o.Meta.GetName() ||
+		ref.Namespace != o.Meta.GetNamespace() {
+		return false
+	}
+	_, cluster := o.Object.(*v1alpha1.ClusterJupyterKernelTemplate)
+	return cluster == (ref.Kind == v1alpha1.ClusterJupyterKernelTemplateKind)
+}
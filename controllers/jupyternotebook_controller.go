@@ -21,11 +21,13 @@ import (
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -40,10 +42,16 @@ type JupyterNotebookReconciler struct {
 	Log      logr.Logger
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles.
+	MaxConcurrentReconciles int
+	// Options are the options of the notebooks.
+	Options notebook.Options
 }
 
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyternotebooks,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=jupyternotebooks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
 
 func (r *JupyterNotebookReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
@@ -64,7 +72,7 @@ func (r *JupyterNotebookReconciler) Reconcile(req ctrl.Request) (ctrl.Result, er
 	}
 	instance := original.DeepCopy()
 
-	gr, err := notebook.NewReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance)
+	gr, err := notebook.NewReconciler(r.Client, r.Log, r.Recorder, r.Scheme, instance, r.Options)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -82,5 +90,11 @@ func (r *JupyterNotebookReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				IsController: true,
 				OwnerType:    &v1alpha1.JupyterNotebook{},
 			}).
+		Watches(&source.Kind{Type: &v1.Secret{}},
+			&handler.EnqueueRequestForOwner{
+				IsController: true,
+				OwnerType:    &v1alpha1.JupyterNotebook{},
+			}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
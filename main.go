@@ -19,16 +19,27 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	kubeflowtkestackiov1alpha1 "github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
 	"github.com/tkestack/elastic-jupyter-operator/controllers"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/config"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/gc"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/preemption"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/quota"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/recommender"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -45,73 +56,226 @@ func init() {
 }
 
 func main() {
+	var configFile string
 	var metricsAddr string
+	var probeAddr string
 	var enableLeaderElection bool
+	var notebookIdleInterval time.Duration
+	var kernelGCDryRun bool
+	var enableWebhooks bool
+	flag.StringVar(&configFile, "config", "",
+		"The operator configuration file. The flags set explicitly take precedence over the file.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&notebookIdleInterval, "notebook-idle-interval", time.Minute,
+		"The interval to poll the idle time of the notebooks for the metrics.")
+	flag.BoolVar(&kernelGCDryRun, "kernel-gc-dry-run", false,
+		"Only report the kernels which are not tracked by the gateways without deleting them.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks, e.g. the quotas of the kernels, which require the serving certificates.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "82ec55e3.kubeflow.tkestack.io",
+	cfg := config.Default()
+	if configFile != "" {
+		var err error
+		if cfg, err = config.Load(configFile); err != nil {
+			setupLog.Error(err, "unable to load the configuration file")
+			os.Exit(1)
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "metrics-addr":
+			cfg.MetricsBindAddress = metricsAddr
+		case "health-probe-bind-address":
+			cfg.HealthProbeBindAddress = probeAddr
+		case "enable-leader-election":
+			cfg.LeaderElection.LeaderElect = enableLeaderElection
+		case "notebook-idle-interval":
+			cfg.NotebookIdleInterval = &metav1.Duration{Duration: notebookIdleInterval}
+		case "kernel-gc-dry-run":
+			cfg.KernelGC.DryRun = kernelGCDryRun
+		case "enable-webhooks":
+			cfg.EnableWebhooks = enableWebhooks
+		}
 	})
+	if cfg.Namespaced() {
+		setupLog.Info("watching namespaces", "namespaces", cfg.Namespaces)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), cfg.ManagerOptions(scheme))
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
 	if err = (&controllers.JupyterNotebookReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("JupyterNotebook"),
-		Recorder: mgr.GetEventRecorderFor("JupyterNotebook"),
-		Scheme:   mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("JupyterNotebook"),
+		Recorder:                mgr.GetEventRecorderFor("JupyterNotebook"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: cfg.ConcurrentReconciles("JupyterNotebook"),
+		Options:                 cfg.NotebookOptions(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JupyterNotebook")
 		os.Exit(1)
 	}
 	if err = (&controllers.JupyterGatewayReconciler{
-		Client:   mgr.GetClient(),
-		Recorder: mgr.GetEventRecorderFor("JupyterGateway"),
-		Log:      ctrl.Log.WithName("controllers").WithName("JupyterGateway"),
-		Scheme:   mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		Recorder:                mgr.GetEventRecorderFor("JupyterGateway"),
+		Log:                     ctrl.Log.WithName("controllers").WithName("JupyterGateway"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: cfg.ConcurrentReconciles("JupyterGateway"),
+		Namespaced:              cfg.Namespaced(),
+		Options:                 cfg.GatewayOptions(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JupyterGateway")
 		os.Exit(1)
 	}
 	if err = (&controllers.JupyterKernelSpecReconciler{
-		Client:   mgr.GetClient(),
-		Recorder: mgr.GetEventRecorderFor("JupyterKernelSpec"),
-		Log:      ctrl.Log.WithName("controllers").WithName("JupyterKernelSpec"),
-		Scheme:   mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		Recorder:                mgr.GetEventRecorderFor("JupyterKernelSpec"),
+		Log:                     ctrl.Log.WithName("controllers").WithName("JupyterKernelSpec"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: cfg.ConcurrentReconciles("JupyterKernelSpec"),
+		Namespaced:              cfg.Namespaced(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JupyterKernelSpec")
 		os.Exit(1)
 	}
+	// The cluster-scoped resources are not reconciled in the namespaced mode.
+	if !cfg.Namespaced() {
+		if err = (&controllers.ClusterJupyterKernelSpecReconciler{
+			Client:                  mgr.GetClient(),
+			Recorder:                mgr.GetEventRecorderFor("ClusterJupyterKernelSpec"),
+			Log:                     ctrl.Log.WithName("controllers").WithName("ClusterJupyterKernelSpec"),
+			Scheme:                  mgr.GetScheme(),
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("ClusterJupyterKernelSpec"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterJupyterKernelSpec")
+			os.Exit(1)
+		}
+	}
 	if err = (&controllers.JupyterKernelTemplateReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("JupyterKernelTemplate"),
-		Scheme: mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("JupyterKernelTemplate"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: cfg.ConcurrentReconciles("JupyterKernelTemplate"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JupyterKernelTemplate")
 		os.Exit(1)
 	}
+	// The peak usage of the deleted kernels is recorded by the finalizer of
+	// the kernel controller.
+	var kernelRecommender *recommender.Recommender
+	if cfg.KernelRecommender.Enabled {
+		kernelRecommender = recommender.NewRecommender(mgr.GetClient(),
+			ctrl.Log.WithName("recommender").WithName("JupyterKernelTemplate"),
+			mgr.GetEventRecorderFor("kernel-recommender"),
+			recommender.NewMetricsSource(mgr.GetAPIReader()),
+			recommender.Options{
+				Interval:   cfg.KernelRecommender.Interval.Duration,
+				MinSamples: cfg.KernelRecommender.MinSamples,
+			})
+	}
 	if err = (&controllers.JupyterKernelReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("JupyterKernel"),
-		Scheme: mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("JupyterKernel"),
+		Recorder:                mgr.GetEventRecorderFor("JupyterKernel"),
+		Scheme:                  mgr.GetScheme(),
+		Pods:                    corev1client.NewForConfigOrDie(mgr.GetConfig()),
+		MaxConcurrentReconciles: cfg.ConcurrentReconciles("JupyterKernel"),
+		Options:                 cfg.KernelOptions(),
+		Recommender:             kernelRecommender,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JupyterKernel")
 		os.Exit(1)
 	}
+	if err = (&controllers.JupyterKernelQuotaReconciler{
+		Client:                  mgr.GetClient(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("JupyterKernelQuota"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: cfg.ConcurrentReconciles("JupyterKernelQuota"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "JupyterKernelQuota")
+		os.Exit(1)
+	}
+	if cfg.KernelQueue.Enabled {
+		if err = (&controllers.JupyterKernelQueueReconciler{
+			Client:          mgr.GetClient(),
+			Log:             ctrl.Log.WithName("controllers").WithName("JupyterKernelQueue"),
+			Recorder:        mgr.GetEventRecorderFor("JupyterKernelQueue"),
+			APIReader:       mgr.GetAPIReader(),
+			Interval:        cfg.KernelQueue.Interval.Duration,
+			BatchPeriod:     cfg.KernelQueue.BatchPeriod.Duration,
+			PriorityClasses: cfg.KernelPriorityClasses,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "JupyterKernelQueue")
+			os.Exit(1)
+		}
+	}
+	if cfg.EnableWebhooks {
+		mgr.GetWebhookServer().Register(quota.WebhookPath, &webhook.Admission{
+			Handler: quota.NewValidator(mgr.GetClient(),
+				ctrl.Log.WithName("webhooks").WithName("JupyterKernelQuota")),
+		})
+	}
 	// +kubebuilder:scaffold:builder
 
+	if err := metrics.Register(mgr, cfg.NotebookIdleInterval.Duration); err != nil {
+		setupLog.Error(err, "unable to register the metrics")
+		os.Exit(1)
+	}
+
+	if cfg.KernelGC.Enabled {
+		if err := mgr.Add(gc.NewCollector(mgr.GetClient(),
+			ctrl.Log.WithName("gc").WithName("JupyterKernel"),
+			mgr.GetEventRecorderFor("kernel-gc"),
+			gc.Options{
+				Interval:    cfg.KernelGC.Interval.Duration,
+				GracePeriod: cfg.KernelGC.GracePeriod.Duration,
+				DryRun:      cfg.KernelGC.DryRun,
+			})); err != nil {
+			setupLog.Error(err, "unable to add the kernel garbage collector")
+			os.Exit(1)
+		}
+	}
+
+	if cfg.KernelPreemption.Enabled {
+		if err := mgr.Add(preemption.NewController(mgr.GetClient(), mgr.GetAPIReader(),
+			ctrl.Log.WithName("preemption").WithName("JupyterKernel"),
+			mgr.GetEventRecorderFor("kernel-preemption"),
+			preemption.Options{
+				Interval:        cfg.KernelPreemption.Interval.Duration,
+				MinIdleTime:     cfg.KernelPreemption.MinIdleTime.Duration,
+				PriorityClasses: cfg.KernelPriorityClasses,
+			})); err != nil {
+			setupLog.Error(err, "unable to add the kernel preemption")
+			os.Exit(1)
+		}
+	}
+
+	if kernelRecommender != nil {
+		if err := mgr.Add(kernelRecommender); err != nil {
+			setupLog.Error(err, "unable to add the kernel recommender")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
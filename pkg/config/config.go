@@ -0,0 +1,454 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package config contains the configuration file of the operator.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/yaml"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/gateway"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/notebook"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/podcache"
+)
+
+const (
+	// APIVersion is the API version of the configuration file.
+	APIVersion = "config.kubeflow.tkestack.io/v1alpha1"
+	// Kind is the kind of the configuration file.
+	Kind = "OperatorConfiguration"
+
+	defaultLeaderElectionID = "82ec55e3.kubeflow.tkestack.io"
+)
+
+// kinds are the kinds of the controllers, which are the keys of
+// maxConcurrentReconciles.
+var kinds = map[string]bool{
+	"JupyterNotebook":          true,
+	"JupyterGateway":           true,
+	"JupyterKernelSpec":        true,
+	"ClusterJupyterKernelSpec": true,
+	"JupyterKernelTemplate":    true,
+	"JupyterKernel":            true,
+	"JupyterKernelQuota":       true,
+}
+
+// OperatorConfiguration is the configuration of the operator.
+type OperatorConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Namespaces are the namespaces watched by the operator. All the
+	// namespaces are watched if it is empty. The cluster-scoped resources,
+	// e.g. ClusterJupyterKernelSpec, are not reconciled if it is set, thus
+	// the operator only needs the permissions in the namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// MetricsBindAddress is the address the metric endpoint binds to.
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+	// HealthProbeBindAddress is the address the probe endpoint binds to.
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+	// WebhookPort is the port the webhook server serves at.
+	WebhookPort int `json:"webhookPort,omitempty"`
+	// EnableWebhooks enables the admission webhooks, e.g. the quotas of
+	// the kernels. The serving certificates are required.
+	EnableWebhooks bool `json:"enableWebhooks,omitempty"`
+
+	// SyncPeriod is the minimum interval at which the watched resources
+	// are reconciled.
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+	// NotebookIdleInterval is the interval to poll the idle time of the
+	// notebooks for the metrics.
+	NotebookIdleInterval *metav1.Duration `json:"notebookIdleInterval,omitempty"`
+
+	LeaderElection LeaderElection `json:"leaderElection,omitempty"`
+
+	Images Images `json:"images,omitempty"`
+
+	// GatewayClusterRole is the default ClusterRole of the gateways, which
+	// is used to create the kernel pods.
+	GatewayClusterRole string `json:"gatewayClusterRole,omitempty"`
+	// GatewayClusterTemplateRole is the ClusterRole bound to the gateways
+	// with the cluster kernels, which allows the kernel launcher to read
+	// the ClusterJupyterKernelTemplates.
+	GatewayClusterTemplateRole string `json:"gatewayClusterTemplateRole,omitempty"`
+
+	// MaxConcurrentReconciles is the maximum number of concurrent
+	// reconciles per controller, keyed by the kind, e.g. JupyterGateway.
+	// It defaults to 1.
+	MaxConcurrentReconciles map[string]int `json:"maxConcurrentReconciles,omitempty"`
+
+	KernelGC KernelGC `json:"kernelGC,omitempty"`
+
+	KernelLifetime KernelLifetime `json:"kernelLifetime,omitempty"`
+
+	KernelQueue KernelQueue `json:"kernelQueue,omitempty"`
+
+	// KernelPriorityClasses maps the priority tiers of the kernel
+	// templates to the PriorityClasses.
+	KernelPriorityClasses map[v1alpha1.KernelPriorityTier]string `json:"kernelPriorityClasses,omitempty"`
+
+	KernelPreemption KernelPreemption `json:"kernelPreemption,omitempty"`
+
+	KernelRecommender KernelRecommender `json:"kernelRecommender,omitempty"`
+}
+
+// KernelRecommender is the configuration of the recommender of the
+// resources of the kernel templates.
+type KernelRecommender struct {
+	// Enabled enables the recommender, which requires the metrics server.
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval is the interval to sample the usage of the kernels.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// MinSamples is the minimum number of the finished kernels of a
+	// template to recommend the resources.
+	MinSamples int `json:"minSamples,omitempty"`
+}
+
+// KernelPreemption is the configuration of the preemption of the idle
+// kernels with lower priority.
+type KernelPreemption struct {
+	// Enabled enables the preemption.
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval is the interval to check the pending kernels.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// MinIdleTime is the minimum duration since the last activity of the
+	// kernels which can be preempted.
+	MinIdleTime *metav1.Duration `json:"minIdleTime,omitempty"`
+}
+
+// KernelQueue is the configuration of the admission queue of the kernels.
+type KernelQueue struct {
+	// Enabled enables the queueing mode, in which the kernels wait in the
+	// queue until there are enough free resources in the cluster.
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval is the interval to retry the kernels in the queue.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// BatchPeriod is the delay of the retry after the kernels change. The
+	// changes in the period are merged into one retry.
+	BatchPeriod *metav1.Duration `json:"batchPeriod,omitempty"`
+}
+
+// KernelLifetime is the default lifetime of the kernels which do not set
+// maxLifetime or ttlSecondsAfterFinished in the kernel templates.
+type KernelLifetime struct {
+	// KernelLifetime is the default of all the namespaces.
+	v1alpha1.KernelLifetime `json:",inline"`
+	// Namespaces are the defaults per namespace, which take precedence
+	// over the default of all the namespaces.
+	Namespaces map[string]v1alpha1.KernelLifetime `json:"namespaces,omitempty"`
+	// WarningPeriod is the duration before the deadline of the kernel, at
+	// which the warning event is emitted.
+	WarningPeriod *metav1.Duration `json:"warningPeriod,omitempty"`
+}
+
+// KernelGC is the configuration of the garbage collector of the kernels
+// which are not tracked by the gateways.
+type KernelGC struct {
+	// Enabled enables the garbage collector.
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval is the interval to check the kernels of the gateways.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// GracePeriod is the duration for which a kernel stays orphaned
+	// before it is deleted.
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+	// DryRun only reports the orphaned kernels by the events and the
+	// metrics without changing them.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// LeaderElection is the configuration of the leader election.
+type LeaderElection struct {
+	// LeaderElect enables the leader election.
+	LeaderElect bool `json:"leaderElect,omitempty"`
+	// ResourceName is the name of the resource used for the lock.
+	ResourceName string `json:"resourceName,omitempty"`
+	// ResourceNamespace is the namespace of the resource used for the
+	// lock. It defaults to the namespace of the operator.
+	ResourceNamespace string `json:"resourceNamespace,omitempty"`
+	// LeaseDuration is the duration that non-leader candidates will wait
+	// to force acquire leadership.
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+	// RenewDeadline is the duration that the acting leader will retry
+	// refreshing leadership before giving up.
+	RenewDeadline *metav1.Duration `json:"renewDeadline,omitempty"`
+	// RetryPeriod is the duration the clients should wait between
+	// tries of actions.
+	RetryPeriod *metav1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// Images are the default images of the resources created by the operator.
+type Images struct {
+	// Gateway is the image of the gateways.
+	Gateway string `json:"gateway,omitempty"`
+	// Kernel is the default kernel image of the gateways.
+	Kernel string `json:"kernel,omitempty"`
+	// Notebook is the image of the notebooks without the template.
+	Notebook string `json:"notebook,omitempty"`
+}
+
+// Default returns the default configuration.
+func Default() *OperatorConfiguration {
+	return &OperatorConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: APIVersion,
+			Kind:       Kind,
+		},
+		MetricsBindAddress:     ":8080",
+		HealthProbeBindAddress: ":8081",
+		WebhookPort:            9443,
+		NotebookIdleInterval:   &metav1.Duration{Duration: time.Minute},
+		LeaderElection: LeaderElection{
+			ResourceName: defaultLeaderElectionID,
+		},
+		Images: Images{
+			Gateway:  gateway.DefaultGatewayImage,
+			Kernel:   gateway.DefaultKernelImage,
+			Notebook: notebook.DefaultNotebookImage,
+		},
+		GatewayClusterRole:         gateway.DefaultClusterRole,
+		GatewayClusterTemplateRole: gateway.DefaultClusterTemplateRole,
+		KernelGC: KernelGC{
+			Enabled:     true,
+			Interval:    &metav1.Duration{Duration: time.Minute},
+			GracePeriod: &metav1.Duration{Duration: 5 * time.Minute},
+		},
+		KernelLifetime: KernelLifetime{
+			WarningPeriod: &metav1.Duration{Duration: kernel.DefaultLifetimeWarningPeriod},
+		},
+		KernelQueue: KernelQueue{
+			Interval:    &metav1.Duration{Duration: 30 * time.Second},
+			BatchPeriod: &metav1.Duration{Duration: 2 * time.Second},
+		},
+		KernelPriorityClasses: kernel.DefaultPriorityClasses(),
+		KernelPreemption: KernelPreemption{
+			Interval:    &metav1.Duration{Duration: 30 * time.Second},
+			MinIdleTime: &metav1.Duration{Duration: 5 * time.Minute},
+		},
+		KernelRecommender: KernelRecommender{
+			Interval:   &metav1.Duration{Duration: time.Minute},
+			MinSamples: 5,
+		},
+	}
+}
+
+// Load reads the configuration file. The fields which are not set in the
+// file keep the defaults.
+func Load(path string) (*OperatorConfiguration, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := Default()
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("failed to parse the configuration file %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks the configuration.
+func (c OperatorConfiguration) Validate() error {
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		return fmt.Errorf("expected %s %s, got %s %s",
+			APIVersion, Kind, c.APIVersion, c.Kind)
+	}
+	for kind, n := range c.MaxConcurrentReconciles {
+		if !kinds[kind] {
+			return fmt.Errorf("unknown kind %q in maxConcurrentReconciles", kind)
+		}
+		if n < 1 {
+			return fmt.Errorf("maxConcurrentReconciles of %s should be positive", kind)
+		}
+	}
+	if c.NotebookIdleInterval == nil || c.NotebookIdleInterval.Duration <= 0 {
+		return fmt.Errorf("notebookIdleInterval should be positive")
+	}
+	if c.KernelGC.Interval == nil || c.KernelGC.Interval.Duration <= 0 {
+		return fmt.Errorf("kernelGC.interval should be positive")
+	}
+	if c.KernelGC.GracePeriod == nil || c.KernelGC.GracePeriod.Duration < 0 {
+		return fmt.Errorf("kernelGC.gracePeriod should not be negative")
+	}
+	if err := validateLifetime("kernelLifetime", c.KernelLifetime.KernelLifetime); err != nil {
+		return err
+	}
+	for ns, l := range c.KernelLifetime.Namespaces {
+		if err := validateLifetime("kernelLifetime.namespaces."+ns, l); err != nil {
+			return err
+		}
+	}
+	if c.KernelLifetime.WarningPeriod == nil || c.KernelLifetime.WarningPeriod.Duration < 0 {
+		return fmt.Errorf("kernelLifetime.warningPeriod should not be negative")
+	}
+	if c.KernelQueue.Interval == nil || c.KernelQueue.Interval.Duration <= 0 {
+		return fmt.Errorf("kernelQueue.interval should be positive")
+	}
+	if c.KernelQueue.BatchPeriod == nil || c.KernelQueue.BatchPeriod.Duration < 0 {
+		return fmt.Errorf("kernelQueue.batchPeriod should not be negative")
+	}
+	// The free resources of the nodes cannot be seen in the namespaced
+	// mode.
+	if c.KernelQueue.Enabled && c.Namespaced() {
+		return fmt.Errorf("kernelQueue cannot be enabled in the namespaced mode")
+	}
+	for tier, class := range c.KernelPriorityClasses {
+		switch tier {
+		case v1alpha1.KernelPriorityInteractive, v1alpha1.KernelPriorityBatch,
+			v1alpha1.KernelPriorityBestEffort:
+		default:
+			return fmt.Errorf("unknown priority tier %q in kernelPriorityClasses", tier)
+		}
+		if class == "" {
+			return fmt.Errorf("empty PriorityClass of %s in kernelPriorityClasses", tier)
+		}
+	}
+	if c.KernelPreemption.Interval == nil || c.KernelPreemption.Interval.Duration <= 0 {
+		return fmt.Errorf("kernelPreemption.interval should be positive")
+	}
+	if c.KernelPreemption.MinIdleTime == nil || c.KernelPreemption.MinIdleTime.Duration < 0 {
+		return fmt.Errorf("kernelPreemption.minIdleTime should not be negative")
+	}
+	if c.KernelPreemption.Enabled && c.Namespaced() {
+		return fmt.Errorf("kernelPreemption cannot be enabled in the namespaced mode")
+	}
+	if c.KernelRecommender.Interval == nil || c.KernelRecommender.Interval.Duration <= 0 {
+		return fmt.Errorf("kernelRecommender.interval should be positive")
+	}
+	if c.KernelRecommender.MinSamples < 1 {
+		return fmt.Errorf("kernelRecommender.minSamples should be positive")
+	}
+	// The ClusterJupyterKernelTemplates cannot be updated in the namespaced
+	// mode.
+	if c.KernelRecommender.Enabled && c.Namespaced() {
+		return fmt.Errorf("kernelRecommender cannot be enabled in the namespaced mode")
+	}
+	for _, ns := range c.Namespaces {
+		if ns == "" {
+			return fmt.Errorf("empty namespace in namespaces")
+		}
+	}
+	return nil
+}
+
+// Namespaced returns true if the operator only watches the given
+// namespaces.
+func (c OperatorConfiguration) Namespaced() bool {
+	return len(c.Namespaces) != 0
+}
+
+// ManagerOptions returns the options of the manager.
+func (c OperatorConfiguration) ManagerOptions(scheme *runtime.Scheme) ctrl.Options {
+	opts := ctrl.Options{
+		Scheme:                  scheme,
+		MetricsBindAddress:      c.MetricsBindAddress,
+		HealthProbeBindAddress:  c.HealthProbeBindAddress,
+		Port:                    c.WebhookPort,
+		LeaderElection:          c.LeaderElection.LeaderElect,
+		LeaderElectionID:        c.LeaderElection.ResourceName,
+		LeaderElectionNamespace: c.LeaderElection.ResourceNamespace,
+		SyncPeriod:              duration(c.SyncPeriod),
+		LeaseDuration:           duration(c.LeaderElection.LeaseDuration),
+		RenewDeadline:           duration(c.LeaderElection.RenewDeadline),
+		RetryPeriod:             duration(c.LeaderElection.RetryPeriod),
+	}
+	newCache := cache.New
+	switch len(c.Namespaces) {
+	case 0:
+	case 1:
+		opts.Namespace = c.Namespaces[0]
+	default:
+		newCache = cache.MultiNamespacedCacheBuilder(c.Namespaces)
+	}
+	// Only the pods of the notebooks and the kernels are cached.
+	opts.NewCache = podcache.NewCacheFunc(newCache)
+	return opts
+}
+
+// ConcurrentReconciles returns the maximum number of concurrent reconciles
+// of the controller of the kind.
+func (c OperatorConfiguration) ConcurrentReconciles(kind string) int {
+	if n, ok := c.MaxConcurrentReconciles[kind]; ok {
+		return n
+	}
+	return 1
+}
+
+// GatewayOptions returns the options of the gateways.
+func (c OperatorConfiguration) GatewayOptions() gateway.Options {
+	return gateway.Options{
+		GatewayImage:        c.Images.Gateway,
+		KernelImage:         c.Images.Kernel,
+		ClusterRole:         c.GatewayClusterRole,
+		ClusterTemplateRole: c.GatewayClusterTemplateRole,
+		Namespaced:          c.Namespaced(),
+	}
+}
+
+// NotebookOptions returns the options of the notebooks.
+func (c OperatorConfiguration) NotebookOptions() notebook.Options {
+	return notebook.Options{
+		Image: c.Images.Notebook,
+	}
+}
+
+// KernelOptions returns the options of the kernels.
+func (c OperatorConfiguration) KernelOptions() kernel.Options {
+	opts := kernel.DefaultOptions()
+	opts.Lifetimes = map[string]v1alpha1.KernelLifetime{
+		"": c.KernelLifetime.KernelLifetime,
+	}
+	for ns, l := range c.KernelLifetime.Namespaces {
+		opts.Lifetimes[ns] = l
+	}
+	if c.KernelLifetime.WarningPeriod != nil {
+		opts.LifetimeWarningPeriod = c.KernelLifetime.WarningPeriod.Duration
+	}
+	opts.QueueEnabled = c.KernelQueue.Enabled
+	for tier, class := range c.KernelPriorityClasses {
+		opts.PriorityClasses[tier] = class
+	}
+	return opts
+}
+
+func validateLifetime(field string, l v1alpha1.KernelLifetime) error {
+	if l.MaxLifetime != nil && l.MaxLifetime.Duration <= 0 {
+		return fmt.Errorf("%s.maxLifetime should be positive", field)
+	}
+	if l.TTLSecondsAfterFinished != nil && *l.TTLSecondsAfterFinished < 0 {
+		return fmt.Errorf("%s.ttlSecondsAfterFinished should not be negative", field)
+	}
+	return nil
+}
+
+func duration(d *metav1.Duration) *time.Duration {
+	if d == nil {
+		return nil
+	}
+	v := d.Duration
+	return &v
+}
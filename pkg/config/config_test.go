@@ -0,0 +1,207 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "config-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+apiVersion: config.kubeflow.tkestack.io/v1alpha1
+kind: OperatorConfiguration
+namespaces:
+  - team-a
+syncPeriod: 1h
+leaderElection:
+  leaderElect: true
+  leaseDuration: 30s
+images:
+  notebook: jupyter/scipy-notebook:latest
+maxConcurrentReconciles:
+  JupyterKernel: 4
+kernelGC:
+  dryRun: true
+kernelPriorityClasses:
+  batch: team-a-batch
+kernelLifetime:
+  maxLifetime: 24h
+  namespaces:
+    team-a:
+      maxLifetime: 8h
+      ttlSecondsAfterFinished: 600
+`)
+	defer os.Remove(path)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Namespaced() {
+		t.Errorf("Expected the namespaced mode")
+	}
+	if c.Images.Notebook != "jupyter/scipy-notebook:latest" {
+		t.Errorf("Expected the notebook image to be overridden, got %s", c.Images.Notebook)
+	}
+	if c.Images.Gateway != Default().Images.Gateway {
+		t.Errorf("Expected the default gateway image, got %s", c.Images.Gateway)
+	}
+	if !c.KernelGC.Enabled || !c.KernelGC.DryRun ||
+		c.KernelGC.GracePeriod.Duration != 5*time.Minute {
+		t.Errorf("Expected the kernel GC in the dry-run mode with the default grace period, got %v",
+			c.KernelGC)
+	}
+	if l := c.KernelLifetime.MaxLifetime; l == nil || l.Duration != 24*time.Hour {
+		t.Errorf("Expected the default max lifetime 24h, got %v", l)
+	}
+	if l := c.KernelLifetime.Namespaces["team-a"]; l.TTLSecondsAfterFinished == nil ||
+		*l.TTLSecondsAfterFinished != 600 {
+		t.Errorf("Expected the TTL of team-a to be 600, got %v", l.TTLSecondsAfterFinished)
+	}
+	if c.KernelPriorityClasses[v1alpha1.KernelPriorityBatch] != "team-a-batch" ||
+		c.KernelPriorityClasses[v1alpha1.KernelPriorityInteractive] != "jupyter-kernel-interactive" {
+		t.Errorf("Expected the batch PriorityClass to be overridden, got %v", c.KernelPriorityClasses)
+	}
+	if image := c.NotebookOptions().Image; image != "jupyter/scipy-notebook:latest" {
+		t.Errorf("Expected the notebook image in the options, got %s", image)
+	}
+	kernelOpts := c.KernelOptions()
+	if l := kernelOpts.Lifetimes["team-a"].MaxLifetime; l == nil || l.Duration != 8*time.Hour {
+		t.Errorf("Expected the max lifetime of team-a to be 8h, got %v", l)
+	}
+	if class := kernelOpts.PriorityClasses[v1alpha1.KernelPriorityBatch]; class != "team-a-batch" {
+		t.Errorf("Expected the batch PriorityClass in the options, got %s", class)
+	}
+	if n := c.ConcurrentReconciles("JupyterKernel"); n != 4 {
+		t.Errorf("Expected 4 concurrent reconciles, got %d", n)
+	}
+	if n := c.ConcurrentReconciles("JupyterGateway"); n != 1 {
+		t.Errorf("Expected 1 concurrent reconcile, got %d", n)
+	}
+
+	opts := c.ManagerOptions(runtime.NewScheme())
+	if opts.Namespace != "team-a" {
+		t.Errorf("Expected the cache in namespace team-a, got %q", opts.Namespace)
+	}
+	if !opts.LeaderElection || opts.LeaderElectionID != defaultLeaderElectionID {
+		t.Errorf("Expected the leader election with the default ID, got %v %s",
+			opts.LeaderElection, opts.LeaderElectionID)
+	}
+	if opts.LeaseDuration == nil || *opts.LeaseDuration != 30*time.Second {
+		t.Errorf("Expected the lease duration 30s, got %v", opts.LeaseDuration)
+	}
+	if opts.SyncPeriod == nil || *opts.SyncPeriod != time.Hour {
+		t.Errorf("Expected the sync period 1h, got %v", opts.SyncPeriod)
+	}
+	if opts.RenewDeadline != nil {
+		t.Errorf("Expected the default renew deadline, got %v", opts.RenewDeadline)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "unknown field",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nnamespace: team-a\n",
+		},
+		{
+			name:    "wrong kind",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: Configuration\n",
+		},
+		{
+			name:    "zero gc interval",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nkernelGC:\n  interval: 0s\n",
+		},
+		{
+			name:    "negative ttl",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nkernelLifetime:\n  namespaces:\n    team-a:\n      ttlSecondsAfterFinished: -1\n",
+		},
+		{
+			name:    "negative queue batch period",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nkernelQueue:\n  batchPeriod: -1s\n",
+		},
+		{
+			name:    "queue in namespaced mode",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nnamespaces:\n  - team-a\nkernelQueue:\n  enabled: true\n",
+		},
+		{
+			name:    "unknown priority tier",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nkernelPriorityClasses:\n  realtime: jupyter-kernel-realtime\n",
+		},
+		{
+			name:    "zero recommender samples",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nkernelRecommender:\n  minSamples: 0\n",
+		},
+		{
+			name:    "zero concurrency",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nmaxConcurrentReconciles:\n  JupyterKernel: 0\n",
+		},
+		{
+			name:    "unknown concurrency kind",
+			content: "apiVersion: config.kubeflow.tkestack.io/v1alpha1\nkind: OperatorConfiguration\nmaxConcurrentReconciles:\n  JupyterKernels: 4\n",
+		},
+	}
+	for _, test := range tests {
+		path := writeConfig(t, test.content)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: Expected an error", test.name)
+		}
+		os.Remove(path)
+	}
+}
+
+func TestMultipleNamespaces(t *testing.T) {
+	c := Default()
+	c.Namespaces = []string{"team-a", "team-b"}
+	opts := c.ManagerOptions(runtime.NewScheme())
+	if opts.Namespace != "" || opts.NewCache == nil {
+		t.Errorf("Expected the multi-namespace cache")
+	}
+}
+
+func TestLoadSamples(t *testing.T) {
+	for _, path := range []string{
+		"../../config/manager/controller_manager_config.yaml",
+		"../../config/namespaced/controller_manager_config.yaml",
+	} {
+		if _, err := Load(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	kernelsPath = "/api/kernels"
+
+	// ExecutionStateIdle is the execution state of the kernels which are
+	// not executing any code.
+	ExecutionStateIdle = "idle"
+)
+
+// Kernel is the kernel model in the response of the kernels API of the
+// gateway.
+type Kernel struct {
+	ID string `json:"id"`
+	// ExecutionState is the state of the kernel, e.g. idle or busy.
+	ExecutionState string `json:"execution_state"`
+	// LastActivity is the time of the last message from or to the kernel.
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// ListKernels returns the kernels tracked by the gateway at the URL.
+func ListKernels(cli *http.Client, url string) ([]Kernel, error) {
+	resp, err := cli.Get(url + kernelsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	kernels := []Kernel{}
+	if err := json.NewDecoder(resp.Body).Decode(&kernels); err != nil {
+		return nil, err
+	}
+	return kernels, nil
+}
@@ -0,0 +1,207 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+const (
+	// Finalizer is the finalizer of the gateways, which shuts down the
+	// kernels launched by the gateway before the gateway is deleted.
+	Finalizer = "kubeflow.tkestack.io/kernels"
+
+	defaultKernelShutdownTimeout = 30 * time.Second
+	kernelShutdownPollInterval   = 5 * time.Second
+	kernelShutdownRequestTimeout = 10 * time.Second
+)
+
+var shutdownClient = &http.Client{Timeout: kernelShutdownRequestTimeout}
+
+// EnsureFinalizer adds the finalizer to the gateway.
+func (r Reconciler) EnsureFinalizer() error {
+	if controllerutil.ContainsFinalizer(r.instance, Finalizer) {
+		return nil
+	}
+	controllerutil.AddFinalizer(r.instance, Finalizer)
+	if err := r.cli.Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to add the finalizer to the gateway")
+		return err
+	}
+	return nil
+}
+
+// Finalize cleans up the kernels of the deleted gateway. The running
+// kernels are asked to shut down through the gateway first. The kernels
+// which are still there after the timeout are deleted, and then the
+// finalizer is removed. It returns the duration after which it should be
+// called again if it is waiting for the kernels to shut down.
+func (r Reconciler) Finalize() (time.Duration, error) {
+	if !controllerutil.ContainsFinalizer(r.instance, Finalizer) {
+		return 0, nil
+	}
+
+	kernels, err := Kernels(r.cli, r.instance)
+	if err != nil {
+		r.log.Error(err, "Failed to list the kernels")
+		return 0, err
+	}
+
+	deadline := r.instance.DeletionTimestamp.Add(r.kernelShutdownTimeout())
+	if remaining := time.Until(deadline); len(kernels) != 0 && remaining > 0 {
+		if r.shutdownKernels(kernels) {
+			if remaining < kernelShutdownPollInterval {
+				return remaining, nil
+			}
+			return kernelShutdownPollInterval, nil
+		}
+	}
+
+	for i := range kernels {
+		k := &kernels[i]
+		r.log.Info("Deleting kernel", "namespace", k.Namespace, "name", k.Name)
+		if err := r.cli.Delete(context.TODO(), k); err != nil && !errors.IsNotFound(err) {
+			r.log.Error(err, "Failed to delete the kernel", "kernel", k.Name)
+			return 0, err
+		}
+	}
+	if len(kernels) != 0 {
+		r.recorder.Eventf(r.instance, v1.EventTypeNormal, "DeletedKernels",
+			"Deleted %d kernels which were not shut down by the gateway", len(kernels))
+	}
+
+	// The cluster role binding cannot be owned by the gateway, thus it is
+	// deleted here. It is never created in the namespaced mode.
+	if !r.gen.opts.Namespaced {
+		crb := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: ClusterRoleBindingName(r.instance)},
+		}
+		if err := r.cli.Delete(context.TODO(), crb); err != nil && !errors.IsNotFound(err) {
+			r.log.Error(err, "Failed to delete the clusterrolebinding",
+				"clusterrolebinding", crb.Name)
+			return 0, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(r.instance, Finalizer)
+	if err := r.cli.Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to remove the finalizer from the gateway")
+		return 0, err
+	}
+	return 0, nil
+}
+
+// Kernels returns the kernels launched by the gateway in all namespaces,
+// which are either labeled with the gateway or controlled by it.
+func Kernels(cli client.Client, gateway *v1alpha1.JupyterGateway) ([]v1alpha1.JupyterKernel, error) {
+	labeled := &v1alpha1.JupyterKernelList{}
+	if err := cli.List(context.TODO(), labeled,
+		client.MatchingLabels(launcher.GatewayLabels(gateway))); err != nil {
+		return nil, err
+	}
+	owned := &v1alpha1.JupyterKernelList{}
+	if err := cli.List(context.TODO(), owned,
+		client.InNamespace(gateway.Namespace)); err != nil {
+		return nil, err
+	}
+
+	kernels := labeled.Items
+	seen := make(map[string]bool, len(kernels))
+	for _, k := range kernels {
+		seen[k.Namespace+"/"+k.Name] = true
+	}
+	for _, k := range owned.Items {
+		if metav1.IsControlledBy(&k, gateway) && !seen[k.Namespace+"/"+k.Name] {
+			kernels = append(kernels, k)
+		}
+	}
+	return kernels, nil
+}
+
+// URL returns the URL of the REST API of the gateway.
+func URL(gateway *v1alpha1.JupyterGateway) string {
+	return fmt.Sprintf("http://%s.%s:%d", gateway.Name, gateway.Namespace, defaultPort)
+}
+
+// shutdownKernels asks the gateway to shut down the kernels. It returns
+// true if any kernel is shutting down, which is worth waiting for.
+func (r Reconciler) shutdownKernels(kernels []v1alpha1.JupyterKernel) bool {
+	waiting := false
+	for _, k := range kernels {
+		if k.DeletionTimestamp != nil {
+			waiting = true
+			continue
+		}
+		id := kernel.ID(&k)
+		if id == "" {
+			continue
+		}
+		ok, err := shutdownKernel(r.gatewayURL, id)
+		if err != nil {
+			r.log.V(1).Info("Failed to shut down the kernel through the gateway",
+				"kernel", k.Name, "error", err.Error())
+			continue
+		}
+		waiting = waiting || ok
+	}
+	return waiting
+}
+
+// shutdownKernel shuts down the kernel through the REST API of the
+// gateway. It returns false if the kernel is not found in the gateway.
+func shutdownKernel(url, id string) (bool, error) {
+	req, err := http.NewRequest(http.MethodDelete,
+		fmt.Sprintf("%s/api/kernels/%s", url, id), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := shutdownClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+func (r Reconciler) kernelShutdownTimeout() time.Duration {
+	if t := r.instance.Spec.KernelShutdownTimeoutSeconds; t != nil {
+		return time.Duration(*t) * time.Second
+	}
+	return defaultKernelShutdownTimeout
+}
@@ -0,0 +1,253 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+func newDeletedGateway(deleted time.Time) *v1alpha1.JupyterGateway {
+	ts := metav1.NewTime(deleted)
+	return &v1alpha1.JupyterGateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              "gateway",
+			UID:               "gateway-uid",
+			DeletionTimestamp: &ts,
+			Finalizers:        []string{Finalizer},
+		},
+	}
+}
+
+func newGatewayKernel(gw *v1alpha1.JupyterGateway, namespace, name, id string) *v1alpha1.JupyterKernel {
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    launcher.GatewayLabels(gw),
+		},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{Name: "kernel", Env: []v1.EnvVar{{Name: "KERNEL_ID", Value: id}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newFinalizeReconciler(t *testing.T, gw *v1alpha1.JupyterGateway,
+	url string, objs ...runtime.Object) (*Reconciler, client.Client) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	cli := fake.NewFakeClientWithScheme(s, append(objs, gw.DeepCopy())...)
+	r, err := NewReconciler(cli, logr.Logger(logf.NullLogger{}),
+		record.NewFakeRecorder(10), s, gw, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.gatewayURL = url
+	return r, cli
+}
+
+func TestFinalizeShutdownKernels(t *testing.T) {
+	requested := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodDelete {
+			t.Errorf("Expected DELETE, got %s", req.Method)
+		}
+		requested[req.URL.Path] = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	gw := newDeletedGateway(time.Now())
+	r, cli := newFinalizeReconciler(t, gw, server.URL,
+		newGatewayKernel(gw, "default", "a", "id-a"),
+		newGatewayKernel(gw, "team", "b", "id-b"),
+	)
+
+	requeueAfter, err := r.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeueAfter == 0 {
+		t.Errorf("Expected to wait for the kernels to shut down")
+	}
+	for _, path := range []string{"/api/kernels/id-a", "/api/kernels/id-b"} {
+		if !requested[path] {
+			t.Errorf("Expected the request %s", path)
+		}
+	}
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := cli.List(context.TODO(), kernels); err != nil {
+		t.Fatal(err)
+	}
+	if len(kernels.Items) != 2 {
+		t.Errorf("Expected the kernels to be kept, got %d", len(kernels.Items))
+	}
+}
+
+func TestFinalizeAfterTimeout(t *testing.T) {
+	gw := newDeletedGateway(time.Now().Add(-time.Hour))
+	other := newGatewayKernel(gw, "team", "other", "id-other")
+	other.Labels[launcher.LabelGatewayName] = "other"
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: ClusterRoleBindingName(gw)},
+	}
+	r, cli := newFinalizeReconciler(t, gw, "http://127.0.0.1:0",
+		newGatewayKernel(gw, "default", "a", "id-a"),
+		newGatewayKernel(gw, "team", "b", "id-b"),
+		other, crb,
+	)
+
+	requeueAfter, err := r.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeueAfter != 0 {
+		t.Errorf("Expected no requeue, got %v", requeueAfter)
+	}
+
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := cli.List(context.TODO(), kernels); err != nil {
+		t.Fatal(err)
+	}
+	if len(kernels.Items) != 1 || kernels.Items[0].Name != "other" {
+		t.Errorf("Expected only the kernel of the other gateway, got %v", kernels.Items)
+	}
+	err = cli.Get(context.TODO(), types.NamespacedName{Name: crb.Name}, &rbacv1.ClusterRoleBinding{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected the clusterrolebinding to be deleted, got %v", err)
+	}
+	actual := &v1alpha1.JupyterGateway{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: gw.Namespace, Name: gw.Name}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.Finalizers) != 0 {
+		t.Errorf("Expected the finalizer to be removed, got %v", actual.Finalizers)
+	}
+}
+
+func TestFinalizeUnreachableGateway(t *testing.T) {
+	gw := newDeletedGateway(time.Now())
+	r, cli := newFinalizeReconciler(t, gw, "http://127.0.0.1:0",
+		newGatewayKernel(gw, "default", "a", "id-a"),
+	)
+
+	if _, err := r.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := cli.List(context.TODO(), kernels); err != nil {
+		t.Fatal(err)
+	}
+	if len(kernels.Items) != 0 {
+		t.Errorf("Expected the kernels to be deleted without waiting, got %d", len(kernels.Items))
+	}
+}
+
+// namespacedClient rejects the cluster scoped requests like the API server
+// does for an operator which is only allowed to access some namespaces.
+type namespacedClient struct {
+	client.Client
+}
+
+func (c namespacedClient) forbidden(obj runtime.Object) error {
+	if _, ok := obj.(*rbacv1.ClusterRoleBinding); ok {
+		return errors.NewForbidden(rbacv1.Resource("clusterrolebindings"), "", nil)
+	}
+	return nil
+}
+
+func (c namespacedClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if err := c.forbidden(obj); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c namespacedClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if err := c.forbidden(obj); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c namespacedClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	if err := c.forbidden(obj); err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestNamespacedClusterRoleBinding(t *testing.T) {
+	gw := newDeletedGateway(time.Now().Add(-time.Hour))
+	sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"}}
+	r, cli := newFinalizeReconciler(t, gw, "http://127.0.0.1:0",
+		newGatewayKernel(gw, "default", "a", "id-a"),
+	)
+	r.cli = namespacedClient{cli}
+	r.gen.opts.Namespaced = true
+
+	for _, kernels := range [][]string{{"python"}, nil} {
+		gw.Spec.ClusterKernels = kernels
+		if err := r.reconcileClusterRoleBinding(sa); err != nil {
+			t.Errorf("Expected the clusterrolebinding to be skipped, got %v", err)
+		}
+	}
+
+	if _, err := r.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	actual := &v1alpha1.JupyterGateway{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: gw.Namespace, Name: gw.Name}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.Finalizers) != 0 {
+		t.Errorf("Expected the finalizer to be removed, got %v", actual.Finalizers)
+	}
+}
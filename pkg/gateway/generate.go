@@ -20,6 +20,7 @@ package gateway
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"strconv"
 	"strings"
@@ -29,20 +30,26 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernelspec"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
 )
 
 const (
-	defaultImage              = "ghcr.io/skai-x/enterprise-gateway:2.6.0"
-	defaultContainerName      = "gateway"
-	defaultKernelImage        = "ghcr.io/skai-x/jupyter-kernel-py:2.6.0"
-	defaultPortName           = "gateway"
-	defaultKernel             = "python_kubernetes"
-	defaultPort               = 8888
-	defaultGatewayClusterRole = "enterprise-gateway-controller"
-	defaultServiceAccount     = "enterprise-gateway-sa"
+	defaultContainerName  = "gateway"
+	defaultPortName       = "gateway"
+	defaultKernel         = "python_kubernetes"
+	defaultPort           = 8888
+	defaultProbePath      = "/api"
+	defaultServiceAccount = "enterprise-gateway-sa"
+	// ProcessProxyClusterRole is the ClusterRole which aggregates the
+	// permissions required by the process proxies, e.g. managing the
+	// SparkApplications. It is provisioned by the cluster admin.
+	ProcessProxyClusterRole = "jupytergateway-process-proxy"
 
 	LabelGateway = "gateway"
 	LabelNS      = "namespace"
@@ -54,22 +61,63 @@ const (
 	defaultKernels    = "'r_kubernetes','python_kubernetes','python_tf_kubernetes','python_tf_gpu_kubernetes','scala_kubernetes','spark_r_kubernetes','spark_python_kubernetes','spark_scala_kubernetes'"
 )
 
+// The defaults of the gateways.
+const (
+	DefaultGatewayImage = "ghcr.io/skai-x/enterprise-gateway:2.6.0"
+	DefaultKernelImage  = "ghcr.io/skai-x/jupyter-kernel-py:2.6.0"
+	DefaultClusterRole  = "enterprise-gateway-controller"
+	// DefaultClusterTemplateRole is the ClusterRole which allows the
+	// launcher to read the ClusterJupyterKernelTemplates.
+	DefaultClusterTemplateRole = "clusterjupyterkerneltemplate-viewer-role"
+)
+
+// Options are the options of the gateways, which are set by the operator
+// configuration. The empty fields default to the constants above.
+type Options struct {
+	// GatewayImage is the image of the gateways.
+	GatewayImage string
+	// KernelImage is the default kernel image of the gateways.
+	KernelImage string
+	// ClusterRole is the default ClusterRole of the gateways.
+	ClusterRole string
+	// ClusterTemplateRole is the ClusterRole bound to the gateways with
+	// the cluster kernels.
+	ClusterTemplateRole string
+	// Namespaced is true if the operator only watches some namespaces, in
+	// which case it cannot manage the ClusterRoleBindings.
+	Namespaced bool
+}
+
 // generator defines the generator which is used to generate
 // desired specs.
 type generator struct {
 	gateway *v1alpha1.JupyterGateway
 	cli     client.Client
+	opts    Options
 }
 
 // newGenerator creates a new Generator.
-func newGenerator(c client.Client, gateway *v1alpha1.JupyterGateway) (
-	*generator, error) {
+func newGenerator(c client.Client, gateway *v1alpha1.JupyterGateway,
+	opts Options) (*generator, error) {
 	if gateway == nil {
 		return nil, fmt.Errorf("Got nil when initializing Generator")
 	}
+	if opts.GatewayImage == "" {
+		opts.GatewayImage = DefaultGatewayImage
+	}
+	if opts.KernelImage == "" {
+		opts.KernelImage = DefaultKernelImage
+	}
+	if opts.ClusterRole == "" {
+		opts.ClusterRole = DefaultClusterRole
+	}
+	if opts.ClusterTemplateRole == "" {
+		opts.ClusterTemplateRole = DefaultClusterTemplateRole
+	}
 	g := &generator{
 		gateway: gateway,
 		cli:     c,
+		opts:    opts,
 	}
 
 	return g, nil
@@ -118,7 +166,35 @@ func (g generator) DesiredRoleBinding(
 			},
 		},
 		RoleRef: rbacv1.RoleRef{
-			Name:     defaultGatewayClusterRole,
+			Name:     g.opts.ClusterRole,
+			Kind:     "ClusterRole",
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}
+	return crb
+}
+
+// DesiredClusterRoleBinding returns the desired cluster role binding,
+// which allows the kernel launcher in the gateway to read the
+// ClusterJupyterKernelTemplates. It cannot be owned by the gateway
+// since owner references cannot cross the namespace boundary.
+func (g generator) DesiredClusterRoleBinding(
+	sa *v1.ServiceAccount) *rbacv1.ClusterRoleBinding {
+	labels := g.labels()
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   ClusterRoleBindingName(g.gateway),
+			Labels: labels,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      sa.Name,
+				Namespace: sa.Namespace,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Name:     g.opts.ClusterTemplateRole,
 			Kind:     "ClusterRole",
 			APIGroup: "rbac.authorization.k8s.io",
 		},
@@ -126,6 +202,20 @@ func (g generator) DesiredRoleBinding(
 	return crb
 }
 
+// ClusterRoleBindingName returns the name of the cluster role binding
+// for the gateway. The hash of the namespace and the name is appended,
+// since the dashes are ambiguous, e.g. a/b-c and a-b/c, and the readable
+// part is truncated to keep the name within the limit.
+func ClusterRoleBindingName(gateway *v1alpha1.JupyterGateway) string {
+	sum := sha256.Sum256([]byte(gateway.Namespace + "/" + gateway.Name))
+	hash := fmt.Sprintf("-%x", sum[:4])
+	name := fmt.Sprintf("jupytergateway-%s-%s", gateway.Namespace, gateway.Name)
+	if max := validation.DNS1123SubdomainMaxLength - len(hash); len(name) > max {
+		name = strings.TrimRight(name[:max], ".-")
+	}
+	return name + hash
+}
+
 func (g generator) DesiredServiceAccountWithoutOwner() *v1.ServiceAccount {
 	labels := g.labels()
 	sa := &v1.ServiceAccount{
@@ -173,7 +263,7 @@ func (g generator) DesiredDeploymentWithoutOwner(
 					Containers: []v1.Container{
 						{
 							Name:            defaultContainerName,
-							Image:           defaultImage,
+							Image:           g.opts.GatewayImage,
 							ImagePullPolicy: v1.PullIfNotPresent,
 							Ports: []v1.ContainerPort{
 								{
@@ -224,9 +314,8 @@ func (g generator) DesiredDeploymentWithoutOwner(
 									Value: "true",
 								},
 								{
-									// TODO(gaocegege): Make it configurable.
 									Name:  "EG_MIRROR_WORKING_DIRS",
-									Value: "false",
+									Value: strconv.FormatBool(g.gateway.Spec.MirrorWorkingDirs),
 								},
 								{
 									Name:  "EG_CULL_IDLE_TIMEOUT",
@@ -238,7 +327,7 @@ func (g generator) DesiredDeploymentWithoutOwner(
 								},
 								{
 									Name:  "EG_KERNEL_IMAGE",
-									Value: defaultKernelImage,
+									Value: g.opts.KernelImage,
 								},
 							},
 						},
@@ -277,13 +366,57 @@ func (g generator) DesiredDeploymentWithoutOwner(
 		d.Spec.Template.Spec.Containers[0].Env = append(
 			d.Spec.Template.Spec.Containers[0].Env, env)
 	}
+	// The reserved env vars are not allowed, which would override the ones
+	// set by the gateway and the kernel launcher.
+	allowlist := []string{}
+	for _, name := range g.gateway.Spec.KernelEnvAllowlist {
+		if !launcher.ReservedEnv(name) {
+			allowlist = append(allowlist, name)
+		}
+	}
+	if len(allowlist) != 0 {
+		env := v1.EnvVar{
+			Name:  "EG_ENV_WHITELIST",
+			Value: strings.Join(allowlist, ","),
+		}
+		d.Spec.Template.Spec.Containers[0].Env = append(
+			d.Spec.Template.Spec.Containers[0].Env, env)
+	}
 	if g.gateway.Spec.Resources != nil {
 		d.Spec.Template.Spec.Containers[0].Resources = *g.gateway.Spec.Resources
 	}
 
+	d.Spec.Template.Spec.Containers[0].LivenessProbe = defaultProbe(10)
+	if g.gateway.Spec.LivenessProbe != nil {
+		d.Spec.Template.Spec.Containers[0].LivenessProbe = g.gateway.Spec.LivenessProbe.DeepCopy()
+	}
+	d.Spec.Template.Spec.Containers[0].ReadinessProbe = defaultProbe(0)
+	if g.gateway.Spec.ReadinessProbe != nil {
+		d.Spec.Template.Spec.Containers[0].ReadinessProbe = g.gateway.Spec.ReadinessProbe.DeepCopy()
+	}
+
 	return d, nil
 }
 
+// defaultProbe returns the probe which requests the API of the gateway.
+// All the fields are set to keep the deployment stable after defaulting.
+func defaultProbe(initialDelaySeconds int32) *v1.Probe {
+	return &v1.Probe{
+		Handler: v1.Handler{
+			HTTPGet: &v1.HTTPGetAction{
+				Path:   defaultProbePath,
+				Port:   intstr.FromString(defaultPortName),
+				Scheme: v1.URISchemeHTTP,
+			},
+		},
+		InitialDelaySeconds: initialDelaySeconds,
+		TimeoutSeconds:      5,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
 func (g generator) volumeMounts(
 	volumes []v1.Volume) []v1.VolumeMount {
 	volumeMounts := []v1.VolumeMount{}
@@ -298,7 +431,39 @@ func (g generator) volumeMounts(
 }
 
 func (g generator) volumes() ([]v1.Volume, error) {
+	specs, err := g.kernelSpecs()
+	if err != nil {
+		return nil, err
+	}
 	volumes := []v1.Volume{}
+	for _, ks := range specs {
+		volumes = append(volumes, kernelspec.DesiredVolume(
+			ks.name, ks.configMap, ks.spec))
+	}
+	return volumes, nil
+}
+
+// kernelSpec is the kernel spec included in the gateway.
+type kernelSpec struct {
+	name      string
+	configMap string
+	spec      *v1alpha1.JupyterKernelSpecSpec
+}
+
+// kernelSpecs returns the kernel specs and the cluster kernel specs
+// included in the gateway.
+func (g generator) kernelSpecs() ([]kernelSpec, error) {
+	// The kernel specs are mounted by the names, thus a name cannot be
+	// used by both a kernel spec and a cluster kernel spec.
+	for _, k := range g.gateway.Spec.ClusterKernels {
+		for _, n := range g.gateway.Spec.Kernels {
+			if k == n {
+				return nil, fmt.Errorf(
+					"kernel %s is in both kernels and clusterKernels", k)
+			}
+		}
+	}
+	specs := []kernelSpec{}
 	for _, k := range g.gateway.Spec.Kernels {
 		ks := &v1alpha1.JupyterKernelSpec{}
 		if err := g.cli.Get(context.TODO(), types.NamespacedName{
@@ -308,23 +473,82 @@ func (g generator) volumes() ([]v1.Volume, error) {
 			return nil, err
 		}
 
-		volumes = append(volumes, v1.Volume{
+		if err := checkResourceFiles(g.cli, g.gateway.Namespace, k, &ks.Spec); err != nil {
+			return nil, err
+		}
+		specs = append(specs, kernelSpec{name: k, configMap: k, spec: &ks.Spec})
+	}
+	for _, k := range g.gateway.Spec.ClusterKernels {
+		ks := &v1alpha1.ClusterJupyterKernelSpec{}
+		if err := g.cli.Get(context.TODO(), types.NamespacedName{
 			Name: k,
-			VolumeSource: v1.VolumeSource{
-				ConfigMap: &v1.ConfigMapVolumeSource{
-					LocalObjectReference: v1.LocalObjectReference{Name: k},
-				},
-			},
+		}, ks); err != nil {
+			return nil, err
+		}
+
+		if err := checkResourceFiles(g.cli, g.gateway.Namespace, k, &ks.Spec); err != nil {
+			return nil, err
+		}
+		// The configmap is rendered in the gateway namespace by the
+		// ClusterJupyterKernelSpec controller.
+		specs = append(specs, kernelSpec{
+			name: k, configMap: kernelspec.ClusterConfigMapName(k), spec: &ks.Spec,
 		})
 	}
-	return volumes, nil
+	return specs, nil
+}
+
+// DesiredProcessProxyRoleBindingWithoutOwner returns the role binding
+// which grants the gateway the permissions required by the process proxies
+// of the kernels, e.g. creating the SparkApplications. The permissions are
+// aggregated by the ClusterRole provisioned by the cluster admin, thus the
+// operator does not need to hold them. It returns nil if no extra
+// permission is required.
+func (g generator) DesiredProcessProxyRoleBindingWithoutOwner(
+	sa *v1.ServiceAccount) (*rbacv1.RoleBinding, error) {
+	specs, err := g.kernelSpecs()
+	if err != nil {
+		return nil, err
+	}
+	rules := []rbacv1.PolicyRule{}
+	for _, ks := range specs {
+		rules = append(rules, kernelspec.PolicyRules(ks.spec)...)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: g.gateway.Namespace,
+			Name:      ProcessProxyRoleBindingName(g.gateway),
+			Labels:    g.labels(),
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      sa.Name,
+				Namespace: sa.Namespace,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Name:     ProcessProxyClusterRole,
+			Kind:     "ClusterRole",
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}, nil
+}
+
+// ProcessProxyRoleBindingName returns the name of the process proxy role
+// binding for the gateway.
+func ProcessProxyRoleBindingName(gateway *v1alpha1.JupyterGateway) string {
+	return gateway.Name + "-process-proxy"
 }
 
 func (g generator) defaultClusterRole() string {
 	if g.gateway.Spec.ClusterRole != nil {
 		return *g.gateway.Spec.ClusterRole
 	}
-	return defaultGatewayClusterRole
+	return g.opts.ClusterRole
 }
 
 func (g generator) labels() map[string]string {
@@ -335,11 +559,14 @@ func (g generator) labels() map[string]string {
 }
 
 func (g generator) kernels() string {
-	if g.gateway.Spec.Kernels != nil {
+	if g.gateway.Spec.Kernels != nil || g.gateway.Spec.ClusterKernels != nil {
 		ks := []string{}
 		for _, k := range g.gateway.Spec.Kernels {
 			ks = append(ks, fmt.Sprintf("'%s'", k))
 		}
+		for _, k := range g.gateway.Spec.ClusterKernels {
+			ks = append(ks, fmt.Sprintf("'%s'", k))
+		}
 		return strings.Join(ks, ",")
 	}
 	return defaultKernels
@@ -351,3 +578,19 @@ func (g generator) defaultKernel() string {
 	}
 	return defaultKernel
 }
+
+// checkResourceFiles returns the error if the configmap of the resource
+// files of the kernel conflicts with the rendered files, which cannot be
+// mounted in the same kernel directory.
+func checkResourceFiles(cli client.Reader, namespace, kernel string,
+	spec *v1alpha1.JupyterKernelSpecSpec) error {
+	conflicts, err := kernelspec.ConflictingResourceFiles(cli, namespace, spec)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) != 0 {
+		return fmt.Errorf("the resource files of kernel %s contain the rendered files %v",
+			kernel, conflicts)
+	}
+	return nil
+}
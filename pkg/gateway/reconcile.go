@@ -42,12 +42,15 @@ type Reconciler struct {
 
 	instance *v1alpha1.JupyterGateway
 	gen      *generator
+
+	// gatewayURL is the URL of the REST API of the gateway.
+	gatewayURL string
 }
 
 func NewReconciler(cli client.Client, l logr.Logger,
 	r record.EventRecorder, s *runtime.Scheme,
-	i *v1alpha1.JupyterGateway) (*Reconciler, error) {
-	g, err := newGenerator(cli, i)
+	i *v1alpha1.JupyterGateway, opts Options) (*Reconciler, error) {
+	g, err := newGenerator(cli, i, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +61,8 @@ func NewReconciler(cli client.Client, l logr.Logger,
 		scheme:   s,
 		instance: i,
 		gen:      g,
+
+		gatewayURL: URL(i),
 	}, nil
 }
 
@@ -83,9 +88,111 @@ func (r Reconciler) reconcileRBAC() (string, error) {
 	if err := r.reconcileRoleBinding(sa); err != nil {
 		return "", err
 	}
+	if err := r.reconcileClusterRoleBinding(sa); err != nil {
+		return "", err
+	}
+	if err := r.reconcileProcessProxyRoleBinding(sa); err != nil {
+		return "", err
+	}
 	return sa.Name, nil
 }
 
+// reconcileProcessProxyRoleBinding grants the gateway the permissions
+// required by the process proxies of the kernels, or deletes the binding if
+// none is required.
+func (r Reconciler) reconcileProcessProxyRoleBinding(sa *v1.ServiceAccount) error {
+	desired, err := r.gen.DesiredProcessProxyRoleBindingWithoutOwner(sa)
+	if err != nil {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, "FailedToGenerate", err.Error())
+		return err
+	}
+
+	actual := &rbacv1.RoleBinding{}
+	err = r.cli.Get(context.TODO(), types.NamespacedName{
+		Name:      ProcessProxyRoleBindingName(r.instance),
+		Namespace: r.instance.Namespace,
+	}, actual)
+	if desired == nil {
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		r.log.Info("Deleting rolebinding",
+			"namespace", actual.Namespace, "name", actual.Name)
+		if err := r.cli.Delete(context.TODO(), actual); err != nil &&
+			!errors.IsNotFound(err) {
+			r.log.Error(err, "Failed to delete the rolebinding",
+				"rolebinding", actual.Name)
+			return err
+		}
+		return nil
+	}
+
+	if err := controllerutil.SetControllerReference(
+		r.instance, desired, r.scheme); err != nil {
+		r.log.Error(err,
+			"Set controller reference error, requeuing the request")
+		return err
+	}
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating rolebinding",
+			"namespace", desired.Namespace, "name", desired.Name)
+
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the rolebinding",
+				"rolebinding", desired.Name)
+			return err
+		}
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected rolebinding",
+			"rolebinding", desired.Name)
+		return err
+	}
+	return nil
+}
+
+// reconcileClusterRoleBinding grants the gateway the permission to read
+// the ClusterJupyterKernelTemplates if it has cluster kernels, or deletes
+// the binding if it has none. The binding is not managed by the operator
+// in the namespaced mode.
+func (r Reconciler) reconcileClusterRoleBinding(
+	sa *v1.ServiceAccount) error {
+	if r.gen.opts.Namespaced {
+		return nil
+	}
+	desired := r.gen.DesiredClusterRoleBinding(sa)
+
+	actual := &rbacv1.ClusterRoleBinding{}
+	err := r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: desired.GetName()}, actual)
+	if len(r.instance.Spec.ClusterKernels) == 0 {
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		r.log.Info("Deleting clusterrolebinding", "name", actual.Name)
+		if err := r.cli.Delete(context.TODO(), actual); err != nil &&
+			!errors.IsNotFound(err) {
+			r.log.Error(err, "Failed to delete the clusterrolebinding",
+				"clusterrolebinding", actual.Name)
+			return err
+		}
+		return nil
+	}
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating clusterrolebinding", "name", desired.Name)
+
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the clusterrolebinding",
+				"clusterrolebinding", desired.Name)
+			return err
+		}
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected clusterrolebinding",
+			"clusterrolebinding", desired.Name)
+		return err
+	}
+	return nil
+}
+
 func (r Reconciler) reconcileRoleBinding(
 	sa *v1.ServiceAccount) error {
 	desired := r.gen.DesiredRoleBinding(sa)
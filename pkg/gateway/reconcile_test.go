@@ -0,0 +1,131 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package gateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestClusterRoleBindingName(t *testing.T) {
+	a := &v1alpha1.JupyterGateway{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "b-c"}}
+	b := &v1alpha1.JupyterGateway{ObjectMeta: metav1.ObjectMeta{Namespace: "a-b", Name: "c"}}
+	if ClusterRoleBindingName(a) == ClusterRoleBindingName(b) {
+		t.Errorf("Expected different names, got %s", ClusterRoleBindingName(a))
+	}
+
+	long := &v1alpha1.JupyterGateway{ObjectMeta: metav1.ObjectMeta{
+		Namespace: strings.Repeat("n", 63),
+		Name:      strings.Repeat("a", 190) + "." + strings.Repeat("b", 62),
+	}}
+	name := ClusterRoleBindingName(long)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		t.Errorf("Expected a valid name, got %s: %v", name, errs)
+	}
+}
+
+func TestReconcileClusterRoleBinding(t *testing.T) {
+	gw := &v1alpha1.JupyterGateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"},
+		Spec:       v1alpha1.JupyterGatewaySpec{ClusterKernels: []string{"python"}},
+	}
+	sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"}}
+	r, cli := newFinalizeReconciler(t, gw, "")
+
+	key := types.NamespacedName{Name: ClusterRoleBindingName(gw)}
+	if err := r.reconcileClusterRoleBinding(sa); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Get(context.TODO(), key, &rbacv1.ClusterRoleBinding{}); err != nil {
+		t.Fatalf("Expected the clusterrolebinding, got %v", err)
+	}
+
+	gw.Spec.ClusterKernels = nil
+	if err := r.reconcileClusterRoleBinding(sa); err != nil {
+		t.Fatal(err)
+	}
+	err := cli.Get(context.TODO(), key, &rbacv1.ClusterRoleBinding{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected the clusterrolebinding to be deleted, got %v", err)
+	}
+	if err := r.reconcileClusterRoleBinding(sa); err != nil {
+		t.Errorf("Expected no error without the clusterrolebinding, got %v", err)
+	}
+}
+
+func TestKernelSpecsOverlap(t *testing.T) {
+	gw := &v1alpha1.JupyterGateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"},
+		Spec: v1alpha1.JupyterGatewaySpec{
+			Kernels:        []string{"python"},
+			ClusterKernels: []string{"python"},
+		},
+	}
+	r, _ := newFinalizeReconciler(t, gw, "")
+	if _, err := r.gen.kernelSpecs(); err == nil {
+		t.Errorf("Expected the error of the kernel in both lists")
+	}
+}
+
+func TestReconcileProcessProxyRoleBinding(t *testing.T) {
+	gw := &v1alpha1.JupyterGateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"},
+		Spec:       v1alpha1.JupyterGatewaySpec{Kernels: []string{"spark"}},
+	}
+	ks := &v1alpha1.JupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "spark"},
+		Spec: v1alpha1.JupyterKernelSpecSpec{
+			ProcessProxy: v1alpha1.ProcessProxySparkOperator,
+		},
+	}
+	sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"}}
+	r, cli := newFinalizeReconciler(t, gw, "", ks)
+
+	key := types.NamespacedName{Namespace: "default", Name: ProcessProxyRoleBindingName(gw)}
+	if err := r.reconcileProcessProxyRoleBinding(sa); err != nil {
+		t.Fatal(err)
+	}
+	binding := &rbacv1.RoleBinding{}
+	if err := cli.Get(context.TODO(), key, binding); err != nil {
+		t.Fatalf("Expected the rolebinding, got %v", err)
+	}
+	if binding.RoleRef.Kind != "ClusterRole" || binding.RoleRef.Name != ProcessProxyClusterRole {
+		t.Errorf("Expected the binding to %s, got %v", ProcessProxyClusterRole, binding.RoleRef)
+	}
+
+	ks.Spec.ProcessProxy = v1alpha1.ProcessProxyKubernetes
+	if err := cli.Update(context.TODO(), ks); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reconcileProcessProxyRoleBinding(sa); err != nil {
+		t.Fatal(err)
+	}
+	err := cli.Get(context.TODO(), key, &rbacv1.RoleBinding{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected the rolebinding to be deleted, got %v", err)
+	}
+}
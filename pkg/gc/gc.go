@@ -0,0 +1,229 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package gc garbage collects the kernels which are not tracked by the
+// gateways any more, e.g. after the gateway pod is restarted.
+package gc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/gateway"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
+)
+
+const (
+	// AnnotationOrphanedAt is the annotation of the kernel, which is set to
+	// the time when the kernel is found not tracked by the gateway.
+	AnnotationOrphanedAt = "kubeflow.tkestack.io/orphaned-at"
+
+	// ReasonOrphaned is the termination reason of the orphaned kernels.
+	ReasonOrphaned = "Orphaned"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Options are the options of the collector.
+type Options struct {
+	// Interval is the interval to check the kernels of the gateways.
+	Interval time.Duration
+	// GracePeriod is the duration for which a kernel stays orphaned before
+	// it is deleted.
+	GracePeriod time.Duration
+	// DryRun only reports the orphaned kernels without changing them.
+	DryRun bool
+}
+
+// Collector checks the kernels of the gateways periodically. The kernels
+// whose IDs are not returned by the kernels API of the gateway are
+// annotated as orphaned first, and are deleted if they are still orphaned
+// after the grace period.
+type Collector struct {
+	cli      client.Client
+	log      logr.Logger
+	recorder record.EventRecorder
+	opts     Options
+	http     *http.Client
+
+	// url returns the URL of the gateway, which is replaced in the tests.
+	url func(*v1alpha1.JupyterGateway) string
+}
+
+// NewCollector creates the collector.
+func NewCollector(cli client.Client, l logr.Logger,
+	recorder record.EventRecorder, opts Options) *Collector {
+	return &Collector{
+		cli:      cli,
+		log:      l,
+		recorder: recorder,
+		opts:     opts,
+		http:     &http.Client{Timeout: defaultTimeout},
+		url:      gateway.URL,
+	}
+}
+
+// Start implements manager.Runnable.
+func (c *Collector) Start(stop <-chan struct{}) error {
+	wait.Until(c.Collect, c.opts.Interval, stop)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the
+// leader deletes the kernels.
+func (c *Collector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect checks the kernels of all the gateways once.
+func (c *Collector) Collect() {
+	gateways := &v1alpha1.JupyterGatewayList{}
+	if err := c.cli.List(context.TODO(), gateways); err != nil {
+		c.log.Error(err, "Failed to list the gateways")
+		return
+	}
+	for i := range gateways.Items {
+		gw := &gateways.Items[i]
+		// The kernels of the deleted gateways are cleaned up by the
+		// finalizer of the gateway.
+		if gw.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.collect(gw); err != nil {
+			c.log.V(1).Info("Failed to collect the kernels of the gateway",
+				"namespace", gw.Namespace, "gateway", gw.Name, "error", err.Error())
+		}
+	}
+}
+
+func (c *Collector) collect(gw *v1alpha1.JupyterGateway) error {
+	live, err := c.liveKernels(gw)
+	if err != nil {
+		// Do nothing if the gateway is not reachable, since the kernels
+		// cannot be told from the orphans.
+		return err
+	}
+	kernels, err := gateway.Kernels(c.cli, gw)
+	if err != nil {
+		return err
+	}
+
+	orphaned := 0
+	for i := range kernels {
+		k := &kernels[i]
+		id := kernel.ID(k)
+		if id == "" || k.DeletionTimestamp != nil || c.launching(k) {
+			continue
+		}
+		if live[id] {
+			if err := c.adopt(k); err != nil {
+				return err
+			}
+			continue
+		}
+		orphaned++
+		if err := c.orphan(k); err != nil {
+			return err
+		}
+	}
+	metrics.KernelsOrphaned(gw, orphaned)
+	return nil
+}
+
+// launching returns true if the kernel may not be tracked by the gateway
+// yet, since the gateway tracks the kernel only after the kernel pod runs.
+// The kernels younger than the grace period, the queued kernels and the
+// kernels which have never been running are not collected.
+func (c *Collector) launching(k *v1alpha1.JupyterKernel) bool {
+	return time.Since(k.CreationTimestamp.Time) < c.opts.GracePeriod ||
+		kernel.Queued(k) || k.Status.StartTime == nil
+}
+
+// orphan annotates the kernel which is not tracked by the gateway, and
+// deletes it after the grace period.
+func (c *Collector) orphan(k *v1alpha1.JupyterKernel) error {
+	now := time.Now()
+	orphanedAt, err := time.Parse(time.RFC3339, k.Annotations[AnnotationOrphanedAt])
+	if err != nil {
+		c.log.Info("Found orphaned kernel", "namespace", k.Namespace,
+			"kernel", k.Name, "dryRun", c.opts.DryRun)
+		c.recorder.Eventf(k, v1.EventTypeWarning, "OrphanedKernel",
+			"The kernel is not tracked by the gateway, it will be deleted after %v",
+			c.opts.GracePeriod)
+		if c.opts.DryRun {
+			return nil
+		}
+		if k.Annotations == nil {
+			k.Annotations = make(map[string]string)
+		}
+		k.Annotations[AnnotationOrphanedAt] = now.UTC().Format(time.RFC3339)
+		return c.cli.Update(context.TODO(), k)
+	}
+
+	if now.Sub(orphanedAt) < c.opts.GracePeriod {
+		return nil
+	}
+	c.log.Info("Deleting orphaned kernel", "namespace", k.Namespace,
+		"kernel", k.Name, "dryRun", c.opts.DryRun)
+	if c.opts.DryRun {
+		return nil
+	}
+	// The termination reason is recorded by the metrics when the kernel
+	// is deleted.
+	k.Annotations[kernel.AnnotationTerminationReason] = ReasonOrphaned
+	if err := c.cli.Update(context.TODO(), k); err != nil {
+		return err
+	}
+	if err := c.cli.Delete(context.TODO(), k); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	c.recorder.Event(k, v1.EventTypeNormal, "DeletedOrphanedKernel",
+		"Deleted the kernel which is not tracked by the gateway")
+	return nil
+}
+
+// adopt removes the orphaned annotation if the kernel is tracked by the
+// gateway again.
+func (c *Collector) adopt(k *v1alpha1.JupyterKernel) error {
+	if _, ok := k.Annotations[AnnotationOrphanedAt]; !ok || c.opts.DryRun {
+		return nil
+	}
+	delete(k.Annotations, AnnotationOrphanedAt)
+	return c.cli.Update(context.TODO(), k)
+}
+
+// liveKernels returns the IDs of the kernels tracked by the gateway.
+func (c *Collector) liveKernels(gw *v1alpha1.JupyterGateway) (map[string]bool, error) {
+	kernels, err := gateway.ListKernels(c.http, c.url(gw))
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]bool, len(kernels))
+	for _, k := range kernels {
+		live[k.ID] = true
+	}
+	return live, nil
+}
@@ -0,0 +1,199 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package gc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+var testGateway = &v1alpha1.JupyterGateway{
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "gateway",
+		UID:       "gateway-uid",
+	},
+}
+
+func newKernel(name, id string, annotations map[string]string) *v1alpha1.JupyterKernel {
+	started := metav1.NewTime(time.Now().Add(-time.Hour))
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "team",
+			Name:              name,
+			Labels:            launcher.GatewayLabels(testGateway),
+			Annotations:       annotations,
+			CreationTimestamp: started,
+		},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{Name: "kernel", Env: []v1.EnvVar{{Name: "KERNEL_ID", Value: id}}},
+					},
+				},
+			},
+		},
+		Status: v1alpha1.JupyterKernelStatus{StartTime: &started},
+	}
+}
+
+func newCollector(t *testing.T, handler http.HandlerFunc,
+	opts Options, objs ...runtime.Object) (*Collector, client.Client) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cli := fake.NewFakeClientWithScheme(s, append(objs, testGateway.DeepCopy())...)
+	c := NewCollector(cli, logr.Logger(logf.NullLogger{}), record.NewFakeRecorder(10), opts)
+	c.url = func(*v1alpha1.JupyterGateway) string { return server.URL }
+	return c, cli
+}
+
+func liveKernels(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/kernels" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}
+}
+
+func getKernel(t *testing.T, cli client.Client, name string) *v1alpha1.JupyterKernel {
+	k := &v1alpha1.JupyterKernel{}
+	err := cli.Get(context.TODO(), types.NamespacedName{Namespace: "team", Name: name}, k)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestCollect(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().UTC().Format(time.RFC3339)
+	c, cli := newCollector(t, liveKernels(`[{"id": "id-live"}, {"id": "id-adopted"}]`),
+		Options{GracePeriod: 5 * time.Minute},
+		newKernel("live", "id-live", nil),
+		newKernel("adopted", "id-adopted", map[string]string{AnnotationOrphanedAt: expired}),
+		newKernel("new-orphan", "id-new", nil),
+		newKernel("recent-orphan", "id-recent", map[string]string{AnnotationOrphanedAt: recent}),
+		newKernel("expired-orphan", "id-expired", map[string]string{AnnotationOrphanedAt: expired}),
+	)
+
+	c.Collect()
+
+	if k := getKernel(t, cli, "live"); k == nil || k.Annotations[AnnotationOrphanedAt] != "" {
+		t.Errorf("Expected the live kernel to be kept, got %v", k)
+	}
+	if k := getKernel(t, cli, "adopted"); k == nil || k.Annotations[AnnotationOrphanedAt] != "" {
+		t.Errorf("Expected the orphaned annotation to be removed, got %v", k)
+	}
+	if k := getKernel(t, cli, "new-orphan"); k == nil || k.Annotations[AnnotationOrphanedAt] == "" {
+		t.Errorf("Expected the kernel to be annotated as orphaned, got %v", k)
+	}
+	if k := getKernel(t, cli, "recent-orphan"); k == nil || k.Annotations[AnnotationOrphanedAt] != recent {
+		t.Errorf("Expected the kernel to be kept in the grace period, got %v", k)
+	}
+	if k := getKernel(t, cli, "expired-orphan"); k != nil {
+		t.Errorf("Expected the kernel to be deleted after the grace period, got %v", k)
+	}
+}
+
+func TestCollectLaunching(t *testing.T) {
+	young := newKernel("young", "id-young", nil)
+	young.CreationTimestamp = metav1.Now()
+	starting := newKernel("starting", "id-starting", nil)
+	starting.Status.StartTime = nil
+	queued := newKernel("queued", "id-queued", nil)
+	queued.Status.StartTime = nil
+	queued.Status.Conditions = []v1alpha1.JupyterKernelCondition{
+		{Type: v1alpha1.JupyterKernelQueued, Status: v1.ConditionTrue},
+	}
+	c, cli := newCollector(t, liveKernels(`[]`),
+		Options{GracePeriod: 5 * time.Minute}, young, starting, queued)
+
+	c.Collect()
+
+	for _, name := range []string{"young", "starting", "queued"} {
+		if k := getKernel(t, cli, name); k == nil || k.Annotations[AnnotationOrphanedAt] != "" {
+			t.Errorf("Expected the launching kernel %s not to be orphaned, got %v", name, k)
+		}
+	}
+}
+
+func TestCollectDryRun(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	c, cli := newCollector(t, liveKernels(`[]`),
+		Options{GracePeriod: 5 * time.Minute, DryRun: true},
+		newKernel("new-orphan", "id-new", nil),
+		newKernel("expired-orphan", "id-expired", map[string]string{AnnotationOrphanedAt: expired}),
+	)
+
+	c.Collect()
+
+	if k := getKernel(t, cli, "new-orphan"); k == nil || k.Annotations[AnnotationOrphanedAt] != "" {
+		t.Errorf("Expected the kernel not to be changed, got %v", k)
+	}
+	k := getKernel(t, cli, "expired-orphan")
+	if k == nil || k.Annotations[kernel.AnnotationTerminationReason] != "" {
+		t.Errorf("Expected the kernel not to be deleted, got %v", k)
+	}
+}
+
+func TestCollectUnreachableGateway(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	c, cli := newCollector(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, Options{GracePeriod: 5 * time.Minute},
+		newKernel("expired-orphan", "id-expired", map[string]string{AnnotationOrphanedAt: expired}),
+	)
+
+	c.Collect()
+
+	if k := getKernel(t, cli, "expired-orphan"); k == nil {
+		t.Errorf("Expected the kernel to be kept when the gateway is unreachable")
+	}
+}
@@ -0,0 +1,347 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// LabelCluster is the label of the pods of the compute cluster, which
+	// is set to the name of the kernel. It differs from LabelKernel to keep
+	// the pods out of the selector of the kernel deployment.
+	LabelCluster = "kernel_cluster"
+	// LabelClusterRole is the label of the role of the pod in the compute
+	// cluster, i.e. scheduler or worker.
+	LabelClusterRole = "kernel_cluster_role"
+
+	clusterRoleScheduler = "scheduler"
+	clusterRoleWorker    = "worker"
+	clusterSuffix        = "-cluster"
+
+	// reasonClusterInvalid is the reason of the event when the workers of
+	// the compute cluster are invalid.
+	reasonClusterInvalid = "InvalidCluster"
+	// reasonNameConflict is the reason of the event when an object of the
+	// kernel exists but is not owned by the kernel.
+	reasonNameConflict = "NameConflict"
+
+	// EnvDaskSchedulerAddress and EnvRayAddress are the envs of the kernel
+	// container, which are read by dask.distributed.Client() and
+	// ray.init() respectively.
+	EnvDaskSchedulerAddress = "DASK_SCHEDULER_ADDRESS"
+	EnvRayAddress           = "RAY_ADDRESS"
+
+	daskSchedulerPort     = 8786
+	daskDashboardPort     = 8787
+	rayGCSPort            = 6379
+	rayClientPort         = 10001
+	rayDashboardPort      = 8265
+	defaultWorkerCPUUsage = 80
+)
+
+type clusterPort struct {
+	name string
+	port int32
+}
+
+// clusterPorts returns the ports of the scheduler, the first of which is
+// connected by the workers.
+func clusterPorts(t v1alpha1.ComputeClusterType) []clusterPort {
+	if t == v1alpha1.ComputeClusterRay {
+		return []clusterPort{
+			{"gcs", rayGCSPort},
+			{"client", rayClientPort},
+			{"dashboard", rayDashboardPort},
+		}
+	}
+	return []clusterPort{
+		{"scheduler", daskSchedulerPort},
+		{"dashboard", daskDashboardPort},
+	}
+}
+
+// DesiredClusterServiceWithoutOwner returns the service of the scheduler
+// of the compute cluster.
+func (g generator) DesiredClusterServiceWithoutOwner() *v1.Service {
+	labels := g.clusterLabels(clusterRoleScheduler)
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName(g.k.Name),
+			Namespace: g.k.Namespace,
+			Labels:    labels,
+		},
+		Spec: v1.ServiceSpec{
+			Selector: labels,
+		},
+	}
+	for _, p := range clusterPorts(g.k.Spec.Cluster.Type) {
+		svc.Spec.Ports = append(svc.Spec.Ports, v1.ServicePort{
+			Name:     p.name,
+			Port:     p.port,
+			Protocol: v1.ProtocolTCP,
+		})
+	}
+	return svc
+}
+
+// DesiredClusterDeploymentsWithoutOwner returns the deployments of the
+// scheduler and the workers of the compute cluster.
+func (g generator) DesiredClusterDeploymentsWithoutOwner() []*appsv1.Deployment {
+	cluster := g.k.Spec.Cluster
+	one := int32(1)
+	workers := minWorkers(cluster)
+
+	scheduler := g.clusterDeployment(clusterRoleScheduler, &cluster.Scheduler, one)
+	worker := g.clusterDeployment(clusterRoleWorker, &cluster.Worker, workers)
+
+	host := fmt.Sprintf("%s.%s.svc", clusterName(g.k.Name), g.k.Namespace)
+	port := clusterPorts(cluster.Type)[0].port
+	if c := firstContainer(&scheduler.Spec.Template); c != nil &&
+		len(c.Command) == 0 && len(c.Args) == 0 {
+		if cluster.Type == v1alpha1.ComputeClusterRay {
+			c.Command = []string{"ray", "start", "--head", "--block",
+				fmt.Sprintf("--port=%d", rayGCSPort),
+				fmt.Sprintf("--ray-client-server-port=%d", rayClientPort),
+				"--dashboard-host=0.0.0.0"}
+		} else {
+			c.Command = []string{"dask-scheduler"}
+		}
+	}
+	if c := firstContainer(&worker.Spec.Template); c != nil &&
+		len(c.Command) == 0 && len(c.Args) == 0 {
+		if cluster.Type == v1alpha1.ComputeClusterRay {
+			c.Command = []string{"ray", "start", "--block",
+				fmt.Sprintf("--address=%s:%d", host, port)}
+		} else {
+			c.Command = []string{"dask-worker", fmt.Sprintf("tcp://%s:%d", host, port)}
+		}
+	}
+	return []*appsv1.Deployment{scheduler, worker}
+}
+
+func (g generator) clusterDeployment(role string,
+	template *v1.PodTemplateSpec, replicas int32) *appsv1.Deployment {
+	labels := g.clusterLabels(role)
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName(g.k.Name) + "-" + role,
+			Namespace: g.k.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: *template.DeepCopy(),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+		},
+	}
+	if d.Spec.Template.Labels == nil {
+		d.Spec.Template.Labels = make(map[string]string)
+	}
+	for k, v := range labels {
+		d.Spec.Template.Labels[k] = v
+	}
+	d.Spec.Template.Spec.PriorityClassName = PriorityClassName(g.k, g.priorityClasses)
+	return d
+}
+
+// DesiredClusterAutoscalerWithoutOwner returns the autoscaler of the
+// workers, which is nil if the workers are not elastic.
+func (g generator) DesiredClusterAutoscalerWithoutOwner() *autoscalingv2beta2.HorizontalPodAutoscaler {
+	cluster := g.k.Spec.Cluster
+	min, max := minWorkers(cluster), maxWorkers(cluster)
+	if max <= min {
+		return nil
+	}
+	metrics := cluster.Metrics
+	if len(metrics) == 0 {
+		utilization := int32(defaultWorkerCPUUsage)
+		metrics = []autoscalingv2beta2.MetricSpec{
+			{
+				Type: autoscalingv2beta2.ResourceMetricSourceType,
+				Resource: &autoscalingv2beta2.ResourceMetricSource{
+					Name: v1.ResourceCPU,
+					Target: autoscalingv2beta2.MetricTarget{
+						Type:               autoscalingv2beta2.UtilizationMetricType,
+						AverageUtilization: &utilization,
+					},
+				},
+			},
+		}
+	}
+	return &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterName(g.k.Name) + "-" + clusterRoleWorker,
+			Namespace: g.k.Namespace,
+			Labels:    g.clusterLabels(clusterRoleWorker),
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       clusterName(g.k.Name) + "-" + clusterRoleWorker,
+			},
+			MinReplicas: &min,
+			MaxReplicas: max,
+			Metrics:     append([]autoscalingv2beta2.MetricSpec(nil), metrics...),
+		},
+	}
+}
+
+// setClusterAddress passes the address of the scheduler to the kernel.
+func (g generator) setClusterAddress(pod *v1.PodTemplateSpec) {
+	c := firstContainer(pod)
+	if c == nil {
+		return
+	}
+	host := fmt.Sprintf("%s.%s.svc", clusterName(g.k.Name), g.k.Namespace)
+	if g.k.Spec.Cluster.Type == v1alpha1.ComputeClusterRay {
+		c.Env = append(c.Env, v1.EnvVar{
+			Name:  EnvRayAddress,
+			Value: fmt.Sprintf("ray://%s:%d", host, rayClientPort),
+		})
+		return
+	}
+	c.Env = append(c.Env, v1.EnvVar{
+		Name:  EnvDaskSchedulerAddress,
+		Value: fmt.Sprintf("tcp://%s:%d", host, daskSchedulerPort),
+	})
+}
+
+func (g generator) clusterLabels(role string) map[string]string {
+	return map[string]string{
+		labelNS:          g.k.Namespace,
+		LabelCluster:     g.k.Name,
+		LabelClusterRole: role,
+	}
+}
+
+func minWorkers(c *v1alpha1.ComputeClusterTemplate) int32 {
+	if c.MinWorkers == nil {
+		return 1
+	}
+	return *c.MinWorkers
+}
+
+func maxWorkers(c *v1alpha1.ComputeClusterTemplate) int32 {
+	if c.MaxWorkers == nil {
+		return minWorkers(c)
+	}
+	return *c.MaxWorkers
+}
+
+// validateCluster returns an error if the numbers of the workers are
+// invalid, which are not validated by the API server together.
+func validateCluster(c *v1alpha1.ComputeClusterTemplate) error {
+	if min := minWorkers(c); min < 1 {
+		return fmt.Errorf("the minimum workers %d of the cluster is less than 1", min)
+	}
+	if min, max := minWorkers(c), maxWorkers(c); max < min {
+		return fmt.Errorf("the maximum workers %d of the cluster is less than the minimum workers %d",
+			max, min)
+	}
+	return nil
+}
+
+func firstContainer(pod *v1.PodTemplateSpec) *v1.Container {
+	if len(pod.Spec.Containers) == 0 {
+		return nil
+	}
+	return &pod.Spec.Containers[0]
+}
+
+func clusterName(kernel string) string {
+	return kernel + clusterSuffix
+}
+
+// reconcileCluster creates the scheduler, the workers and the autoscaler
+// of the compute cluster, which are owned by the kernel, thus they are
+// garbage collected with the kernel.
+func (r Reconciler) reconcileCluster() error {
+	if err := validateCluster(r.instance.Spec.Cluster); err != nil {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, reasonClusterInvalid, err.Error())
+		return err
+	}
+	objects := []runtime.Object{r.gen.DesiredClusterServiceWithoutOwner()}
+	for _, d := range r.gen.DesiredClusterDeploymentsWithoutOwner() {
+		objects = append(objects, d)
+	}
+	if hpa := r.gen.DesiredClusterAutoscalerWithoutOwner(); hpa != nil {
+		objects = append(objects, hpa)
+	}
+	for _, o := range objects {
+		if err := r.createIfNotFound(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIfNotFound sets the kernel as the owner of the desired object, and
+// creates it if it does not exist. An error is returned if the existing
+// object is not controlled by the kernel.
+func (r Reconciler) createIfNotFound(desired runtime.Object) error {
+	m, err := meta.Accessor(desired)
+	if err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(
+		r.instance, m, r.scheme); err != nil {
+		r.log.Error(err,
+			"Set controller reference error, requeuing the request")
+		return err
+	}
+
+	gvk, err := apiutil.GVKForObject(desired, r.scheme)
+	if err != nil {
+		return err
+	}
+	kind := strings.ToLower(gvk.Kind)
+	actual, err := r.scheme.New(gvk)
+	if err != nil {
+		return err
+	}
+	err = r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: m.GetName(), Namespace: m.GetNamespace()}, actual)
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating "+kind, "namespace", m.GetNamespace(), "name", m.GetName())
+
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the "+kind, "name", m.GetName())
+			return err
+		}
+		return nil
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected "+kind, "name", m.GetName())
+		return err
+	}
+
+	// The object with the same name is not adopted, e.g. a deployment
+	// created by the user before the kernel.
+	a, err := meta.Accessor(actual)
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(a, r.instance) {
+		r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonNameConflict,
+			"The %s %s already exists and is not owned by the kernel", kind, m.GetName())
+		return fmt.Errorf("the %s %s already exists and is not owned by the kernel %s",
+			kind, m.GetName(), r.instance.Name)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package kernel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newClusterKernel(t v1alpha1.ComputeClusterType, min, max int32) *v1alpha1.JupyterKernel {
+	pod := v1.PodTemplateSpec{
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "cluster"}}},
+	}
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "kernel"}}},
+			},
+			Cluster: &v1alpha1.ComputeClusterTemplate{
+				Type:       t,
+				Scheduler:  *pod.DeepCopy(),
+				Worker:     *pod.DeepCopy(),
+				MinWorkers: &min,
+				MaxWorkers: &max,
+			},
+		},
+	}
+}
+
+func TestDesiredCluster(t *testing.T) {
+	tests := []struct {
+		t         v1alpha1.ComputeClusterType
+		env       v1.EnvVar
+		scheduler []string
+		worker    []string
+	}{
+		{
+			t:         v1alpha1.ComputeClusterDask,
+			env:       v1.EnvVar{Name: EnvDaskSchedulerAddress, Value: "tcp://kernel-cluster.default.svc:8786"},
+			scheduler: []string{"dask-scheduler"},
+			worker:    []string{"dask-worker", "tcp://kernel-cluster.default.svc:8786"},
+		},
+		{
+			t:   v1alpha1.ComputeClusterRay,
+			env: v1.EnvVar{Name: EnvRayAddress, Value: "ray://kernel-cluster.default.svc:10001"},
+			scheduler: []string{"ray", "start", "--head", "--block", "--port=6379",
+				"--ray-client-server-port=10001", "--dashboard-host=0.0.0.0"},
+			worker: []string{"ray", "start", "--block", "--address=kernel-cluster.default.svc:6379"},
+		},
+	}
+	for _, test := range tests {
+		g, err := newGenerator(newClusterKernel(test.t, 1, 4), DefaultPriorityClasses())
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := g.DesiredDeployment()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env := d.Spec.Template.Spec.Containers[0].Env; len(env) != 1 || env[0] != test.env {
+			t.Errorf("%s: Expected the env %v, got %v", test.t, test.env, env)
+		}
+
+		deployments := g.DesiredClusterDeploymentsWithoutOwner()
+		if len(deployments) != 2 {
+			t.Fatalf("%s: Expected 2 deployments, got %d", test.t, len(deployments))
+		}
+		scheduler, worker := deployments[0], deployments[1]
+		if c := scheduler.Spec.Template.Spec.Containers[0].Command; !reflect.DeepEqual(c, test.scheduler) {
+			t.Errorf("%s: Expected the scheduler command %v, got %v", test.t, test.scheduler, c)
+		}
+		if c := worker.Spec.Template.Spec.Containers[0].Command; !reflect.DeepEqual(c, test.worker) {
+			t.Errorf("%s: Expected the worker command %v, got %v", test.t, test.worker, c)
+		}
+		if _, ok := worker.Spec.Template.Labels[LabelKernel]; ok {
+			t.Errorf("%s: Expected the workers not to be selected by the kernel deployment", test.t)
+		}
+	}
+}
+
+func TestReconcileCluster(t *testing.T) {
+	tests := []struct {
+		name       string
+		min, max   int32
+		autoscaler bool
+		invalid    bool
+	}{
+		{name: "fixed", min: 2, max: 2},
+		{name: "elastic", min: 1, max: 4, autoscaler: true},
+		{name: "no workers", min: 0, max: 2, invalid: true},
+		{name: "max less than min", min: 3, max: 2, invalid: true},
+	}
+	for _, test := range tests {
+		k := newClusterKernel(v1alpha1.ComputeClusterDask, test.min, test.max)
+		r, cli, recorder := newTestReconciler(t, k)
+		err := r.Reconcile()
+		if test.invalid {
+			if err == nil || len(recorder.Events) != 1 {
+				t.Errorf("%s: Expected the invalid cluster to be reported, got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key := func(name string) types.NamespacedName {
+			return types.NamespacedName{Namespace: "default", Name: name}
+		}
+		if err := cli.Get(context.TODO(), key("kernel-cluster"), &v1.Service{}); err != nil {
+			t.Errorf("%s: Expected the service of the scheduler, got %v", test.name, err)
+		}
+		worker := &appsv1.Deployment{}
+		if err := cli.Get(context.TODO(), key("kernel-cluster-worker"), worker); err != nil {
+			t.Fatalf("%s: Expected the deployment of the workers, got %v", test.name, err)
+		}
+		if *worker.Spec.Replicas != test.min {
+			t.Errorf("%s: Expected %d workers, got %d", test.name, test.min, *worker.Spec.Replicas)
+		}
+		if !isOwnedBy(worker, k) {
+			t.Errorf("%s: Expected the workers to be owned by the kernel", test.name)
+		}
+		hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+		err = cli.Get(context.TODO(), key("kernel-cluster-worker"), hpa)
+		if test.autoscaler && (err != nil || hpa.Spec.MaxReplicas != test.max) {
+			t.Errorf("%s: Expected the autoscaler up to %d workers, got %v", test.name, test.max, err)
+		}
+		if !test.autoscaler && err == nil {
+			t.Errorf("%s: Expected no autoscaler", test.name)
+		}
+	}
+}
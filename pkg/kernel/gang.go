@@ -0,0 +1,251 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/podcache"
+)
+
+const (
+	// DriverGroup is the name of the replica group of the kernel pod.
+	DriverGroup = "driver"
+
+	// LabelGang is the label of all the pods of the gang, which is set to
+	// the name of the kernel. It differs from LabelKernel to keep the pods
+	// of the replica groups out of the selector of the kernel deployment.
+	LabelGang = "kernel_gang"
+	// LabelReplicaGroup is the label of the replica group of the pod.
+	LabelReplicaGroup = "kernel_replica_group"
+
+	// LabelPodGroupName and LabelPodGroupMinAvailable are the labels of
+	// the coscheduling plugin of the scheduler-plugins.
+	LabelPodGroupName         = "pod-group.scheduling.sigs.k8s.io/name"
+	LabelPodGroupMinAvailable = "pod-group.scheduling.sigs.k8s.io/min-available"
+
+	envReplicaGroup      = "KERNEL_REPLICA_GROUP"
+	reasonGangIncomplete = "GangIncomplete"
+	// reasonReplicaGroupsInvalid is the reason of the Failed condition of
+	// the kernels with the reserved or duplicate replica group names.
+	reasonReplicaGroupsInvalid = "InvalidReplicaGroups"
+)
+
+// Gang returns true if the kernel has replica groups, whose pods are
+// scheduled together with the kernel pod.
+func Gang(k *v1alpha1.JupyterKernel) bool {
+	return len(k.Spec.ReplicaGroups) != 0
+}
+
+// GangSize returns the number of the pods of the kernel, including the
+// kernel pod.
+func GangSize(k *v1alpha1.JupyterKernel) int32 {
+	size := int32(1)
+	for _, g := range k.Spec.ReplicaGroups {
+		size += replicas(g)
+	}
+	return size
+}
+
+// PodSpecs returns the specs of all the pods of the kernel, starting with
+// the kernel pod. The compute cluster is counted with the minimum workers.
+func PodSpecs(k *v1alpha1.JupyterKernel) []*v1.PodSpec {
+	specs := []*v1.PodSpec{PodSpec(k)}
+	for i := range k.Spec.ReplicaGroups {
+		g := &k.Spec.ReplicaGroups[i]
+		for j := int32(0); j < replicas(*g); j++ {
+			specs = append(specs, &g.Template.Spec)
+		}
+	}
+	if c := k.Spec.Cluster; c != nil {
+		specs = append(specs, &c.Scheduler.Spec)
+		for j := int32(0); j < minWorkers(c); j++ {
+			specs = append(specs, &c.Worker.Spec)
+		}
+	}
+	return specs
+}
+
+func replicas(g v1alpha1.KernelReplicaGroup) int32 {
+	if g.Replicas == nil {
+		return 1
+	}
+	return *g.Replicas
+}
+
+// DesiredGroupDeployments returns the deployments of the replica groups.
+func (g generator) DesiredGroupDeployments() []*appsv1.Deployment {
+	deployments := []*appsv1.Deployment{}
+	for i := range g.k.Spec.ReplicaGroups {
+		group := &g.k.Spec.ReplicaGroups[i]
+		labels := map[string]string{
+			labelNS:           g.k.Namespace,
+			LabelGang:         g.k.Name,
+			LabelReplicaGroup: group.Name,
+		}
+		n := replicas(*group)
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      groupDeploymentName(g.k.Name, group.Name),
+				Namespace: g.k.Namespace,
+				Labels:    labels,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &n,
+				Template: *group.Template.DeepCopy(),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: labels,
+				},
+			},
+		}
+		if d.Spec.Template.Labels == nil {
+			d.Spec.Template.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			d.Spec.Template.Labels[k] = v
+		}
+		d.Spec.Template.Labels[podcache.LabelManaged] = podcache.ValueKernel
+		d.Spec.Template.Spec.PriorityClassName = PriorityClassName(g.k, g.priorityClasses)
+		g.setGang(&d.Spec.Template, group.Name)
+		deployments = append(deployments, d)
+	}
+	return deployments
+}
+
+// setGang sets the labels of the PodGroup, which is named after the kernel,
+// thus the coscheduling plugin schedules all the pods of the kernel
+// together. The group is passed to the containers in the env.
+func (g generator) setGang(pod *v1.PodTemplateSpec, group string) {
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[LabelGang] = g.k.Name
+	pod.Labels[LabelReplicaGroup] = group
+	pod.Labels[LabelPodGroupName] = g.k.Name
+	pod.Labels[LabelPodGroupMinAvailable] = strconv.Itoa(int(GangSize(g.k)))
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		c.Env = append(c.Env, v1.EnvVar{Name: envReplicaGroup, Value: group})
+	}
+}
+
+func groupDeploymentName(kernel, group string) string {
+	return kernel + "-" + group
+}
+
+// ValidateReplicaGroups returns an error if any replica group uses the
+// reserved name of the kernel pod, or the name of another group.
+func ValidateReplicaGroups(groups []v1alpha1.KernelReplicaGroup) error {
+	names := map[string]bool{}
+	for _, g := range groups {
+		if g.Name == DriverGroup {
+			return fmt.Errorf("the replica group name %s is reserved for the kernel pod", DriverGroup)
+		}
+		if names[g.Name] {
+			return fmt.Errorf("duplicate replica group name %s", g.Name)
+		}
+		names[g.Name] = true
+	}
+	return nil
+}
+
+// reconcileInvalidReplicaGroups marks the kernel as failed if the replica
+// groups are invalid, which cannot be fixed by retrying. It returns false
+// if the groups are valid.
+func (r Reconciler) reconcileInvalidReplicaGroups() (bool, error) {
+	err := ValidateReplicaGroups(r.instance.Spec.ReplicaGroups)
+	if err == nil {
+		return false, nil
+	}
+	status := &r.instance.Status
+	if isConditionTrue(status, v1alpha1.JupyterKernelFailed) {
+		return true, nil
+	}
+	metrics.KernelFailed(r.instance)
+	r.recorder.Event(r.instance, v1.EventTypeWarning, reasonReplicaGroupsInvalid, err.Error())
+	setCondition(status, v1alpha1.JupyterKernelRunning,
+		v1.ConditionFalse, reasonReplicaGroupsInvalid, err.Error())
+	setCondition(status, v1alpha1.JupyterKernelFailed,
+		v1.ConditionTrue, reasonReplicaGroupsInvalid, err.Error())
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the kernel")
+		return true, err
+	}
+	return true, nil
+}
+
+func (r Reconciler) reconcileReplicaGroups() error {
+	for _, desired := range r.gen.DesiredGroupDeployments() {
+		if err := r.createIfNotFound(desired); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gangStatus returns the readiness of the replica groups, including the
+// kernel pod as the driver group, and the time when the last pod of the
+// gang is started if the gang is complete.
+func gangStatus(k *v1alpha1.JupyterKernel, pods []v1.Pod) (
+	[]v1alpha1.KernelReplicaGroupStatus, metav1.Time, bool) {
+	statuses := []v1alpha1.KernelReplicaGroupStatus{
+		{Name: DriverGroup, Replicas: 1},
+	}
+	for _, g := range k.Spec.ReplicaGroups {
+		statuses = append(statuses, v1alpha1.KernelReplicaGroupStatus{
+			Name:     g.Name,
+			Replicas: replicas(g),
+		})
+	}
+
+	started := metav1.Time{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		t, ok := podStartedAt(pod)
+		if !ok {
+			continue
+		}
+		for j := range statuses {
+			s := &statuses[j]
+			if s.Name == pod.Labels[LabelReplicaGroup] && s.Ready < s.Replicas {
+				s.Ready++
+				if started.Before(&t) {
+					started = t
+				}
+				break
+			}
+		}
+	}
+
+	for _, s := range statuses {
+		if s.Ready < s.Replicas {
+			return statuses, metav1.Time{}, false
+		}
+	}
+	return statuses, started, true
+}
+
+// gangIncompleteMessage describes the groups which are not ready, e.g.
+// worker 1/2 ready.
+func gangIncompleteMessage(statuses []v1alpha1.KernelReplicaGroupStatus) string {
+	groups := []string{}
+	for _, s := range statuses {
+		if s.Ready < s.Replicas {
+			groups = append(groups, fmt.Sprintf("%s %d/%d ready", s.Name, s.Ready, s.Replicas))
+		}
+	}
+	sort.Strings(groups)
+	return "Waiting for the gang: " + strings.Join(groups, ", ")
+}
@@ -0,0 +1,193 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newGangKernel() *v1alpha1.JupyterKernel {
+	workers := int32(2)
+	spec := v1.PodSpec{
+		Containers: []v1.Container{
+			{
+				Name: "kernel",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				},
+			},
+		},
+	}
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{Spec: *spec.DeepCopy()},
+			ReplicaGroups: []v1alpha1.KernelReplicaGroup{
+				{
+					Name:     "worker",
+					Replicas: &workers,
+					Template: v1.PodTemplateSpec{Spec: *spec.DeepCopy()},
+				},
+			},
+		},
+	}
+}
+
+func TestDesiredGroupDeployments(t *testing.T) {
+	k := newGangKernel()
+	g, err := newGenerator(k, DefaultPriorityClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := GangSize(k); size != 3 {
+		t.Errorf("Expected the gang size to be 3, got %d", size)
+	}
+	if cpu := Requests(k)[v1.ResourceCPU]; cpu.Cmp(resource.MustParse("3")) != 0 {
+		t.Errorf("Expected the requests of the gang to be 3 cpus, got %s", cpu.String())
+	}
+
+	driver, err := g.DesiredDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployments := g.DesiredGroupDeployments()
+	if len(deployments) != 1 {
+		t.Fatalf("Expected 1 deployment, got %d", len(deployments))
+	}
+	worker := deployments[0]
+	if worker.Name != "kernel-worker" || *worker.Spec.Replicas != 2 {
+		t.Errorf("Expected 2 replicas of kernel-worker, got %d of %s",
+			*worker.Spec.Replicas, worker.Name)
+	}
+	if _, ok := worker.Spec.Template.Labels[LabelKernel]; ok {
+		t.Errorf("Expected the workers not to be selected by the kernel deployment")
+	}
+	for group, d := range map[string]*appsv1.Deployment{DriverGroup: driver, "worker": worker} {
+		pod := d.Spec.Template
+		if pod.Labels[LabelPodGroupName] != "kernel" ||
+			pod.Labels[LabelPodGroupMinAvailable] != "3" {
+			t.Errorf("%s: Expected the PodGroup of the kernel, got %v", group, pod.Labels)
+		}
+		if pod.Labels[LabelGang] != "kernel" || pod.Labels[LabelReplicaGroup] != group {
+			t.Errorf("%s: Expected the gang labels, got %v", group, pod.Labels)
+		}
+	}
+}
+
+func TestReconcileReplicaGroupsConflict(t *testing.T) {
+	k := newGangKernel()
+	r, _, recorder := newTestReconciler(t, k, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel-worker"},
+	})
+	if err := r.reconcileReplicaGroups(); err == nil {
+		t.Errorf("Expected the deployment which is not owned by the kernel to be rejected")
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(recorder.Events))
+	}
+}
+
+func TestReconcileGangStatus(t *testing.T) {
+	started := metav1.NewTime(time.Now().Truncate(time.Second))
+	running := v1.PodStatus{
+		Phase: v1.PodRunning,
+		ContainerStatuses: []v1.ContainerStatus{
+			{State: v1.ContainerState{
+				Running: &v1.ContainerStateRunning{StartedAt: started},
+			}},
+		},
+	}
+	pod := func(name, group string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels:    map[string]string{LabelGang: "kernel", LabelReplicaGroup: group},
+			},
+			Status: running,
+		}
+	}
+
+	k := newGangKernel()
+	r, cli, _ := newTestReconciler(t, k, pod("driver", DriverGroup), pod("worker-0", "worker"))
+	get := func() *v1alpha1.JupyterKernel {
+		actual := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: "kernel"}, actual); err != nil {
+			t.Fatal(err)
+		}
+		return actual
+	}
+
+	if err := r.reconcileStatus(); err != nil {
+		t.Fatal(err)
+	}
+	actual := get()
+	if isConditionTrue(&actual.Status, v1alpha1.JupyterKernelRunning) || actual.Status.StartTime != nil {
+		t.Errorf("Expected the kernel not to be running before the gang is complete")
+	}
+	expected := []v1alpha1.KernelReplicaGroupStatus{
+		{Name: DriverGroup, Replicas: 1, Ready: 1},
+		{Name: "worker", Replicas: 2, Ready: 1},
+	}
+	for i, g := range expected {
+		if actual.Status.ReplicaGroups[i] != g {
+			t.Errorf("Expected %v, got %v", g, actual.Status.ReplicaGroups[i])
+		}
+	}
+
+	if err := cli.Create(context.TODO(), pod("worker-1", "worker")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reconcileStatus(); err != nil {
+		t.Fatal(err)
+	}
+	actual = get()
+	if !isConditionTrue(&actual.Status, v1alpha1.JupyterKernelRunning) || actual.Status.StartTime == nil {
+		t.Errorf("Expected the kernel to be running after the gang is complete")
+	}
+}
+
+func TestInvalidReplicaGroups(t *testing.T) {
+	for name, groups := range map[string][]string{
+		"reserved":  {DriverGroup},
+		"duplicate": {"worker", "worker"},
+	} {
+		k := newGangKernel()
+		k.Spec.ReplicaGroups = nil
+		for _, group := range groups {
+			g := newGangKernel().Spec.ReplicaGroups[0]
+			g.Name = group
+			k.Spec.ReplicaGroups = append(k.Spec.ReplicaGroups, g)
+		}
+		r, cli, _ := newTestReconciler(t, k)
+
+		if err := r.Reconcile(); err != nil {
+			t.Fatalf("%s: Expected no error to requeue, got %v", name, err)
+		}
+		actual := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: "kernel"}, actual); err != nil {
+			t.Fatal(err)
+		}
+		if !isConditionTrue(&actual.Status, v1alpha1.JupyterKernelFailed) {
+			t.Errorf("%s: Expected the kernel to fail, got %v", name, actual.Status.Conditions)
+		}
+		deployments := &appsv1.DeploymentList{}
+		if err := cli.List(context.TODO(), deployments); err != nil {
+			t.Fatal(err)
+		}
+		if len(deployments.Items) != 0 {
+			t.Errorf("%s: Expected no deployment, got %d", name, len(deployments.Items))
+		}
+	}
+}
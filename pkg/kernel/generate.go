@@ -8,29 +8,47 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/podcache"
 )
 
 const (
 	labelNS       = "namespace"
-	labelKernel   = "kernel"
+	LabelKernel   = "kernel"
 	envKernelID   = "KERNEL_ID"
 	labelKernelID = "kernel_id"
 )
 
+const (
+	// EnvKernelUsername is the env of the user who requests the kernel,
+	// which is set by the launcher.
+	EnvKernelUsername = "KERNEL_USERNAME"
+	// EnvKernelName is the env of the name of the kernel spec, which is
+	// set by the launcher.
+	EnvKernelName = "KERNEL_NAME"
+)
+
+// AnnotationTerminationReason is the annotation of the kernel, which is
+// set by the operator to the reason before the kernel is deleted, e.g.
+// Culled. The kernels deleted with the annotation are counted as culled
+// in the metrics.
+const AnnotationTerminationReason = "kubeflow.tkestack.io/termination-reason"
+
 // generator defines the generator which is used to generate
 // desired specs.
 type generator struct {
-	k *v1alpha1.JupyterKernel
+	k               *v1alpha1.JupyterKernel
+	priorityClasses map[v1alpha1.KernelPriorityTier]string
 }
 
 // newGenerator creates a new Generator.
-func newGenerator(k *v1alpha1.JupyterKernel) (
-	*generator, error) {
+func newGenerator(k *v1alpha1.JupyterKernel,
+	priorityClasses map[v1alpha1.KernelPriorityTier]string) (*generator, error) {
 	if k == nil {
 		return nil, fmt.Errorf("Got nil when initializing Generator")
 	}
 	g := &generator{
-		k: k,
+		k:               k,
+		priorityClasses: priorityClasses,
 	}
 
 	return g, nil
@@ -46,7 +64,10 @@ func (g generator) DesiredDeployment() (*appsv1.Deployment, error) {
 			Labels:    labels,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Template: g.k.Spec.Template,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: *g.k.Spec.Template.ObjectMeta.DeepCopy(),
+				Spec:       *PodSpec(g.k).DeepCopy(),
+			},
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -60,18 +81,52 @@ func (g generator) DesiredDeployment() (*appsv1.Deployment, error) {
 	for k, v := range labels {
 		d.Spec.Template.Labels[k] = v
 	}
+	d.Spec.Template.Labels[podcache.LabelManaged] = podcache.ValueKernel
 
 	// Update the metadata.
 	g.hackLabelID(&d.Spec.Template)
 
+	// Set the PriorityClass of the priority tier.
+	d.Spec.Template.Spec.PriorityClassName = PriorityClassName(g.k, g.priorityClasses)
+
+	if g.k.Spec.Spark != nil {
+		g.setSparkDriver(&d.Spec.Template)
+	}
+	if Gang(g.k) {
+		g.setGang(&d.Spec.Template, DriverGroup)
+	}
+	if g.k.Spec.Cluster != nil {
+		g.setClusterAddress(&d.Spec.Template)
+	}
+
 	return d, nil
 }
 
 func (g generator) labels() map[string]string {
 	return map[string]string{
 		labelNS:     g.k.Namespace,
-		labelKernel: g.k.Name,
+		LabelKernel: g.k.Name,
+	}
+}
+
+// ID returns the ID of the kernel in the gateway, which is passed to the
+// kernel by the launcher in the env.
+func ID(k *v1alpha1.JupyterKernel) string {
+	return Env(k, envKernelID)
+}
+
+// Env returns the value of the env of the kernel container, e.g.
+// KERNEL_USERNAME set by the launcher.
+func Env(k *v1alpha1.JupyterKernel, name string) string {
+	if len(k.Spec.Template.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, env := range k.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == name {
+			return env.Value
+		}
 	}
+	return ""
 }
 
 // hackLabelID copies the ID from environment variables to
@@ -0,0 +1,148 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// ReasonMaxLifetimeExceeded is the termination reason of the kernels
+	// which live longer than the max lifetime.
+	ReasonMaxLifetimeExceeded = "MaxLifetimeExceeded"
+	// ReasonTTLAfterFinished is the termination reason of the kernels
+	// which are deleted after ttlSecondsAfterFinished.
+	ReasonTTLAfterFinished = "TTLAfterFinished"
+
+	// AnnotationExpiring is the annotation of the kernel, which is set to
+	// the deadline when the KernelExpiring event is emitted, thus the
+	// event is emitted once per deadline.
+	AnnotationExpiring = "kubeflow.tkestack.io/expiring-at"
+)
+
+// DefaultLifetimeWarningPeriod is the default duration before the
+// deadline of the kernel, at which the warning event is emitted.
+const DefaultLifetimeWarningPeriod = 5 * time.Minute
+
+// ReconcileLifetime deletes the kernel after the max lifetime, or after
+// ttlSecondsAfterFinished since it fails. A warning event is emitted
+// the lifetime warning period before the deadline. It returns the duration
+// after which it should be called again, and true if the kernel is deleted.
+func (r Reconciler) ReconcileLifetime() (time.Duration, bool, error) {
+	if r.instance.DeletionTimestamp != nil {
+		return 0, false, nil
+	}
+	deadline, reason, ok := r.deadline()
+	if !ok {
+		return 0, false, nil
+	}
+
+	remaining := time.Until(deadline)
+	if remaining > r.opts.LifetimeWarningPeriod {
+		return remaining - r.opts.LifetimeWarningPeriod, false, nil
+	}
+	if remaining > 0 {
+		return remaining, false, r.warnExpiring(deadline, reason)
+	}
+
+	r.log.Info("Deleting expired kernel", "namespace", r.instance.Namespace,
+		"name", r.instance.Name, "reason", reason)
+	// The termination reason is recorded by the metrics when the kernel is
+	// deleted.
+	if r.instance.Annotations == nil {
+		r.instance.Annotations = make(map[string]string)
+	}
+	r.instance.Annotations[AnnotationTerminationReason] = reason
+	if err := r.cli.Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to annotate the kernel")
+		return 0, false, err
+	}
+	if err := r.cli.Delete(context.TODO(), r.instance); err != nil && !errors.IsNotFound(err) {
+		r.log.Error(err, "Failed to delete the kernel")
+		return 0, false, err
+	}
+	r.recorder.Event(r.instance, v1.EventTypeWarning, reason,
+		fmt.Sprintf("Deleted the kernel at the deadline %s",
+			deadline.UTC().Format(time.RFC3339)))
+	return 0, true, nil
+}
+
+// warnExpiring emits the KernelExpiring event once per deadline, which is
+// recorded in the annotation of the kernel.
+func (r Reconciler) warnExpiring(deadline time.Time, reason string) error {
+	at := deadline.UTC().Format(time.RFC3339)
+	if r.instance.Annotations[AnnotationExpiring] == at {
+		return nil
+	}
+	if r.instance.Annotations == nil {
+		r.instance.Annotations = make(map[string]string)
+	}
+	r.instance.Annotations[AnnotationExpiring] = at
+	if err := r.cli.Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to annotate the expiring kernel")
+		return err
+	}
+	r.recorder.Eventf(r.instance, v1.EventTypeWarning, "KernelExpiring",
+		"The kernel will be deleted at %s: %s", at, reason)
+	return nil
+}
+
+// deadline returns the earlier one of the max lifetime and the TTL after
+// the kernel fails, with the termination reason.
+func (r Reconciler) deadline() (time.Time, string, bool) {
+	lifetime := effectiveLifetime(r.instance, r.opts.Lifetimes)
+
+	var deadline time.Time
+	reason := ""
+	if l := lifetime.MaxLifetime; l != nil {
+		deadline = r.instance.CreationTimestamp.Add(l.Duration)
+		reason = ReasonMaxLifetimeExceeded
+	}
+	if ttl := lifetime.TTLSecondsAfterFinished; ttl != nil {
+		if finished, ok := finishedAt(&r.instance.Status); ok {
+			d := finished.Add(time.Duration(*ttl) * time.Second)
+			if reason == "" || d.Before(deadline) {
+				deadline = d
+				reason = ReasonTTLAfterFinished
+			}
+		}
+	}
+	return deadline, reason, reason != ""
+}
+
+// effectiveLifetime returns the lifetime of the kernel. The fields which
+// are not set in the kernel default to the ones of the namespace in
+// defaults, and then to the ones of all the namespaces.
+func effectiveLifetime(k *v1alpha1.JupyterKernel,
+	defaults map[string]v1alpha1.KernelLifetime) v1alpha1.KernelLifetime {
+	lifetime := *k.Spec.KernelLifetime.DeepCopy()
+	for _, ns := range []string{k.Namespace, ""} {
+		d, ok := defaults[ns]
+		if !ok {
+			continue
+		}
+		if lifetime.MaxLifetime == nil {
+			lifetime.MaxLifetime = d.MaxLifetime
+		}
+		if lifetime.TTLSecondsAfterFinished == nil {
+			lifetime.TTLSecondsAfterFinished = d.TTLSecondsAfterFinished
+		}
+	}
+	return lifetime
+}
+
+// finishedAt returns the time when the kernel fails. The kernel pods are
+// managed by the deployment, thus they never succeed.
+func finishedAt(status *v1alpha1.JupyterKernelStatus) (time.Time, bool) {
+	for _, c := range status.Conditions {
+		if c.Type == v1alpha1.JupyterKernelFailed && c.Status == v1.ConditionTrue {
+			return c.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
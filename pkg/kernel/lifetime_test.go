@@ -0,0 +1,148 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestReconcileLifetime(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	failed := func(since time.Duration) v1alpha1.JupyterKernelStatus {
+		return v1alpha1.JupyterKernelStatus{
+			Conditions: []v1alpha1.JupyterKernelCondition{
+				{
+					Type:               v1alpha1.JupyterKernelFailed,
+					Status:             v1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-since)),
+				},
+			},
+		}
+	}
+	type test struct {
+		name     string
+		age      time.Duration
+		lifetime v1alpha1.KernelLifetime
+		defaults map[string]v1alpha1.KernelLifetime
+		status   v1alpha1.JupyterKernelStatus
+		deleted  string
+		requeue  bool
+		recorded int
+	}
+	tests := []test{
+		{
+			name: "no lifetime",
+			age:  time.Hour,
+		},
+		{
+			name:     "before the warning",
+			age:      time.Hour,
+			lifetime: v1alpha1.KernelLifetime{MaxLifetime: &metav1.Duration{Duration: 2 * time.Hour}},
+			requeue:  true,
+		},
+		{
+			name:     "warning",
+			age:      time.Hour,
+			lifetime: v1alpha1.KernelLifetime{MaxLifetime: &metav1.Duration{Duration: time.Hour + time.Minute}},
+			requeue:  true,
+			recorded: 1,
+		},
+		{
+			name:     "max lifetime exceeded",
+			age:      time.Hour,
+			lifetime: v1alpha1.KernelLifetime{MaxLifetime: &metav1.Duration{Duration: time.Minute}},
+			deleted:  ReasonMaxLifetimeExceeded,
+			recorded: 1,
+		},
+		{
+			name: "namespace default",
+			age:  time.Hour,
+			defaults: map[string]v1alpha1.KernelLifetime{
+				"":        {MaxLifetime: &metav1.Duration{Duration: 24 * time.Hour}},
+				"default": {MaxLifetime: &metav1.Duration{Duration: time.Minute}},
+			},
+			deleted:  ReasonMaxLifetimeExceeded,
+			recorded: 1,
+		},
+		{
+			name:     "kernel overrides the default",
+			age:      time.Hour,
+			lifetime: v1alpha1.KernelLifetime{MaxLifetime: &metav1.Duration{Duration: 24 * time.Hour}},
+			defaults: map[string]v1alpha1.KernelLifetime{
+				"default": {MaxLifetime: &metav1.Duration{Duration: time.Minute}},
+			},
+			requeue: true,
+		},
+		{
+			name:     "running kernel with ttl",
+			age:      time.Hour,
+			lifetime: v1alpha1.KernelLifetime{TTLSecondsAfterFinished: int32Ptr(60)},
+		},
+		{
+			name:     "ttl after finished",
+			age:      time.Hour,
+			lifetime: v1alpha1.KernelLifetime{TTLSecondsAfterFinished: int32Ptr(60)},
+			status:   failed(10 * time.Minute),
+			deleted:  ReasonTTLAfterFinished,
+			recorded: 1,
+		},
+	}
+
+	for _, test := range tests {
+		k := &v1alpha1.JupyterKernel{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              "kernel",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-test.age)),
+			},
+			Spec:   v1alpha1.JupyterKernelCRDSpec{KernelLifetime: test.lifetime},
+			Status: test.status,
+		}
+		r, cli, recorder := newTestReconciler(t, k)
+		r.opts.Lifetimes = test.defaults
+
+		requeueAfter, deleted, err := r.ReconcileLifetime()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if deleted != (test.deleted != "") {
+			t.Errorf("%s: Expected deleted %v, got %v", test.name, test.deleted != "", deleted)
+		}
+		if (requeueAfter != 0) != test.requeue {
+			t.Errorf("%s: Expected requeue %v, got %v", test.name, test.requeue, requeueAfter)
+		}
+		if len(recorder.Events) != test.recorded {
+			t.Errorf("%s: Expected %d events, got %d", test.name, test.recorded, len(recorder.Events))
+		}
+		actual := &v1alpha1.JupyterKernel{}
+		err = cli.Get(context.TODO(), types.NamespacedName{Namespace: k.Namespace, Name: k.Name}, actual)
+		if test.deleted == "" && err != nil {
+			t.Errorf("%s: Expected the kernel to be kept, got %v", test.name, err)
+		}
+		if test.deleted != "" && !errors.IsNotFound(err) {
+			t.Errorf("%s: Expected the kernel to be deleted, got %v", test.name, err)
+		}
+		if test.deleted != "" && k.Annotations[AnnotationTerminationReason] != test.deleted {
+			t.Errorf("%s: Expected the termination reason %s, got %s", test.name,
+				test.deleted, k.Annotations[AnnotationTerminationReason])
+		}
+
+		// The warning is emitted once.
+		if test.deleted == "" {
+			if _, _, err := r.ReconcileLifetime(); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if len(recorder.Events) != test.recorded {
+				t.Errorf("%s: Expected %d events after reconciling again, got %d",
+					test.name, test.recorded, len(recorder.Events))
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// ReasonPreempted is the reason of the Running and Failed conditions of
+// the kernels which are preempted for the kernels with higher priority.
+const ReasonPreempted = "Preempted"
+
+// reasonPriorityClassNotFound is the reason of the event when the
+// PriorityClass of the priority tier does not exist.
+const reasonPriorityClassNotFound = "PriorityClassNotFound"
+
+// DefaultPriorityClasses returns the PriorityClasses of the priority tiers
+// of the kernels, which are installed with the operator.
+func DefaultPriorityClasses() map[v1alpha1.KernelPriorityTier]string {
+	return map[v1alpha1.KernelPriorityTier]string{
+		v1alpha1.KernelPriorityInteractive: "jupyter-kernel-interactive",
+		v1alpha1.KernelPriorityBatch:       "jupyter-kernel-batch",
+		v1alpha1.KernelPriorityBestEffort:  "jupyter-kernel-best-effort",
+	}
+}
+
+// PriorityClassName returns the PriorityClass of the kernel pod. The class
+// in the pod template takes precedence over the priority tier, which is
+// mapped by classes.
+func PriorityClassName(k *v1alpha1.JupyterKernel,
+	classes map[v1alpha1.KernelPriorityTier]string) string {
+	if name := k.Spec.Template.Spec.PriorityClassName; name != "" {
+		return name
+	}
+	return classes[k.Spec.PriorityTier]
+}
+
+// reconcilePriorityClass verifies that the PriorityClass of the priority
+// tier exists, otherwise the kernel pods would be rejected by the API
+// server. The pods are created without the class instead, with a warning
+// event. The class set in the pod template is not verified.
+func (r Reconciler) reconcilePriorityClass() error {
+	if r.instance.Spec.Template.Spec.PriorityClassName != "" {
+		return nil
+	}
+	tier := r.instance.Spec.PriorityTier
+	name := r.gen.priorityClasses[tier]
+	if name == "" {
+		return nil
+	}
+	err := r.cli.Get(context.TODO(), types.NamespacedName{Name: name},
+		&schedulingv1.PriorityClass{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		// The class is kept if it cannot be read, e.g. without the
+		// cluster-wide permissions in the namespaced mode.
+		r.log.Info("Failed to get the PriorityClass", "priorityclass", name, "error", err.Error())
+		return nil
+	}
+
+	r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonPriorityClassNotFound,
+		"The PriorityClass %s of the priority tier %s is not found, the pods are created without it",
+		name, tier)
+	classes := make(map[v1alpha1.KernelPriorityTier]string, len(r.gen.priorityClasses))
+	for t, c := range r.gen.priorityClasses {
+		if t != tier {
+			classes[t] = c
+		}
+	}
+	r.gen.priorityClasses = classes
+	return nil
+}
+
+// Preempted returns true if the kernel is preempted.
+func Preempted(k *v1alpha1.JupyterKernel) bool {
+	return k.Status.Preemption != nil
+}
+
+// Preempt records the preemption in the status of the kernel. The
+// deployment of the kernel is deleted by the reconciler afterwards.
+func Preempt(k *v1alpha1.JupyterKernel, preemptor *v1alpha1.JupyterKernel,
+	now metav1.Time) {
+	name := preemptor.Namespace + "/" + preemptor.Name
+	message := fmt.Sprintf("The kernel is preempted for the kernel %s with higher priority", name)
+	k.Status.Preemption = &v1alpha1.KernelPreemption{
+		Preemptor: name,
+		Time:      now,
+	}
+	setCondition(&k.Status, v1alpha1.JupyterKernelRunning,
+		v1.ConditionFalse, ReasonPreempted, message)
+	setCondition(&k.Status, v1alpha1.JupyterKernelFailed,
+		v1.ConditionTrue, ReasonPreempted, message)
+}
+
+// reconcilePreempted deletes the deployments of the preempted kernel,
+// including the replica groups and the compute cluster, to free the
+// resources for the preemptor. The kernel itself is kept with the
+// preemption in the status, until it is deleted by the gateway or after
+// ttlSecondsAfterFinished.
+func (r Reconciler) reconcilePreempted() error {
+	names := []string{r.instance.Name}
+	for _, d := range r.gen.DesiredGroupDeployments() {
+		names = append(names, d.Name)
+	}
+	if r.instance.Spec.Cluster != nil {
+		for _, d := range r.gen.DesiredClusterDeploymentsWithoutOwner() {
+			names = append(names, d.Name)
+		}
+	}
+
+	for _, name := range names {
+		d := &appsv1.Deployment{}
+		err := r.cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: r.instance.Namespace,
+			Name:      name,
+		}, d)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			r.log.Error(err, "Failed to get the deployment")
+			return err
+		}
+		r.log.Info("Deleting the deployment of the preempted kernel",
+			"namespace", r.instance.Namespace, "name", name)
+		if err := r.cli.Delete(context.TODO(), d); err != nil && !errors.IsNotFound(err) {
+			r.log.Error(err, "Failed to delete the deployment")
+			return err
+		}
+	}
+	return nil
+}
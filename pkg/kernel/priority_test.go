@@ -0,0 +1,110 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestPriorityClassName(t *testing.T) {
+	k := &v1alpha1.JupyterKernel{
+		Spec: v1alpha1.JupyterKernelCRDSpec{PriorityTier: v1alpha1.KernelPriorityBatch},
+	}
+	g, err := newGenerator(k, DefaultPriorityClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := g.DesiredDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := d.Spec.Template.Spec.PriorityClassName; name != "jupyter-kernel-batch" {
+		t.Errorf("Expected the PriorityClass of the batch tier, got %q", name)
+	}
+
+	k.Spec.Template.Spec.PriorityClassName = "custom"
+	if name := PriorityClassName(k, DefaultPriorityClasses()); name != "custom" {
+		t.Errorf("Expected the PriorityClass of the pod template, got %q", name)
+	}
+}
+
+func TestReconcilePreempted(t *testing.T) {
+	k := &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "kernel"}}},
+			},
+		},
+	}
+	preemptor := &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "preemptor"},
+	}
+	Preempt(k, preemptor, metav1.Now())
+	if !isConditionTrue(&k.Status, v1alpha1.JupyterKernelFailed) ||
+		k.Status.Preemption.Preemptor != "default/preemptor" {
+		t.Errorf("Expected the preemption in the status, got %v", k.Status)
+	}
+
+	r, cli, _ := newTestReconciler(t, k, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+	})
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	err := cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: "default", Name: "kernel"}, &appsv1.Deployment{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected the deployment of the preempted kernel to be deleted, got %v", err)
+	}
+}
+
+func TestReconcilePriorityClass(t *testing.T) {
+	k := &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			PriorityTier: v1alpha1.KernelPriorityBatch,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "kernel"}}},
+			},
+		},
+	}
+	batch := &schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "jupyter-kernel-batch"},
+	}
+	tests := []struct {
+		name     string
+		objs     []runtime.Object
+		expected string
+		events   int
+	}{
+		{name: "found", objs: []runtime.Object{batch}, expected: "jupyter-kernel-batch"},
+		{name: "not found", expected: "", events: 1},
+	}
+	for _, test := range tests {
+		r, cli, recorder := newTestReconciler(t, k, test.objs...)
+		if err := r.Reconcile(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		d := &appsv1.Deployment{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: "kernel"}, d); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if actual := d.Spec.Template.Spec.PriorityClassName; actual != test.expected {
+			t.Errorf("%s: Expected the PriorityClass %q, got %q", test.name, test.expected, actual)
+		}
+		if len(recorder.Events) != test.events {
+			t.Errorf("%s: Expected %d events, got %d", test.name, test.events, len(recorder.Events))
+		}
+	}
+}
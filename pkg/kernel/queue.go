@@ -0,0 +1,80 @@
+package kernel
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// ReasonWaiting is the reason of the Queued condition of the kernels
+	// which wait to be admitted.
+	ReasonWaiting = "Waiting"
+	// ReasonAdmitted is the reason of the Queued condition of the kernels
+	// which are admitted by the queue.
+	ReasonAdmitted = "Admitted"
+)
+
+// Queued returns true if the kernel waits in the queue.
+func Queued(k *v1alpha1.JupyterKernel) bool {
+	return isConditionTrue(&k.Status, v1alpha1.JupyterKernelQueued)
+}
+
+// Admitted returns true if the kernel is admitted by the queue.
+func Admitted(k *v1alpha1.JupyterKernel) bool {
+	for _, c := range k.Status.Conditions {
+		if c.Type == v1alpha1.JupyterKernelQueued {
+			return c.Status == v1.ConditionFalse
+		}
+	}
+	return false
+}
+
+// Admit admits the kernel in the status.
+func Admit(k *v1alpha1.JupyterKernel) {
+	setCondition(&k.Status, v1alpha1.JupyterKernelQueued,
+		v1.ConditionFalse, ReasonAdmitted, "")
+	k.Status.QueuePosition = nil
+}
+
+// SetQueuePosition sets the position of the queued kernel in the status.
+func SetQueuePosition(k *v1alpha1.JupyterKernel, position int32) {
+	k.Status.QueuePosition = &position
+}
+
+// reconcileQueue puts the kernel into the queue if it is not admitted. It
+// returns false if the kernel waits in the queue. The kernels whose
+// deployments exist are treated as admitted.
+func (r Reconciler) reconcileQueue() (bool, error) {
+	if !r.opts.QueueEnabled || Admitted(r.instance) {
+		return true, nil
+	}
+	err := r.cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: r.instance.Namespace,
+		Name:      r.instance.Name,
+	}, &appsv1.Deployment{})
+	if err == nil {
+		return true, nil
+	} else if !errors.IsNotFound(err) {
+		r.log.Error(err, "Failed to get the deployment")
+		return false, err
+	}
+
+	if Queued(r.instance) {
+		return false, nil
+	}
+	r.log.Info("Queueing kernel", "namespace", r.instance.Namespace,
+		"name", r.instance.Name)
+	setCondition(&r.instance.Status, v1alpha1.JupyterKernelQueued,
+		v1.ConditionTrue, ReasonWaiting, "The kernel waits to be admitted by the queue")
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the kernel")
+		return false, err
+	}
+	return false, nil
+}
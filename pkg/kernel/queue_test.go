@@ -0,0 +1,57 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestReconcileQueue(t *testing.T) {
+	k := &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "kernel"}}},
+			},
+		},
+	}
+	r, cli, _ := newTestReconciler(t, k)
+	r.opts.QueueEnabled = true
+	key := types.NamespacedName{Namespace: k.Namespace, Name: k.Name}
+	reconcile := func() {
+		if err := cli.Get(context.TODO(), key, r.instance); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Reconcile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reconcile()
+	actual := &v1alpha1.JupyterKernel{}
+	if err := cli.Get(context.TODO(), key, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !Queued(actual) {
+		t.Errorf("Expected the kernel to be queued, got %v", actual.Status)
+	}
+	if err := cli.Get(context.TODO(), key, &appsv1.Deployment{}); !errors.IsNotFound(err) {
+		t.Errorf("Expected no deployment for the queued kernel, got %v", err)
+	}
+
+	Admit(actual)
+	if err := cli.Status().Update(context.TODO(), actual); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if err := cli.Get(context.TODO(), key, &appsv1.Deployment{}); err != nil {
+		t.Errorf("Expected the deployment of the admitted kernel, got %v", err)
+	}
+}
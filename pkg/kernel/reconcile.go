@@ -2,52 +2,130 @@ package kernel
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
 )
 
+// Options are the options of the kernels, which are set by the operator
+// configuration.
+type Options struct {
+	// Lifetimes are the lifetimes of the kernels which do not set them,
+	// keyed by the namespace. The key "" applies to all the namespaces.
+	Lifetimes map[string]v1alpha1.KernelLifetime
+	// LifetimeWarningPeriod is the duration before the deadline of the
+	// kernel, at which the warning event is emitted.
+	LifetimeWarningPeriod time.Duration
+	// QueueEnabled enables the queueing mode, in which the kernel pods
+	// are created only after the kernels are admitted by the queue.
+	QueueEnabled bool
+	// PriorityClasses maps the priority tiers of the kernels to the
+	// PriorityClasses.
+	PriorityClasses map[v1alpha1.KernelPriorityTier]string
+}
+
+// DefaultOptions returns the default options of the kernels.
+func DefaultOptions() Options {
+	return Options{
+		LifetimeWarningPeriod: DefaultLifetimeWarningPeriod,
+		PriorityClasses:       DefaultPriorityClasses(),
+	}
+}
+
 type Reconciler struct {
 	cli      client.Client
+	pods     corev1client.PodsGetter
 	log      logr.Logger
 	recorder record.EventRecorder
 	scheme   *runtime.Scheme
+	opts     Options
 
 	instance *v1alpha1.JupyterKernel
 	gen      *generator
 }
 
-func NewReconciler(cli client.Client, l logr.Logger,
+func NewReconciler(cli client.Client, pods corev1client.PodsGetter, l logr.Logger,
 	r record.EventRecorder, s *runtime.Scheme,
-	i *v1alpha1.JupyterKernel) (*Reconciler, error) {
-	g, err := newGenerator(i)
+	i *v1alpha1.JupyterKernel, opts Options) (*Reconciler, error) {
+	g, err := newGenerator(i, opts.PriorityClasses)
 	if err != nil {
 		return nil, err
 	}
 	return &Reconciler{
 		cli:      cli,
+		pods:     pods,
 		log:      l,
 		recorder: r,
 		scheme:   s,
+		opts:     opts,
 		instance: i,
 		gen:      g,
 	}, nil
 }
 
 func (r Reconciler) Reconcile() error {
+	// The preempted kernels are not restarted.
+	if Preempted(r.instance) {
+		return r.reconcilePreempted()
+	}
+	// The invalid replica groups are rejected by the webhook, unless it
+	// is not enabled in the namespace.
+	if invalid, err := r.reconcileInvalidReplicaGroups(); err != nil || invalid {
+		return err
+	}
+	if err := r.reconcileResourcesAnnotation(); err != nil {
+		return err
+	}
+	if admitted, err := r.reconcileQueue(); err != nil || !admitted {
+		return err
+	}
+	if err := r.reconcilePriorityClass(); err != nil {
+		return err
+	}
+	if r.instance.Spec.Spark != nil {
+		if err := r.reconcileSparkConfigmap(); err != nil {
+			return err
+		}
+		if err := r.reconcileSparkService(); err != nil {
+			return err
+		}
+	}
+	if r.instance.Spec.Cluster != nil {
+		if err := r.reconcileCluster(); err != nil {
+			return err
+		}
+	}
 	if err := r.reconcileDeployment(); err != nil {
 		return err
 	}
+	if Gang(r.instance) {
+		if err := r.reconcileReplicaGroups(); err != nil {
+			return err
+		}
+	}
+	if r.instance.Spec.Spark != nil {
+		if err := r.reconcileSparkExecutors(); err != nil {
+			return err
+		}
+	}
+	if err := r.reconcileResize(); err != nil {
+		return err
+	}
 
-	return nil
+	return r.reconcileStatus()
 }
 
 func (r Reconciler) reconcileDeployment() error {
@@ -74,12 +152,111 @@ func (r Reconciler) reconcileDeployment() error {
 				"deployment", desired.Name)
 			return err
 		}
+		metrics.KernelLaunched(r.instance)
 	} else if err != nil {
 		r.log.Error(err, "failed to get the expected deployment",
 			"deployment", desired.Name)
 		return err
 	}
+	return nil
+}
+
+func (r Reconciler) reconcileSparkConfigmap() error {
+	desired, err := r.gen.DesiredSparkConfigmapWithoutOwner()
+	if err != nil {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, "FailedToGenerate", err.Error())
+		return err
+	}
+
+	if err := controllerutil.SetControllerReference(
+		r.instance, desired, r.scheme); err != nil {
+		r.log.Error(err,
+			"Set controller reference error, requeuing the request")
+		return err
+	}
+
+	actual := &v1.ConfigMap{}
+	err = r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, actual)
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating confimap", "namespace", desired.Namespace, "name", desired.Name)
+
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the confimap",
+				"confimap", desired.Name)
+			return err
+		}
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected confimap",
+			"confimap", desired.Name)
+		return err
+	}
+	return nil
+}
+
+func (r Reconciler) reconcileSparkService() error {
+	desired := r.gen.DesiredSparkServiceWithoutOwner()
+
+	if err := controllerutil.SetControllerReference(
+		r.instance, desired, r.scheme); err != nil {
+		r.log.Error(err,
+			"Set controller reference error, requeuing the request")
+		return err
+	}
+
+	actual := &v1.Service{}
+	err := r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, actual)
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating service", "namespace", desired.Namespace, "name", desired.Name)
 
-	// TODO(gaocegege): Update status.
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the service",
+				"service", desired.Name)
+			return err
+		}
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected service",
+			"service", desired.Name)
+		return err
+	}
+	return nil
+}
+
+// reconcileSparkExecutors sets the kernel as the owner of the executors,
+// thus they are garbage collected with the kernel.
+func (r Reconciler) reconcileSparkExecutors() error {
+	pods := &v1.PodList{}
+	if err := r.cli.List(context.TODO(), pods,
+		client.InNamespace(r.instance.Namespace),
+		client.MatchingLabels{LabelSparkKernel: r.instance.Name}); err != nil {
+		r.log.Error(err, "Failed to list the executors")
+		return err
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if isOwnedBy(pod, r.instance) {
+			continue
+		}
+		if err := controllerutil.SetOwnerReference(
+			r.instance, pod, r.scheme); err != nil {
+			r.log.Error(err, "Set owner reference error", "pod", pod.Name)
+			return err
+		}
+		if err := r.cli.Update(context.TODO(), pod); err != nil {
+			r.log.Error(err, "Failed to update the executor", "pod", pod.Name)
+			return err
+		}
+	}
 	return nil
 }
+
+func isOwnedBy(o metav1.Object, owner metav1.Object) bool {
+	for _, ref := range o.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
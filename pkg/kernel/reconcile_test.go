@@ -0,0 +1,38 @@
+package kernel
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// newTestReconciler creates the reconciler of the kernel with the default
+// options. The fake client has a copy of the kernel and the objects, and
+// the events are recorded by the returned recorder.
+func newTestReconciler(t *testing.T, k *v1alpha1.JupyterKernel,
+	objs ...runtime.Object) (*Reconciler, client.Client, *record.FakeRecorder) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	cli := fake.NewFakeClientWithScheme(s, append(objs, k.DeepCopy())...)
+	recorder := record.NewFakeRecorder(10)
+	r, err := NewReconciler(cli, kubefake.NewSimpleClientset().CoreV1(),
+		logr.Logger(logf.NullLogger{}), recorder, s, k, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, cli, recorder
+}
@@ -0,0 +1,286 @@
+package kernel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// AnnotationResources is the annotation of the kernel to resize it, which
+// is the JSON of the resource requirements, e.g.
+// {"requests":{"memory":"4Gi"}}. It is used by the gateway, which only
+// patches the metadata of the kernels. The operator moves it to
+// spec.resources.
+const AnnotationResources = "kubeflow.tkestack.io/resources"
+
+const (
+	reasonResized          = "Resized"
+	reasonRestarting       = "Restarting"
+	reasonRestartRequired  = "RestartRequired"
+	reasonResourcesInvalid = "InvalidResources"
+	reasonResizeDenied     = "ResizeDenied"
+	reasonResizing         = "Resizing"
+
+	// The reasons of the PodResizePending condition of the pods.
+	reasonResizeInProgress = "InProgress"
+	reasonResizeDeferred   = "Deferred"
+	reasonResizeInfeasible = "Infeasible"
+)
+
+// podResizePending and podResizeInProgress are the conditions of the pods
+// which are being resized in place.
+const (
+	podResizePending    v1.PodConditionType = "PodResizePending"
+	podResizeInProgress v1.PodConditionType = "PodResizeInProgress"
+)
+
+// subresourceResize is the subresource of the pods to resize them in place.
+const subresourceResize = "resize"
+
+// reconcileResourcesAnnotation moves the resources in the annotation to
+// the spec of the kernel.
+func (r Reconciler) reconcileResourcesAnnotation() error {
+	value, ok := r.instance.Annotations[AnnotationResources]
+	if !ok {
+		return nil
+	}
+	delete(r.instance.Annotations, AnnotationResources)
+
+	resources := &v1.ResourceRequirements{}
+	if err := json.Unmarshal([]byte(value), resources); err != nil {
+		r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonResourcesInvalid,
+			"Failed to parse the annotation %s: %v", AnnotationResources, err)
+		return r.cli.Update(context.TODO(), r.instance)
+	}
+
+	original := r.instance.Spec.Resources
+	r.instance.Spec.Resources = resources
+	err := r.cli.Update(context.TODO(), r.instance)
+	if errors.IsForbidden(err) {
+		// The resize is denied by the webhook, e.g. the quotas. Only the
+		// annotation is removed.
+		r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonResizeDenied,
+			"Failed to resize the kernel: %v", err)
+		r.instance.Spec.Resources = original
+		err = r.cli.Update(context.TODO(), r.instance)
+	}
+	if err != nil {
+		r.log.Error(err, "Failed to update the resources of the kernel")
+		return err
+	}
+	return nil
+}
+
+// reconcileResize resizes the kernel pods whose resources differ from the
+// spec. The pods are patched in place by the pods/resize subresource, and
+// the kernel gets the Resizing condition until the kubelet resizes them.
+// If the cluster rejects the patch, or the node cannot fit the resources,
+// the kernel is restarted by updating the deployment if the resize policy
+// is Restart, otherwise the RestartRequired condition is set.
+func (r Reconciler) reconcileResize() error {
+	desired := r.instance.Spec.Resources
+	if desired == nil {
+		return nil
+	}
+	pods := &v1.PodList{}
+	if err := r.cli.List(context.TODO(), pods,
+		client.InNamespace(r.instance.Namespace),
+		client.MatchingLabels{LabelKernel: r.instance.Name}); err != nil {
+		r.log.Error(err, "Failed to list the kernel pods")
+		return err
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		actual := &pod.Spec.Containers[0].Resources
+		if equality.Semantic.DeepEqual(actual, desired) {
+			reason, message, _ := resizeStatus(pod)
+			if reason != reasonResizeInfeasible {
+				continue
+			}
+			return r.restartRequired(fmt.Sprintf(
+				"The kernel pod %s cannot be resized in place: %s", pod.Name, message))
+		}
+
+		patch, err := resourcesPatch(pod.Spec.Containers[0].Name, desired)
+		if err != nil {
+			return err
+		}
+		_, err = r.pods.Pods(pod.Namespace).Patch(context.TODO(), pod.Name,
+			types.StrategicMergePatchType, patch, metav1.PatchOptions{}, subresourceResize)
+		if err == nil {
+			message := fmt.Sprintf("Resizing the kernel pod %s in place", pod.Name)
+			r.log.Info("Resizing the kernel pod in place",
+				"namespace", pod.Namespace, "pod", pod.Name)
+			r.recorder.Event(r.instance, v1.EventTypeNormal, reasonResizing, message)
+			setCondition(&r.instance.Status, v1alpha1.JupyterKernelResizing,
+				v1.ConditionTrue, reasonResizeInProgress, message)
+			if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+				r.log.Error(err, "Failed to update the status of the kernel")
+				return err
+			}
+			continue
+		}
+		// The subresource is not found in the clusters without the in-place
+		// pod resize.
+		if !errors.IsInvalid(err) && !errors.IsForbidden(err) && !errors.IsNotFound(err) {
+			r.log.Error(err, "Failed to resize the kernel pod", "pod", pod.Name)
+			return err
+		}
+		return r.restartRequired(fmt.Sprintf(
+			"The kernel pod %s cannot be resized in place: %v", pod.Name, err))
+	}
+	return nil
+}
+
+// restartRequired restarts the kernel if the resize policy is Restart,
+// otherwise it sets the RestartRequired condition.
+func (r Reconciler) restartRequired(message string) error {
+	if r.instance.Spec.ResizePolicy == v1alpha1.KernelResizeRestart {
+		return r.restart(message)
+	}
+	status := &r.instance.Status
+	if isConditionTrue(status, v1alpha1.JupyterKernelRestartRequired) {
+		return nil
+	}
+	r.recorder.Event(r.instance, v1.EventTypeWarning, reasonRestartRequired, message)
+	setCondition(status, v1alpha1.JupyterKernelRestartRequired,
+		v1.ConditionTrue, reasonRestartRequired, message)
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the kernel")
+		return err
+	}
+	return nil
+}
+
+// resizeStatus returns the reason and the message if the in-place resize
+// of the pod is not finished, which is InProgress, or Deferred and
+// Infeasible in the PodResizePending condition.
+func resizeStatus(pod *v1.Pod) (string, string, bool) {
+	for _, c := range pod.Status.Conditions {
+		if c.Status != v1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case podResizePending:
+			return c.Reason, c.Message, true
+		case podResizeInProgress:
+			return reasonResizeInProgress, c.Message, true
+		}
+	}
+	return "", "", false
+}
+
+// resourcesPatch returns the strategic merge patch of the resources of the
+// container in the pod.
+func resourcesPatch(container string, resources *v1.ResourceRequirements) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []map[string]interface{}{
+				{"name": container, "resources": resources},
+			},
+		},
+	})
+}
+
+// restart updates the resources in the deployment, thus the kernel pod is
+// replaced by a new one with the resources. The state of the kernel is
+// lost, unless the kernel checkpoints it when it is terminated, e.g. in
+// the preStop hook, and restores it when it starts.
+func (r Reconciler) restart(message string) error {
+	d := &appsv1.Deployment{}
+	if err := r.cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: r.instance.Namespace,
+		Name:      r.instance.Name,
+	}, d); err != nil {
+		r.log.Error(err, "Failed to get the deployment")
+		return err
+	}
+	if len(d.Spec.Template.Spec.Containers) == 0 ||
+		equality.Semantic.DeepEqual(&d.Spec.Template.Spec.Containers[0].Resources,
+			r.instance.Spec.Resources) {
+		return nil
+	}
+	d.Spec.Template.Spec.Containers[0].Resources = *r.instance.Spec.Resources.DeepCopy()
+	if err := r.cli.Update(context.TODO(), d); err != nil {
+		r.log.Error(err, "Failed to update the resources of the deployment")
+		return err
+	}
+	r.log.Info("Restarting the kernel to resize it",
+		"namespace", r.instance.Namespace, "name", r.instance.Name)
+	r.recorder.Event(r.instance, v1.EventTypeNormal, reasonRestarting, message)
+	// The pod which is being resized in place is replaced.
+	if isConditionTrue(&r.instance.Status, v1alpha1.JupyterKernelResizing) {
+		setCondition(&r.instance.Status, v1alpha1.JupyterKernelResizing,
+			v1.ConditionFalse, reasonRestarting, message)
+		if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+			r.log.Error(err, "Failed to update the status of the kernel")
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileResizeStatus records the resources of the kernel pod, and
+// clears the RestartRequired condition once the pod is resized. The
+// Resizing condition follows the resize of the kubelet, and turns false
+// once it is finished.
+func (r Reconciler) reconcileResizeStatus(pods []v1.Pod) {
+	status := &r.instance.Status
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil || len(pod.Spec.Containers) == 0 ||
+			pod.Labels[LabelKernel] != r.instance.Name {
+			continue
+		}
+		resources := pod.Spec.Containers[0].Resources.DeepCopy()
+		status.Resources = resources
+		if !equality.Semantic.DeepEqual(resources, r.instance.Spec.Resources) {
+			return
+		}
+		_, _, pending := resizeStatus(pod)
+		if isConditionTrue(status, v1alpha1.JupyterKernelRestartRequired) && !pending {
+			setCondition(status, v1alpha1.JupyterKernelRestartRequired,
+				v1.ConditionFalse, reasonResized, "")
+		}
+		if isConditionTrue(status, v1alpha1.JupyterKernelResizing) {
+			r.reconcileResizing(pod)
+		}
+		return
+	}
+}
+
+// reconcileResizing updates the Resizing condition from the pod, and
+// records the event when the resize is finished, deferred or infeasible.
+func (r Reconciler) reconcileResizing(pod *v1.Pod) {
+	status := &r.instance.Status
+	reason, message, pending := resizeStatus(pod)
+	if !pending {
+		r.recorder.Eventf(r.instance, v1.EventTypeNormal, reasonResized,
+			"Resized the kernel pod %s in place", pod.Name)
+		setCondition(status, v1alpha1.JupyterKernelResizing,
+			v1.ConditionFalse, reasonResized, "")
+		return
+	}
+	if c := getCondition(status, v1alpha1.JupyterKernelResizing); c != nil && c.Reason == reason {
+		return
+	}
+	if reason == reasonResizeDeferred || reason == reasonResizeInfeasible {
+		r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonResizing,
+			"The resize of the kernel pod %s is %s: %s", pod.Name, reason, message)
+	}
+	setCondition(status, v1alpha1.JupyterKernelResizing, v1.ConditionTrue, reason, message)
+}
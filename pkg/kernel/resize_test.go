@@ -0,0 +1,207 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// noInPlaceResize rejects the resize of the pods, as the clusters without
+// the pods/resize subresource.
+func noInPlaceResize(action k8stesting.Action) (bool, runtime.Object, error) {
+	if action.GetSubresource() != subresourceResize {
+		return false, nil, nil
+	}
+	return true, nil, errors.NewNotFound(schema.GroupResource{Resource: "pods/resize"}, "")
+}
+
+func memory(q string) v1.ResourceRequirements {
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse(q)},
+	}
+}
+
+func TestReconcileResize(t *testing.T) {
+	tests := []struct {
+		name            string
+		inPlace         bool
+		policy          v1alpha1.KernelResizePolicy
+		podResized      bool
+		restarted       bool
+		restartRequired bool
+	}{
+		{name: "in place", inPlace: true, podResized: true},
+		{name: "restart required", restartRequired: true},
+		{name: "restart", policy: v1alpha1.KernelResizeRestart, restarted: true},
+	}
+	for _, test := range tests {
+		k := &v1alpha1.JupyterKernel{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "kernel",
+				// The gateway resizes the kernel with the annotation.
+				Annotations: map[string]string{
+					AnnotationResources: `{"requests":{"memory":"4Gi"}}`,
+				},
+			},
+			Spec: v1alpha1.JupyterKernelCRDSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{Name: "kernel", Resources: memory("1Gi")}},
+					},
+				},
+				ResizePolicy: test.policy,
+			},
+		}
+		pod := newKernelPod(k, v1.PodStatus{Phase: v1.PodRunning})
+		pod.Spec = *k.Spec.Template.Spec.DeepCopy()
+		g, err := newGenerator(k, DefaultPriorityClasses())
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := g.DesiredDeployment()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, cli, _ := newTestReconciler(t, k, pod, d)
+		clientset := kubefake.NewSimpleClientset(pod.DeepCopy())
+		if !test.inPlace {
+			clientset.PrependReactor("patch", "pods", noInPlaceResize)
+		}
+		r.pods = clientset.CoreV1()
+		if err := r.Reconcile(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		key := types.NamespacedName{Namespace: "default", Name: "kernel"}
+		actual := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), key, actual); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := actual.Annotations[AnnotationResources]; ok ||
+			actual.Spec.Resources == nil {
+			t.Errorf("%s: Expected the annotation to be moved to the spec, got %v",
+				test.name, actual.Spec.Resources)
+		}
+		if isConditionTrue(&actual.Status, v1alpha1.JupyterKernelRestartRequired) != test.restartRequired {
+			t.Errorf("%s: Expected RestartRequired %v", test.name, test.restartRequired)
+		}
+		if isConditionTrue(&actual.Status, v1alpha1.JupyterKernelResizing) != test.podResized {
+			t.Errorf("%s: Expected Resizing %v", test.name, test.podResized)
+		}
+
+		want := resource.MustParse("4Gi")
+		p, err := clientset.CoreV1().Pods("default").Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Spec.Containers[0].Resources.Requests[v1.ResourceMemory]; (got.Cmp(want) == 0) != test.podResized {
+			t.Errorf("%s: Expected the pod resized %v, got %s", test.name, test.podResized, got.String())
+		}
+		deployment := &appsv1.Deployment{}
+		if err := cli.Get(context.TODO(), key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if got := deployment.Spec.Template.Spec.Containers[0].Resources.Requests[v1.ResourceMemory]; (got.Cmp(want) == 0) != test.restarted {
+			t.Errorf("%s: Expected the deployment updated %v, got %s", test.name, test.restarted, got.String())
+		}
+	}
+}
+
+func TestReconcileResizing(t *testing.T) {
+	resources := memory("4Gi")
+	tests := []struct {
+		name            string
+		conditions      []v1.PodCondition
+		resizing        bool
+		reason          string
+		restartRequired bool
+		events          int
+	}{
+		{name: "resized", reason: reasonResized, events: 1},
+		{
+			name: "in progress",
+			conditions: []v1.PodCondition{
+				{Type: podResizeInProgress, Status: v1.ConditionTrue},
+			},
+			resizing: true,
+			reason:   reasonResizeInProgress,
+		},
+		{
+			name: "deferred",
+			conditions: []v1.PodCondition{
+				{Type: podResizePending, Status: v1.ConditionTrue, Reason: reasonResizeDeferred},
+			},
+			resizing: true,
+			reason:   reasonResizeDeferred,
+			events:   1,
+		},
+		{
+			name: "infeasible",
+			conditions: []v1.PodCondition{
+				{Type: podResizePending, Status: v1.ConditionTrue, Reason: reasonResizeInfeasible},
+			},
+			resizing:        true,
+			reason:          reasonResizeInfeasible,
+			restartRequired: true,
+			// The warnings of the resize and the restart.
+			events: 2,
+		},
+	}
+	for _, test := range tests {
+		k := &v1alpha1.JupyterKernel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+			Spec: v1alpha1.JupyterKernelCRDSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{Name: "kernel", Resources: memory("1Gi")}},
+					},
+				},
+				Resources: &resources,
+			},
+		}
+		setCondition(&k.Status, v1alpha1.JupyterKernelResizing,
+			v1.ConditionTrue, reasonResizeInProgress, "")
+		// The pod is patched, while the kubelet may not resize it yet.
+		pod := newKernelPod(k, v1.PodStatus{Phase: v1.PodRunning, Conditions: test.conditions})
+		pod.Spec = *PodSpec(k)
+
+		r, cli, recorder := newTestReconciler(t, k, pod)
+		if err := r.reconcileResize(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := r.reconcileStatus(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		actual := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: "kernel"}, actual); err != nil {
+			t.Fatal(err)
+		}
+		c := getCondition(&actual.Status, v1alpha1.JupyterKernelResizing)
+		if (c.Status == v1.ConditionTrue) != test.resizing || c.Reason != test.reason {
+			t.Errorf("%s: Expected Resizing %v with the reason %s, got %v",
+				test.name, test.resizing, test.reason, c)
+		}
+		if isConditionTrue(&actual.Status, v1alpha1.JupyterKernelRestartRequired) != test.restartRequired {
+			t.Errorf("%s: Expected RestartRequired %v", test.name, test.restartRequired)
+		}
+		if len(recorder.Events) != test.events {
+			t.Errorf("%s: Expected %d events, got %d", test.name, test.events, len(recorder.Events))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package kernel
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// Requests returns the total resources requested by the containers of the
+// kernel, including the pods of the replica groups. The limits are used if
+// the requests are not set, which is the default of Kubernetes.
+func Requests(k *v1alpha1.JupyterKernel) v1.ResourceList {
+	total := v1.ResourceList{}
+	for _, spec := range PodSpecs(k) {
+		for name, q := range PodRequests(spec) {
+			addQuantity(total, name, q)
+		}
+	}
+	return total
+}
+
+// PodSpec returns the spec of the kernel pod, in which the resources of
+// the kernel container are overridden by the resources of the kernel.
+func PodSpec(k *v1alpha1.JupyterKernel) *v1.PodSpec {
+	if k.Spec.Resources == nil || len(k.Spec.Template.Spec.Containers) == 0 {
+		return &k.Spec.Template.Spec
+	}
+	spec := k.Spec.Template.Spec.DeepCopy()
+	spec.Containers[0].Resources = *k.Spec.Resources.DeepCopy()
+	return spec
+}
+
+// PodRequests returns the total resources requested by the containers of
+// the pod.
+func PodRequests(spec *v1.PodSpec) v1.ResourceList {
+	total := v1.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Limits {
+			if _, ok := c.Resources.Requests[name]; ok {
+				continue
+			}
+			addQuantity(total, name, q)
+		}
+		for name, q := range c.Resources.Requests {
+			addQuantity(total, name, q)
+		}
+	}
+	return total
+}
+
+func addQuantity(l v1.ResourceList, name v1.ResourceName, q resource.Quantity) {
+	total := l[name].DeepCopy()
+	total.Add(q)
+	l[name] = total
+}
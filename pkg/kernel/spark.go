@@ -0,0 +1,220 @@
+package kernel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/tkestack/elastic-jupyter-operator/pkg/podcache"
+)
+
+const (
+	// LabelSparkKernel is the label of the executors, which is set to the
+	// name of the kernel. It differs from LabelKernel to keep the executors
+	// out of the selector of the kernel deployment.
+	LabelSparkKernel = "kernel_spark"
+
+	defaultSparkDriverPort       = 7078
+	defaultSparkBlockManagerPort = 7079
+	sparkDriverPortName          = "driver"
+	sparkBlockManagerPortName    = "blockmanager"
+
+	sparkConfDir          = "/etc/kernel-spark"
+	sparkConfVolume       = "spark-conf"
+	fileSparkDefaults     = "spark-defaults.conf"
+	fileExecutorTemplate  = "executor-pod-template.yaml"
+	envSparkConfDir       = "SPARK_CONF_DIR"
+	sparkResourceSuffix   = "-spark"
+	sparkDriverSvcSuffix  = "-driver"
+	defaultSparkMasterURL = "k8s://https://kubernetes.default.svc"
+)
+
+// DesiredSparkServiceWithoutOwner returns the headless service of the
+// driver, which is used by the executors to connect to the kernel.
+func (g generator) DesiredSparkServiceWithoutOwner() *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sparkDriverServiceName(g.k.Name),
+			Namespace: g.k.Namespace,
+			Labels:    g.labels(),
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: v1.ClusterIPNone,
+			Selector:  g.labels(),
+			Ports: []v1.ServicePort{
+				{
+					Name:     sparkDriverPortName,
+					Port:     g.sparkDriverPort(),
+					Protocol: v1.ProtocolTCP,
+				},
+				{
+					Name:     sparkBlockManagerPortName,
+					Port:     g.sparkBlockManagerPort(),
+					Protocol: v1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// DesiredSparkConfigmapWithoutOwner returns the configmap which contains
+// spark-defaults.conf and the executor pod template.
+func (g generator) DesiredSparkConfigmapWithoutOwner() (*v1.ConfigMap, error) {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sparkConfigmapName(g.k.Name),
+			Namespace: g.k.Namespace,
+			Labels:    g.labels(),
+		},
+		Data: map[string]string{
+			fileSparkDefaults: g.sparkDefaults(),
+		},
+	}
+
+	if t := g.k.Spec.Spark.ExecutorTemplate; t != nil {
+		pod := &v1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Pod",
+			},
+			ObjectMeta: t.ObjectMeta,
+			Spec:       t.Spec,
+		}
+		b, err := yaml.Marshal(pod)
+		if err != nil {
+			return nil, err
+		}
+		cm.Data[fileExecutorTemplate] = string(b)
+	}
+	return cm, nil
+}
+
+// sparkConf returns the Spark configuration of the kernel, which is
+// overridden by the conf in the template.
+func (g generator) sparkConf() map[string]string {
+	spark := g.k.Spec.Spark
+	conf := map[string]string{
+		"spark.master":               defaultSparkMasterURL,
+		"spark.submit.deployMode":    "client",
+		"spark.kubernetes.namespace": g.k.Namespace,
+		"spark.driver.host": fmt.Sprintf("%s.%s.svc",
+			sparkDriverServiceName(g.k.Name), g.k.Namespace),
+		"spark.driver.bindAddress": "0.0.0.0",
+		"spark.driver.port":        strconv.Itoa(int(g.sparkDriverPort())),
+		"spark.blockManager.port":  strconv.Itoa(int(g.sparkBlockManagerPort())),
+	}
+	conf["spark.kubernetes.executor.label."+LabelSparkKernel] = g.k.Name
+	conf["spark.kubernetes.executor.label."+podcache.LabelManaged] = podcache.ValueKernel
+	if image := g.sparkExecutorImage(); image != "" {
+		conf["spark.kubernetes.container.image"] = image
+	}
+	if spark.ExecutorTemplate != nil {
+		conf["spark.kubernetes.executor.podTemplateFile"] = fmt.Sprintf(
+			"%s/%s", sparkConfDir, fileExecutorTemplate)
+	}
+	if spark.Executors != nil {
+		conf["spark.executor.instances"] = strconv.Itoa(int(*spark.Executors))
+	}
+	for k, v := range spark.Conf {
+		conf[k] = v
+	}
+	return conf
+}
+
+// sparkDefaults renders the Spark configuration in the format of
+// spark-defaults.conf.
+func (g generator) sparkDefaults() string {
+	conf := g.sparkConf()
+	keys := make([]string, 0, len(conf))
+	for k := range conf {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s %s\n", k, conf[k])
+	}
+	return b.String()
+}
+
+// sparkExecutorImage returns the image of the executors, which defaults
+// to the image of the kernel.
+func (g generator) sparkExecutorImage() string {
+	if t := g.k.Spec.Spark.ExecutorTemplate; t != nil &&
+		len(t.Spec.Containers) != 0 && t.Spec.Containers[0].Image != "" {
+		return t.Spec.Containers[0].Image
+	}
+	if len(g.k.Spec.Template.Spec.Containers) != 0 {
+		return g.k.Spec.Template.Spec.Containers[0].Image
+	}
+	return ""
+}
+
+// setSparkDriver mounts the Spark configuration into the kernel and
+// exposes the ports of the driver.
+func (g generator) setSparkDriver(pod *v1.PodTemplateSpec) {
+	if len(pod.Spec.Containers) == 0 {
+		return
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: sparkConfVolume,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: sparkConfigmapName(g.k.Name),
+				},
+			},
+		},
+	})
+
+	c := &pod.Spec.Containers[0]
+	c.VolumeMounts = append(c.VolumeMounts, v1.VolumeMount{
+		Name:      sparkConfVolume,
+		MountPath: sparkConfDir,
+		ReadOnly:  true,
+	})
+	c.Env = append(c.Env, v1.EnvVar{
+		Name:  envSparkConfDir,
+		Value: sparkConfDir,
+	})
+	c.Ports = append(c.Ports,
+		v1.ContainerPort{
+			Name:          sparkDriverPortName,
+			ContainerPort: g.sparkDriverPort(),
+			Protocol:      v1.ProtocolTCP,
+		},
+		v1.ContainerPort{
+			Name:          sparkBlockManagerPortName,
+			ContainerPort: g.sparkBlockManagerPort(),
+			Protocol:      v1.ProtocolTCP,
+		},
+	)
+}
+
+func (g generator) sparkDriverPort() int32 {
+	if p := g.k.Spec.Spark.DriverPort; p != nil {
+		return *p
+	}
+	return defaultSparkDriverPort
+}
+
+func (g generator) sparkBlockManagerPort() int32 {
+	if p := g.k.Spec.Spark.BlockManagerPort; p != nil {
+		return *p
+	}
+	return defaultSparkBlockManagerPort
+}
+
+func sparkDriverServiceName(kernel string) string {
+	return kernel + sparkDriverSvcSuffix
+}
+
+func sparkConfigmapName(kernel string) string {
+	return kernel + sparkResourceSuffix
+}
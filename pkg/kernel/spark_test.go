@@ -0,0 +1,131 @@
+package kernel
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newSparkKernel() *v1alpha1.JupyterKernel {
+	executors := int32(2)
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{Name: "kernel", Image: "elyra/kernel-spark-py:2.6.0"},
+					},
+				},
+			},
+			Spark: &v1alpha1.SparkTemplate{
+				Executors: &executors,
+				Conf: map[string]string{
+					"spark.executor.memory": "2g",
+					"spark.driver.host":     "driver.example.com",
+				},
+			},
+		},
+	}
+}
+
+func TestSparkConf(t *testing.T) {
+	k := newSparkKernel()
+	g, err := newGenerator(k, DefaultPriorityClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := g.sparkConf()
+	expected := map[string]string{
+		"spark.master":                                 defaultSparkMasterURL,
+		"spark.kubernetes.namespace":                   "default",
+		"spark.driver.port":                            "7078",
+		"spark.blockManager.port":                      "7079",
+		"spark.executor.instances":                     "2",
+		"spark.executor.memory":                        "2g",
+		"spark.driver.host":                            "driver.example.com",
+		"spark.kubernetes.container.image":             "elyra/kernel-spark-py:2.6.0",
+		"spark.kubernetes.executor.label.kernel_spark": "kernel",
+		"spark.kubernetes.executor.label.kubeflow.tkestack.io/managed-pod": "kernel",
+	}
+	for key, v := range expected {
+		if conf[key] != v {
+			t.Errorf("Expected %s to be %s, got %s", key, v, conf[key])
+		}
+	}
+	if _, ok := conf["spark.kubernetes.executor.podTemplateFile"]; ok {
+		t.Errorf("Expected no executor pod template file")
+	}
+}
+
+func TestSparkExecutorTemplate(t *testing.T) {
+	k := newSparkKernel()
+	k.Spec.Spark.ExecutorTemplate = &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "executor", Image: "elyra/spark:2.6.0"},
+			},
+		},
+	}
+	g, err := newGenerator(k, DefaultPriorityClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := g.DesiredSparkConfigmapWithoutOwner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cm.Data[fileExecutorTemplate], "image: elyra/spark:2.6.0") {
+		t.Errorf("Expected the executor image in the pod template, got %s",
+			cm.Data[fileExecutorTemplate])
+	}
+	defaults := cm.Data[fileSparkDefaults]
+	for _, line := range []string{
+		"spark.kubernetes.container.image elyra/spark:2.6.0\n",
+		"spark.kubernetes.executor.podTemplateFile /etc/kernel-spark/executor-pod-template.yaml\n",
+	} {
+		if !strings.Contains(defaults, line) {
+			t.Errorf("Expected %q in spark-defaults.conf, got %s", line, defaults)
+		}
+	}
+}
+
+func TestSparkDeployment(t *testing.T) {
+	k := newSparkKernel()
+	g, err := newGenerator(k, DefaultPriorityClasses())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := g.DesiredDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := d.Spec.Template.Spec.Containers[0]
+	if len(c.VolumeMounts) != 1 || c.VolumeMounts[0].MountPath != sparkConfDir {
+		t.Errorf("Expected the spark conf to be mounted, got %v", c.VolumeMounts)
+	}
+	if len(c.Ports) != 2 {
+		t.Errorf("Expected the driver ports, got %v", c.Ports)
+	}
+	if len(k.Spec.Template.Spec.Containers[0].VolumeMounts) != 0 {
+		t.Errorf("Expected the kernel spec not to be mutated")
+	}
+
+	svc := g.DesiredSparkServiceWithoutOwner()
+	if svc.Spec.ClusterIP != v1.ClusterIPNone || svc.Name != "kernel-driver" {
+		t.Errorf("Expected headless service kernel-driver, got %s %s",
+			svc.Name, svc.Spec.ClusterIP)
+	}
+	for key := range svc.Spec.Selector {
+		if key == LabelSparkKernel {
+			t.Errorf("Expected the executors not to be selected by the driver service")
+		}
+	}
+}
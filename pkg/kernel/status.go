@@ -0,0 +1,204 @@
+package kernel
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
+)
+
+const (
+	reasonPodRunning = "PodRunning"
+	reasonPodFailed  = "PodFailed"
+)
+
+// failedWaitingReasons are the reasons of the waiting containers, which
+// indicate that the kernel fails to start.
+var failedWaitingReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// reconcileStatus updates the status of the kernel from the kernel pod,
+// and the pods of the replica groups if the kernel is a gang. The launch
+// latency and the failures are recorded in the metrics when the conditions
+// transition.
+func (r Reconciler) reconcileStatus() error {
+	selector := client.MatchingLabels{LabelKernel: r.instance.Name}
+	if Gang(r.instance) {
+		selector = client.MatchingLabels{LabelGang: r.instance.Name}
+	}
+	pods := &v1.PodList{}
+	if err := r.cli.List(context.TODO(), pods,
+		client.InNamespace(r.instance.Namespace), selector); err != nil {
+		r.log.Error(err, "Failed to list the kernel pods")
+		return err
+	}
+
+	original := r.instance.Status.DeepCopy()
+	status := &r.instance.Status
+	r.reconcileResizeStatus(pods.Items)
+	if Gang(r.instance) {
+		groups, started, complete := gangStatus(r.instance, pods.Items)
+		status.ReplicaGroups = groups
+		if !r.reconcilePodFailure(pods.Items) {
+			if complete {
+				r.setRunning(started)
+			} else if groups[0].Ready != 0 {
+				// The kernel pod is started, while the gang is incomplete.
+				message := gangIncompleteMessage(groups)
+				setCondition(status, v1alpha1.JupyterKernelRunning,
+					v1.ConditionFalse, reasonGangIncomplete, message)
+				setCondition(status, v1alpha1.JupyterKernelFailed,
+					v1.ConditionFalse, reasonGangIncomplete, "")
+			}
+		}
+	} else if !r.reconcilePodFailure(pods.Items) {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			if started, ok := podStartedAt(pod); ok {
+				r.setRunning(started)
+				break
+			}
+		}
+	}
+
+	if equality.Semantic.DeepEqual(original, status) {
+		return nil
+	}
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the kernel")
+		return err
+	}
+	return nil
+}
+
+// reconcilePodFailure marks the kernel as failed if any of the pods fails.
+func (r Reconciler) reconcilePodFailure(pods []v1.Pod) bool {
+	status := &r.instance.Status
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		reason, message, failed := podFailure(pod)
+		if !failed {
+			continue
+		}
+		if !isConditionTrue(status, v1alpha1.JupyterKernelFailed) {
+			metrics.KernelFailed(r.instance)
+			r.recorder.Event(r.instance, v1.EventTypeWarning, reason, message)
+		}
+		setCondition(status, v1alpha1.JupyterKernelRunning,
+			v1.ConditionFalse, reasonPodFailed, message)
+		setCondition(status, v1alpha1.JupyterKernelFailed,
+			v1.ConditionTrue, reason, message)
+		return true
+	}
+	return false
+}
+
+// setRunning marks the kernel as running since the time.
+func (r Reconciler) setRunning(started metav1.Time) {
+	status := &r.instance.Status
+	if status.StartTime == nil {
+		status.StartTime = &started
+		metrics.KernelRunning(r.instance, started)
+	}
+	setCondition(status, v1alpha1.JupyterKernelRunning,
+		v1.ConditionTrue, reasonPodRunning, "")
+	setCondition(status, v1alpha1.JupyterKernelFailed,
+		v1.ConditionFalse, reasonPodRunning, "")
+}
+
+// podFailure returns the reason and the message if the pod fails.
+func podFailure(pod *v1.Pod) (string, string, bool) {
+	if pod.Status.Phase == v1.PodFailed {
+		return pod.Status.Reason, pod.Status.Message, true
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if w := s.State.Waiting; w != nil && failedWaitingReasons[w.Reason] {
+			return w.Reason, w.Message, true
+		}
+	}
+	return "", "", false
+}
+
+// podStartedAt returns the time when all the containers of the pod are
+// started.
+func podStartedAt(pod *v1.Pod) (metav1.Time, bool) {
+	if pod.Status.Phase != v1.PodRunning || len(pod.Status.ContainerStatuses) == 0 {
+		return metav1.Time{}, false
+	}
+	started := metav1.Time{}
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.State.Running == nil {
+			return metav1.Time{}, false
+		}
+		if started.Before(&s.State.Running.StartedAt) {
+			started = s.State.Running.StartedAt
+		}
+	}
+	return started, true
+}
+
+// setCondition sets the condition in the status. The transition time is
+// only updated when the condition status changes.
+func setCondition(status *v1alpha1.JupyterKernelStatus,
+	t v1alpha1.JupyterKernelConditionType, s v1.ConditionStatus,
+	reason, message string) {
+	now := metav1.Now()
+	for i := range status.Conditions {
+		c := &status.Conditions[i]
+		if c.Type != t {
+			continue
+		}
+		if c.Status == s && c.Reason == reason && c.Message == message {
+			return
+		}
+		if c.Status != s {
+			c.LastTransitionTime = now
+		}
+		c.Status = s
+		c.Reason = reason
+		c.Message = message
+		c.LastUpdateTime = now
+		return
+	}
+	status.Conditions = append(status.Conditions, v1alpha1.JupyterKernelCondition{
+		Type:               t,
+		Status:             s,
+		Reason:             reason,
+		Message:            message,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+	})
+}
+
+func isConditionTrue(status *v1alpha1.JupyterKernelStatus,
+	t v1alpha1.JupyterKernelConditionType) bool {
+	c := getCondition(status, t)
+	return c != nil && c.Status == v1.ConditionTrue
+}
+
+func getCondition(status *v1alpha1.JupyterKernelStatus,
+	t v1alpha1.JupyterKernelConditionType) *v1alpha1.JupyterKernelCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == t {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package kernel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newKernelPod(k *v1alpha1.JupyterKernel, status v1.PodStatus) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: k.Namespace,
+			Name:      k.Name + "-pod",
+			Labels:    map[string]string{LabelKernel: k.Name},
+		},
+		Status: status,
+	}
+}
+
+func TestReconcileStatus(t *testing.T) {
+	started := metav1.NewTime(time.Now().Truncate(time.Second))
+	type test struct {
+		name     string
+		status   v1.PodStatus
+		running  v1.ConditionStatus
+		failed   v1.ConditionStatus
+		started  bool
+		recorded int
+	}
+	tests := []test{
+		{
+			name:   "pending",
+			status: v1.PodStatus{Phase: v1.PodPending},
+		},
+		{
+			name: "running",
+			status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					{State: v1.ContainerState{
+						Running: &v1.ContainerStateRunning{StartedAt: started},
+					}},
+				},
+			},
+			running: v1.ConditionTrue,
+			failed:  v1.ConditionFalse,
+			started: true,
+		},
+		{
+			name: "image pull back off",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{State: v1.ContainerState{
+						Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+					}},
+				},
+			},
+			running:  v1.ConditionFalse,
+			failed:   v1.ConditionTrue,
+			recorded: 1,
+		},
+	}
+
+	for _, test := range tests {
+		k := &v1alpha1.JupyterKernel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+		}
+		r, cli, recorder := newTestReconciler(t, k, newKernelPod(k, test.status))
+		// The conditions are only updated once.
+		for i := 0; i < 2; i++ {
+			if err := r.reconcileStatus(); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+
+		actual := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: "kernel"}, actual); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []struct {
+			t v1alpha1.JupyterKernelConditionType
+			s v1.ConditionStatus
+		}{
+			{v1alpha1.JupyterKernelRunning, test.running},
+			{v1alpha1.JupyterKernelFailed, test.failed},
+		} {
+			s := v1.ConditionStatus("")
+			for _, cond := range actual.Status.Conditions {
+				if cond.Type == c.t {
+					s = cond.Status
+				}
+			}
+			if s != c.s {
+				t.Errorf("%s: Expected %s to be %q, got %q", test.name, c.t, c.s, s)
+			}
+		}
+		if test.started != (actual.Status.StartTime != nil) {
+			t.Errorf("%s: Expected the start time to be set: %v", test.name, test.started)
+		}
+		if len(recorder.Events) != test.recorded {
+			t.Errorf("%s: Expected %d events, got %d", test.name, test.recorded, len(recorder.Events))
+		}
+	}
+}
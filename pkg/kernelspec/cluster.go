@@ -0,0 +1,229 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kernelspec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// ClusterReconciler renders the ClusterJupyterKernelSpec into every
+// namespace which has a JupyterGateway referencing it.
+type ClusterReconciler struct {
+	cli      client.Client
+	log      logr.Logger
+	recorder record.EventRecorder
+	scheme   *runtime.Scheme
+
+	instance *v1alpha1.ClusterJupyterKernelSpec
+}
+
+func NewClusterReconciler(cli client.Client, l logr.Logger,
+	r record.EventRecorder, s *runtime.Scheme,
+	i *v1alpha1.ClusterJupyterKernelSpec) (*ClusterReconciler, error) {
+	if i == nil {
+		return nil, fmt.Errorf("Got nil when initializing ClusterReconciler")
+	}
+	return &ClusterReconciler{
+		cli:      cli,
+		log:      l,
+		recorder: r,
+		scheme:   s,
+		instance: i,
+	}, nil
+}
+
+func (r ClusterReconciler) Reconcile() error {
+	original := r.instance.Status.DeepCopy()
+
+	if err := r.reconcileTemplate(); err != nil {
+		return err
+	}
+
+	namespaces, err := r.namespaces()
+	if err != nil {
+		return err
+	}
+
+	if err := r.reconcileConfigmaps(namespaces); err != nil {
+		return err
+	}
+	if err := r.cleanupConfigmaps(namespaces); err != nil {
+		return err
+	}
+	if err := r.reconcileTemplateStatus(); err != nil {
+		return err
+	}
+
+	return r.updateStatus(original)
+}
+
+// reconcileConfigmaps renders the configmap in every namespace which has
+// a gateway referencing the cluster kernel spec.
+func (r ClusterReconciler) reconcileConfigmaps(namespaces map[string]bool) error {
+	status := &r.instance.Status
+	// The content does not depend on the namespace, thus render it once
+	// to report the errors and the hash.
+	g, err := newClusterGenerator(r.instance, "")
+	if err != nil {
+		return err
+	}
+	cm, err := g.DesiredConfigmapWithoutOwner()
+	if err != nil {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, "FailedToRender", err.Error())
+		setCondition(status, v1alpha1.JupyterKernelSpecConfigMapCurrent,
+			v1.ConditionFalse, reasonFailedToRender, err.Error())
+		return nil
+	}
+
+	valid := true
+	for ns := range namespaces {
+		ok, err := checkResourceFiles(r.cli, ns, &r.instance.Spec, status)
+		if err != nil {
+			r.log.Error(err, "Failed to get the configmap of the resource files",
+				"namespace", ns)
+			return err
+		}
+		valid = valid && ok
+
+		g, err := newClusterGenerator(r.instance, ns)
+		if err != nil {
+			return err
+		}
+		desired, err := g.DesiredConfigmapWithoutOwner()
+		if err != nil {
+			return err
+		}
+		if err := reconcileConfigmap(
+			r.cli, r.log, r.scheme, r.instance, desired); err != nil {
+			return err
+		}
+	}
+
+	status.ConfigMapHash = cm.Annotations[AnnotationContentHash]
+	if !valid {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, reasonInvalidResourceFiles,
+			getCondition(status, v1alpha1.JupyterKernelSpecConfigMapCurrent).Message)
+		return nil
+	}
+	setCondition(status, v1alpha1.JupyterKernelSpecConfigMapCurrent,
+		v1.ConditionTrue, reasonRendered, "")
+	return nil
+}
+
+// reconcileTemplateStatus checks the kernel template used by the cluster
+// kernel spec.
+func (r ClusterReconciler) reconcileTemplateStatus() error {
+	status := &r.instance.Status
+	g, err := newClusterGenerator(r.instance, "")
+	if err != nil {
+		return err
+	}
+	status.Template = g.templateRef()
+	if hasInlineTemplate(&r.instance.Spec) {
+		if _, err := desiredTemplateSpec(&r.instance.Spec); err != nil {
+			setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+				v1.ConditionFalse, reasonInvalidTemplate, err.Error())
+			return nil
+		}
+	}
+	var owner metav1.Object
+	if hasInlineTemplate(&r.instance.Spec) {
+		owner = r.instance
+	}
+	if err := checkTemplate(r.cli, status, owner); err != nil {
+		r.log.Error(err, "Failed to get the kernel template")
+		return err
+	}
+	return nil
+}
+
+func (r ClusterReconciler) updateStatus(original *v1alpha1.JupyterKernelSpecStatus) error {
+	setReady(&r.instance.Status)
+	r.instance.Status.ObservedGeneration = r.instance.Generation
+	if equality.Semantic.DeepEqual(original, &r.instance.Status) {
+		return nil
+	}
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the cluster kernel spec")
+		return err
+	}
+	return nil
+}
+
+// namespaces returns the namespaces of the gateways which reference
+// the cluster kernel spec, and records the gateways in the status.
+func (r ClusterReconciler) namespaces() (map[string]bool, error) {
+	gateways := &v1alpha1.JupyterGatewayList{}
+	if err := r.cli.List(context.TODO(), gateways); err != nil {
+		r.log.Error(err, "Failed to list the gateways")
+		return nil, err
+	}
+
+	namespaces := make(map[string]bool)
+	names := []string{}
+	for i := range gateways.Items {
+		gw := &gateways.Items[i]
+		for _, k := range gw.Spec.ClusterKernels {
+			if k == r.instance.Name {
+				namespaces[gw.Namespace] = true
+				names = append(names, gatewayName(gw))
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	r.instance.Status.Gateways = names
+	return namespaces, nil
+}
+
+// cleanupConfigmaps deletes the configmaps in the namespaces which
+// do not have a gateway referencing the cluster kernel spec anymore.
+func (r ClusterReconciler) cleanupConfigmaps(namespaces map[string]bool) error {
+	cms := &v1.ConfigMapList{}
+	if err := r.cli.List(context.TODO(), cms, client.MatchingLabels{
+		LabelClusterKernelSpec: r.instance.Name,
+	}); err != nil {
+		r.log.Error(err, "Failed to list the configmaps")
+		return err
+	}
+
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if namespaces[cm.Namespace] {
+			continue
+		}
+		r.log.Info("Deleting confimap", "namespace", cm.Namespace, "name", cm.Name)
+		if err := r.cli.Delete(context.TODO(), cm); client.IgnoreNotFound(err) != nil {
+			r.log.Error(err, "Failed to delete the confimap",
+				"confimap", cm.Name)
+			return err
+		}
+	}
+	return nil
+}
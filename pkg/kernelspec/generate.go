@@ -42,12 +42,22 @@ import (
 )
 
 const (
-	LabelKernelSpec = "kernelspec"
-	LabelNS         = "namespace"
+	LabelKernelSpec        = "kernelspec"
+	LabelClusterKernelSpec = "clusterkernelspec"
+	LabelNS                = "namespace"
+
+	// AnnotationContentHash is the annotation of the rendered configmap
+	// which records the hash of its content.
+	AnnotationContentHash = "kubeflow.tkestack.io/content-hash"
 
 	fileName         = "kernel.json"
+	fileLogo32x32    = "logo-32x32.png"
+	fileLogo64x64    = "logo-64x64.png"
+	fileLogoSVG      = "logo-svg.svg"
 	defaultClassName = "enterprise_gateway.services.processproxies.k8s.KubernetesProcessProxy"
 
+	clusterConfigMapPrefix = "cluster-"
+
 	keyKernelTemplateName      = "--kernel-template-name"
 	keyKernelTemplateNamespace = "--kernel-template-namespace"
 )
@@ -55,7 +65,19 @@ const (
 // generator defines the generator which is used to generate
 // desired specs.
 type generator struct {
-	kernelSpec *v1alpha1.JupyterKernelSpec
+	name      string
+	namespace string
+	labels    map[string]string
+	spec      *v1alpha1.JupyterKernelSpecSpec
+
+	// templateNamespace is the namespace used when the template
+	// reference does not have one. It is empty for cluster-scoped
+	// kernel specs, which makes the launcher look for a
+	// ClusterJupyterKernelTemplate.
+	templateNamespace string
+	// inlineTemplate is the reference to the kernel template which is
+	// generated from the inline pod template.
+	inlineTemplate *v1.ObjectReference
 }
 
 // newGenerator creates a new Generator.
@@ -65,64 +87,275 @@ func newGenerator(kernelSpec *v1alpha1.JupyterKernelSpec) (
 		return nil, fmt.Errorf("Got nil when initializing Generator")
 	}
 	g := &generator{
-		kernelSpec: kernelSpec,
+		name:      kernelSpec.Name,
+		namespace: kernelSpec.Namespace,
+		labels: map[string]string{
+			LabelNS:         kernelSpec.Namespace,
+			LabelKernelSpec: kernelSpec.Name,
+		},
+		spec:              &kernelSpec.Spec,
+		templateNamespace: kernelSpec.Namespace,
+		inlineTemplate: &v1.ObjectReference{
+			Name:      kernelSpec.Name,
+			Namespace: kernelSpec.Namespace,
+		},
 	}
 
 	return g, nil
 }
 
-func (g generator) DesiredConfigmapWithoutOwner() (*v1.ConfigMap, error) {
-	labels := g.labels()
+// newClusterGenerator creates a new Generator for the cluster-scoped
+// kernel spec, which renders the configmap in the given namespace.
+func newClusterGenerator(kernelSpec *v1alpha1.ClusterJupyterKernelSpec,
+	namespace string) (*generator, error) {
+	if kernelSpec == nil {
+		return nil, fmt.Errorf("Got nil when initializing Generator")
+	}
+	g := &generator{
+		name:      ClusterConfigMapName(kernelSpec.Name),
+		namespace: namespace,
+		labels: map[string]string{
+			LabelNS:                namespace,
+			LabelClusterKernelSpec: kernelSpec.Name,
+		},
+		spec: &kernelSpec.Spec,
+		inlineTemplate: &v1.ObjectReference{
+			Kind: v1alpha1.ClusterJupyterKernelTemplateKind,
+			Name: kernelSpec.Name,
+		},
+	}
+
+	return g, nil
+}
+
+// ClusterConfigMapName returns the name of the configmap rendered
+// from the ClusterJupyterKernelSpec in the gateway namespace.
+func ClusterConfigMapName(name string) string {
+	return clusterConfigMapPrefix + name
+}
 
+func (g generator) DesiredConfigmapWithoutOwner() (*v1.ConfigMap, error) {
 	jsonConfig, err := g.desiredJSON()
 	if err != nil {
 		return nil, err
 	}
 
+	binaryData, err := g.binaryData()
+	if err != nil {
+		return nil, err
+	}
+
 	cm := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: g.kernelSpec.Namespace,
-			Name:      g.kernelSpec.Name,
-			Labels:    labels,
+			Namespace: g.namespace,
+			Name:      g.name,
+			Labels:    g.labels,
 		},
 		Data: map[string]string{
 			fileName: jsonConfig,
 		},
+		BinaryData: binaryData,
+	}
+	cm.Annotations = map[string]string{
+		AnnotationContentHash: contentHash(cm),
 	}
 
 	return cm, nil
 }
 
-func (g generator) desiredJSON() (string, error) {
-	c := &kernelConfig{
-		Language:    g.kernelSpec.Spec.Language,
-		DisplayName: g.kernelSpec.Spec.DisplayName,
-		Metadata: metadata{
-			ProcessProxy: processProxy{
-				ClassName: defaultClassName,
-				Config: config{
-					ImageName: g.kernelSpec.Spec.Image,
+// binaryData returns the inline resource files, which are placed next to
+// the kernel.json.
+func (g generator) binaryData() (map[string][]byte, error) {
+	files := g.spec.ResourceFiles
+	if files == nil {
+		return nil, nil
+	}
+
+	data := map[string][]byte{}
+	for name, content := range files.BinaryData {
+		if name == fileName {
+			return nil, fmt.Errorf("%s cannot be used as a resource file", fileName)
+		}
+		data[name] = content
+	}
+	for name, content := range map[string][]byte{
+		fileLogo32x32: files.Logo32x32,
+		fileLogo64x64: files.Logo64x64,
+		fileLogoSVG:   files.LogoSVG,
+	} {
+		if len(content) != 0 {
+			data[name] = content
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// DesiredVolume returns the volume of the kernel directory in the gateway,
+// which contains the rendered configmap and the configmap of the resource
+// files if it is referenced.
+func DesiredVolume(name, configMap string,
+	spec *v1alpha1.JupyterKernelSpecSpec) v1.Volume {
+	if spec.ResourceFiles == nil || spec.ResourceFiles.ConfigMap == nil {
+		return v1.Volume{
+			Name: name,
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: configMap},
+				},
+			},
+		}
+	}
+
+	return v1.Volume{
+		Name: name,
+		VolumeSource: v1.VolumeSource{
+			Projected: &v1.ProjectedVolumeSource{
+				Sources: []v1.VolumeProjection{
+					{
+						ConfigMap: &v1.ConfigMapProjection{
+							LocalObjectReference: v1.LocalObjectReference{Name: configMap},
+						},
+					},
+					{
+						ConfigMap: &v1.ConfigMapProjection{
+							LocalObjectReference: *spec.ResourceFiles.ConfigMap,
+						},
+					},
 				},
 			},
 		},
-		Argv: g.kernelSpec.Spec.Command,
 	}
+}
 
-	// Set the class name to desired.
-	if g.kernelSpec.Spec.ClassName != "" {
-		c.Metadata.ProcessProxy.ClassName = g.kernelSpec.Spec.ClassName
+func (g generator) desiredJSON() (string, error) {
+	c, err := g.desiredKernelConfig()
+	if err != nil {
+		return "", err
 	}
-	// Set the namespace and name for the jupyter kernel spec.
-	c.Argv = append(c.Argv,
-		keyKernelTemplateName, g.kernelSpec.Spec.Template.Name,
-		keyKernelTemplateNamespace, g.kernelSpec.Spec.Template.Namespace)
 	v, err := json.Marshal(c)
 	return string(v), err
 }
 
-func (g generator) labels() map[string]string {
-	return map[string]string{
-		LabelNS:         g.kernelSpec.Namespace,
-		LabelKernelSpec: g.kernelSpec.Name,
+func (g generator) desiredKernelConfig() (*kernelConfig, error) {
+	env, err := g.env()
+	if err != nil {
+		return nil, err
+	}
+	c := &kernelConfig{
+		Language:      g.spec.Language,
+		DisplayName:   g.spec.DisplayName,
+		InterruptMode: string(g.spec.InterruptMode),
+		Env:           env,
+		Argv:          append([]string{}, g.spec.Command...),
+	}
+
+	if g.spec.Metadata != nil && len(g.spec.Metadata.Raw) != 0 {
+		if err := json.Unmarshal(g.spec.Metadata.Raw, &c.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse the metadata: %v", err)
+		}
+	}
+	if g.spec.Debugger != nil {
+		c.Metadata.Debugger = g.spec.Debugger
+	}
+
+	pp, err := g.processProxy()
+	if err != nil {
+		return nil, err
+	}
+	c.Metadata.ProcessProxy = pp
+
+	// Set the namespace and name for the jupyter kernel template.
+	c.Argv = append(c.Argv, g.templateArgs()...)
+	return c, nil
+}
+
+// processProxy returns the process proxy stanza, the config is merged
+// from the free-form config, the image and the custom resource.
+func (g generator) processProxy() (*processProxy, error) {
+	pp := &processProxy{
+		ClassName: className(g.spec),
+		Config:    map[string]interface{}{},
+	}
+
+	if g.spec.ProcessProxyConfig != nil && len(g.spec.ProcessProxyConfig.Raw) != 0 {
+		if err := json.Unmarshal(g.spec.ProcessProxyConfig.Raw, &pp.Config); err != nil {
+			return nil, fmt.Errorf("failed to parse the process proxy config: %v", err)
+		}
+	}
+	if g.spec.Image != "" {
+		pp.Config[keyImageName] = g.spec.Image
+	}
+
+	cr, err := customResource(g.spec)
+	if err != nil {
+		return nil, err
+	}
+	if cr != nil {
+		pp.Config[keyGroup] = cr.Group
+		pp.Config[keyVersion] = cr.Version
+		pp.Config[keyPlural] = cr.Plural
+	}
+	return pp, nil
+}
+
+// env returns the environment variables in the kernel.json. The
+// variables with valueFrom cannot be rendered and are ignored.
+func (g generator) env() (map[string]string, error) {
+	if len(g.spec.Env) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(g.spec.Env))
+	for _, e := range g.spec.Env {
+		// kernel.json only has the values, thus the kernel spec is not
+		// rendered instead of dropping the env silently.
+		if e.ValueFrom != nil {
+			return nil, fmt.Errorf("the env %s with valueFrom is not supported in kernel.json", e.Name)
+		}
+		env[e.Name] = e.Value
+	}
+	return env, nil
+}
+
+// templateRef returns the reference to the kernel template used by the
+// kernel spec, with the kind and namespace resolved.
+func (g generator) templateRef() *v1.ObjectReference {
+	t := g.spec.Template
+	if hasInlineTemplate(g.spec) {
+		t = g.inlineTemplate
+	}
+	if t == nil {
+		return nil
+	}
+	ref := &v1.ObjectReference{
+		Kind: v1alpha1.ClusterJupyterKernelTemplateKind,
+		Name: t.Name,
+	}
+	if t.Kind == v1alpha1.ClusterJupyterKernelTemplateKind {
+		return ref
+	}
+	ref.Namespace = t.Namespace
+	if ref.Namespace == "" {
+		ref.Namespace = g.templateNamespace
+	}
+	if ref.Namespace != "" {
+		ref.Kind = v1alpha1.JupyterKernelTemplateKind
+	}
+	return ref
+}
+
+// templateArgs returns the launcher arguments which reference the kernel
+// template. The namespace is omitted for cluster-scoped templates.
+func (g generator) templateArgs() []string {
+	ref := g.templateRef()
+	if ref == nil {
+		return nil
+	}
+	args := []string{keyKernelTemplateName, ref.Name}
+	if ref.Namespace != "" {
+		args = append(args, keyKernelTemplateNamespace, ref.Namespace)
 	}
+	return args
 }
@@ -0,0 +1,425 @@
+package kernelspec
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	KernelSpecName      = "python-kubernetes"
+	KernelSpecNamespace = "default"
+	TemplateName        = "jupyterkerneltemplate-sample"
+	TemplateNamespace   = "kernels"
+	GatewayNamespace    = "gateway"
+)
+
+func TestTemplateArgs(t *testing.T) {
+	type test struct {
+		name     string
+		gen      func() (*generator, error)
+		expected []string
+	}
+
+	kernelSpec := func(ref *v1.ObjectReference) *v1alpha1.JupyterKernelSpec {
+		return &v1alpha1.JupyterKernelSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      KernelSpecName,
+				Namespace: KernelSpecNamespace,
+			},
+			Spec: v1alpha1.JupyterKernelSpecSpec{
+				Template: ref,
+			},
+		}
+	}
+	clusterKernelSpec := func(ref *v1.ObjectReference) *v1alpha1.ClusterJupyterKernelSpec {
+		return &v1alpha1.ClusterJupyterKernelSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: KernelSpecName,
+			},
+			Spec: v1alpha1.JupyterKernelSpecSpec{
+				Template: ref,
+			},
+		}
+	}
+
+	tests := []test{
+		{
+			name: "no template",
+			gen: func() (*generator, error) {
+				return newGenerator(kernelSpec(nil))
+			},
+			expected: nil,
+		},
+		{
+			name: "template with namespace",
+			gen: func() (*generator, error) {
+				return newGenerator(kernelSpec(&v1.ObjectReference{
+					Name: TemplateName, Namespace: TemplateNamespace,
+				}))
+			},
+			expected: []string{
+				keyKernelTemplateName, TemplateName,
+				keyKernelTemplateNamespace, TemplateNamespace,
+			},
+		},
+		{
+			name: "template defaults to the kernel spec namespace",
+			gen: func() (*generator, error) {
+				return newGenerator(kernelSpec(&v1.ObjectReference{
+					Name: TemplateName,
+				}))
+			},
+			expected: []string{
+				keyKernelTemplateName, TemplateName,
+				keyKernelTemplateNamespace, KernelSpecNamespace,
+			},
+		},
+		{
+			name: "cluster template",
+			gen: func() (*generator, error) {
+				return newGenerator(kernelSpec(&v1.ObjectReference{
+					Kind: v1alpha1.ClusterJupyterKernelTemplateKind,
+					Name: TemplateName,
+				}))
+			},
+			expected: []string{keyKernelTemplateName, TemplateName},
+		},
+		{
+			name: "cluster kernel spec without namespace",
+			gen: func() (*generator, error) {
+				return newClusterGenerator(clusterKernelSpec(&v1.ObjectReference{
+					Name: TemplateName,
+				}), GatewayNamespace)
+			},
+			expected: []string{keyKernelTemplateName, TemplateName},
+		},
+	}
+
+	for _, tc := range tests {
+		g, err := tc.gen()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		actual := g.templateArgs()
+		if !reflect.DeepEqual(tc.expected, actual) {
+			t.Errorf("%s: expected: %v, got: %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestClusterConfigmap(t *testing.T) {
+	ks := &v1alpha1.ClusterJupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: KernelSpecName,
+		},
+	}
+	g, err := newClusterGenerator(ks, GatewayNamespace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cm, err := g.DesiredConfigmapWithoutOwner()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cm.Namespace != GatewayNamespace {
+		t.Errorf("expected namespace %s, got %s", GatewayNamespace, cm.Namespace)
+	}
+	if cm.Name != ClusterConfigMapName(KernelSpecName) {
+		t.Errorf("expected name %s, got %s", ClusterConfigMapName(KernelSpecName), cm.Name)
+	}
+	if cm.Labels[LabelClusterKernelSpec] != KernelSpecName {
+		t.Errorf("expected label %s, got %v", KernelSpecName, cm.Labels)
+	}
+}
+
+// kernelJSONFiles returns the kernel.json files in testdata. They are
+// written in the layout of the Enterprise Gateway kernel specs, which
+// covers the fields rendered by the generator, but they are not copies of
+// the files in Enterprise Gateway.
+func kernelJSONFiles(t *testing.T) []string {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list the test data: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no test data found")
+	}
+	return files
+}
+
+// normalize unmarshals the JSON into the generic form, and drops the empty
+// env since it is the same as the missing one.
+func normalize(t *testing.T, data []byte) map[string]interface{} {
+	v := map[string]interface{}{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if env, ok := v["env"].(map[string]interface{}); ok && len(env) == 0 {
+		delete(v, "env")
+	}
+	return v
+}
+
+func TestKernelConfigRoundTrip(t *testing.T) {
+	for _, f := range kernelJSONFiles(t) {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatalf("%s: failed to read: %v", f, err)
+		}
+		c := &kernelConfig{}
+		if err := json.Unmarshal(data, c); err != nil {
+			t.Fatalf("%s: failed to unmarshal: %v", f, err)
+		}
+		actual, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal: %v", f, err)
+		}
+		if !reflect.DeepEqual(normalize(t, data), normalize(t, actual)) {
+			t.Errorf("%s: expected: %s, got: %s", f, data, actual)
+		}
+	}
+}
+
+// specFromKernelConfig converts the kernel.json to the JupyterKernelSpecSpec.
+func specFromKernelConfig(t *testing.T, c *kernelConfig) v1alpha1.JupyterKernelSpecSpec {
+	spec := v1alpha1.JupyterKernelSpecSpec{
+		Language:      c.Language,
+		DisplayName:   c.DisplayName,
+		InterruptMode: v1alpha1.InterruptMode(c.InterruptMode),
+		Command:       c.Argv,
+		Debugger:      c.Metadata.Debugger,
+	}
+
+	keys := []string{}
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		spec.Env = append(spec.Env, v1.EnvVar{Name: k, Value: c.Env[k]})
+	}
+
+	if c.Metadata.Extra != nil {
+		raw, err := json.Marshal(c.Metadata.Extra)
+		if err != nil {
+			t.Fatalf("failed to marshal the metadata: %v", err)
+		}
+		spec.Metadata = &runtime.RawExtension{Raw: raw}
+	}
+	if pp := c.Metadata.ProcessProxy; pp != nil {
+		spec.ClassName = pp.ClassName
+		config := map[string]interface{}{}
+		for k, v := range pp.Config {
+			if k == keyImageName {
+				spec.Image = v.(string)
+				continue
+			}
+			config[k] = v
+		}
+		raw, err := json.Marshal(config)
+		if err != nil {
+			t.Fatalf("failed to marshal the config: %v", err)
+		}
+		spec.ProcessProxyConfig = &runtime.RawExtension{Raw: raw}
+	}
+	return spec
+}
+
+func TestDesiredJSONFromKernelJSON(t *testing.T) {
+	for _, f := range kernelJSONFiles(t) {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatalf("%s: failed to read: %v", f, err)
+		}
+		c := &kernelConfig{}
+		if err := json.Unmarshal(data, c); err != nil {
+			t.Fatalf("%s: failed to unmarshal: %v", f, err)
+		}
+
+		g, err := newGenerator(&v1alpha1.JupyterKernelSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      KernelSpecName,
+				Namespace: KernelSpecNamespace,
+			},
+			Spec: specFromKernelConfig(t, c),
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", f, err)
+		}
+		actual, err := g.desiredJSON()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", f, err)
+		}
+		if !reflect.DeepEqual(normalize(t, data), normalize(t, []byte(actual))) {
+			t.Errorf("%s: expected: %s, got: %s", f, data, actual)
+		}
+	}
+}
+
+func TestDesiredJSONPrecedence(t *testing.T) {
+	debugger := false
+	g, err := newGenerator(&v1alpha1.JupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KernelSpecName,
+			Namespace: KernelSpecNamespace,
+		},
+		Spec: v1alpha1.JupyterKernelSpecSpec{
+			Image:    "image",
+			Debugger: &debugger,
+			Env: []v1.EnvVar{
+				{Name: "A", Value: "a"},
+			},
+			ProcessProxyConfig: &runtime.RawExtension{
+				Raw: []byte(`{"image_name":"overridden","port_range":"1..2"}`),
+			},
+			Metadata: &runtime.RawExtension{
+				Raw: []byte(`{"debugger":true,"process_proxy":{"class_name":"overridden"},"custom":1}`),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := g.desiredKernelConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.Env, map[string]string{"A": "a"}) {
+		t.Errorf("expected env A, got %v", c.Env)
+	}
+	if c.Metadata.Debugger == nil || *c.Metadata.Debugger {
+		t.Errorf("expected debugger to be false, got %v", c.Metadata.Debugger)
+	}
+	if c.Metadata.ProcessProxy.ClassName != defaultClassName {
+		t.Errorf("expected class name %s, got %s",
+			defaultClassName, c.Metadata.ProcessProxy.ClassName)
+	}
+	expectedConfig := map[string]interface{}{
+		keyImageName: "image",
+		"port_range": "1..2",
+	}
+	if !reflect.DeepEqual(c.Metadata.ProcessProxy.Config, expectedConfig) {
+		t.Errorf("expected config %v, got %v",
+			expectedConfig, c.Metadata.ProcessProxy.Config)
+	}
+	if string(c.Metadata.Extra["custom"]) != "1" {
+		t.Errorf("expected the custom metadata to be kept, got %v", c.Metadata.Extra)
+	}
+}
+
+func TestDesiredJSONEnvValueFrom(t *testing.T) {
+	g, err := newGenerator(&v1alpha1.JupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KernelSpecName,
+			Namespace: KernelSpecNamespace,
+		},
+		Spec: v1alpha1.JupyterKernelSpecSpec{
+			Image: "image",
+			Env: []v1.EnvVar{
+				{Name: "A", Value: "a"},
+				{Name: "B", ValueFrom: &v1.EnvVarSource{
+					FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := g.DesiredConfigmapWithoutOwner(); err == nil {
+		t.Errorf("expected the env with valueFrom to be rejected")
+	}
+}
+
+func TestResourceFiles(t *testing.T) {
+	type test struct {
+		name         string
+		files        *v1alpha1.KernelResourceFiles
+		expectedErr  bool
+		expectedData map[string][]byte
+	}
+
+	logo := []byte("logo")
+	tests := []test{
+		{name: "no files", files: nil, expectedData: nil},
+		{
+			name: "inline files",
+			files: &v1alpha1.KernelResourceFiles{
+				Logo64x64:  logo,
+				LogoSVG:    logo,
+				BinaryData: map[string][]byte{"kernel.js": logo},
+			},
+			expectedData: map[string][]byte{
+				fileLogo64x64: logo,
+				fileLogoSVG:   logo,
+				"kernel.js":   logo,
+			},
+		},
+		{
+			name: "kernel.json is reserved",
+			files: &v1alpha1.KernelResourceFiles{
+				BinaryData: map[string][]byte{fileName: logo},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		g, err := newGenerator(&v1alpha1.JupyterKernelSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      KernelSpecName,
+				Namespace: KernelSpecNamespace,
+			},
+			Spec: v1alpha1.JupyterKernelSpecSpec{
+				ResourceFiles: tc.files,
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		cm, err := g.DesiredConfigmapWithoutOwner()
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(cm.BinaryData, tc.expectedData) {
+			t.Errorf("%s: expected: %v, got: %v", tc.name, tc.expectedData, cm.BinaryData)
+		}
+	}
+}
+
+func TestDesiredVolume(t *testing.T) {
+	spec := &v1alpha1.JupyterKernelSpecSpec{}
+	v := DesiredVolume(KernelSpecName, KernelSpecName, spec)
+	if v.ConfigMap == nil || v.ConfigMap.Name != KernelSpecName {
+		t.Errorf("expected configmap volume, got %v", v)
+	}
+
+	spec.ResourceFiles = &v1alpha1.KernelResourceFiles{
+		ConfigMap: &v1.LocalObjectReference{Name: "logos"},
+	}
+	v = DesiredVolume(KernelSpecName, KernelSpecName, spec)
+	if v.Projected == nil || len(v.Projected.Sources) != 2 {
+		t.Fatalf("expected projected volume, got %v", v)
+	}
+	if v.Projected.Sources[0].ConfigMap.Name != KernelSpecName ||
+		v.Projected.Sources[1].ConfigMap.Name != "logos" {
+		t.Errorf("unexpected projected sources: %v", v.Projected.Sources)
+	}
+}
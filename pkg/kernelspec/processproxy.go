@@ -0,0 +1,97 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kernelspec
+
+import (
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	classSparkOperator  = "enterprise_gateway.services.processproxies.spark_operator.SparkOperatorProcessProxy"
+	classCustomResource = "enterprise_gateway.services.processproxies.crd.CustomResourceProcessProxy"
+
+	keyGroup   = "group"
+	keyVersion = "version"
+	keyPlural  = "plural"
+)
+
+// sparkApplication is the custom resource created by the
+// SparkOperatorProcessProxy.
+var sparkApplication = v1alpha1.KernelCustomResource{
+	Group:   "sparkoperator.k8s.io",
+	Version: "v1beta2",
+	Plural:  "sparkapplications",
+}
+
+// className returns the class of the process proxy.
+func className(spec *v1alpha1.JupyterKernelSpecSpec) string {
+	if spec.ClassName != "" {
+		return spec.ClassName
+	}
+	switch spec.ProcessProxy {
+	case v1alpha1.ProcessProxySparkOperator:
+		return classSparkOperator
+	case v1alpha1.ProcessProxyCustomResource:
+		return classCustomResource
+	default:
+		return defaultClassName
+	}
+}
+
+// customResource returns the custom resource created by the process
+// proxy, or nil if the process proxy launches pods.
+func customResource(spec *v1alpha1.JupyterKernelSpecSpec) (
+	*v1alpha1.KernelCustomResource, error) {
+	switch spec.ProcessProxy {
+	case v1alpha1.ProcessProxySparkOperator:
+		if spec.CustomResource != nil {
+			return spec.CustomResource, nil
+		}
+		return &sparkApplication, nil
+	case v1alpha1.ProcessProxyCustomResource:
+		cr := spec.CustomResource
+		if cr == nil || cr.Group == "" || cr.Version == "" || cr.Plural == "" {
+			return nil, fmt.Errorf(
+				"customResource with group, version and plural is required by the CustomResource process proxy")
+		}
+		return cr, nil
+	default:
+		return nil, nil
+	}
+}
+
+// PolicyRules returns the rules which the gateway needs to launch the
+// kernels with the process proxy of the kernel spec. The permissions
+// on pods are granted by the gateway cluster role, thus only the
+// custom resources are returned.
+func PolicyRules(spec *v1alpha1.JupyterKernelSpecSpec) []rbacv1.PolicyRule {
+	cr, err := customResource(spec)
+	if err != nil || cr == nil {
+		return nil
+	}
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{cr.Group},
+			Resources: []string{cr.Plural},
+			Verbs:     []string{"get", "watch", "list", "create", "delete"},
+		},
+	}
+}
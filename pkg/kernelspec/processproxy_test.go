@@ -0,0 +1,114 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kernelspec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestProcessProxy(t *testing.T) {
+	crontab := &v1alpha1.KernelCustomResource{
+		Group:   "stable.example.com",
+		Version: "v1",
+		Plural:  "crontabs",
+	}
+
+	type test struct {
+		name        string
+		spec        v1alpha1.JupyterKernelSpecSpec
+		expected    string
+		expectedCR  *v1alpha1.KernelCustomResource
+		expectedErr bool
+	}
+	tests := []test{
+		{
+			name:     "default",
+			expected: defaultClassName,
+		},
+		{
+			name: "kubernetes",
+			spec: v1alpha1.JupyterKernelSpecSpec{
+				ProcessProxy: v1alpha1.ProcessProxyKubernetes,
+			},
+			expected: defaultClassName,
+		},
+		{
+			name: "spark operator",
+			spec: v1alpha1.JupyterKernelSpecSpec{
+				ProcessProxy: v1alpha1.ProcessProxySparkOperator,
+			},
+			expected:   classSparkOperator,
+			expectedCR: &sparkApplication,
+		},
+		{
+			name: "custom resource with class name",
+			spec: v1alpha1.JupyterKernelSpecSpec{
+				ProcessProxy:   v1alpha1.ProcessProxyCustomResource,
+				ClassName:      "crontab.CrontabProcessProxy",
+				CustomResource: crontab,
+			},
+			expected:   "crontab.CrontabProcessProxy",
+			expectedCR: crontab,
+		},
+		{
+			name: "custom resource without resource",
+			spec: v1alpha1.JupyterKernelSpecSpec{
+				ProcessProxy: v1alpha1.ProcessProxyCustomResource,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		g := generator{spec: &tc.spec}
+		pp, err := g.processProxy()
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if pp.ClassName != tc.expected {
+			t.Errorf("%s: expected class %s, got %s", tc.name, tc.expected, pp.ClassName)
+		}
+
+		rules := PolicyRules(&tc.spec)
+		if tc.expectedCR == nil {
+			if len(rules) != 0 || pp.Config[keyGroup] != nil {
+				t.Errorf("%s: expected no custom resource, got %v", tc.name, rules)
+			}
+			continue
+		}
+		if pp.Config[keyGroup] != tc.expectedCR.Group ||
+			pp.Config[keyVersion] != tc.expectedCR.Version ||
+			pp.Config[keyPlural] != tc.expectedCR.Plural {
+			t.Errorf("%s: expected custom resource %v in config, got %v",
+				tc.name, tc.expectedCR, pp.Config)
+		}
+		if len(rules) != 1 ||
+			!reflect.DeepEqual(rules[0].APIGroups, []string{tc.expectedCR.Group}) ||
+			!reflect.DeepEqual(rules[0].Resources, []string{tc.expectedCR.Plural}) {
+			t.Errorf("%s: unexpected rules %v", tc.name, rules)
+		}
+	}
+}
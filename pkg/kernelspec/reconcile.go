@@ -18,10 +18,13 @@ package kernelspec
 
 import (
 	"context"
+	"sort"
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -59,39 +62,156 @@ func NewReconciler(cli client.Client, l logr.Logger,
 }
 
 func (r Reconciler) Reconcile() error {
+	original := r.instance.Status.DeepCopy()
+
+	if err := r.reconcileTemplate(); err != nil {
+		return err
+	}
 	if err := r.reconcileConfigmap(); err != nil {
 		return err
 	}
+	if err := r.reconcileTemplateStatus(); err != nil {
+		return err
+	}
+	if err := r.reconcileGateways(); err != nil {
+		return err
+	}
 
-	return nil
+	return r.updateStatus(original)
 }
 
 func (r Reconciler) reconcileConfigmap() error {
 	desired, err := r.gen.DesiredConfigmapWithoutOwner()
 	if err != nil {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, "FailedToRender", err.Error())
+		setCondition(&r.instance.Status, v1alpha1.JupyterKernelSpecConfigMapCurrent,
+			v1.ConditionFalse, reasonFailedToRender, err.Error())
+		return nil
+	}
+
+	if err := reconcileConfigmap(r.cli, r.log, r.scheme, r.instance, desired); err != nil {
+		return err
+	}
+	r.instance.Status.ConfigMapHash = desired.Annotations[AnnotationContentHash]
+	ok, err := checkResourceFiles(r.cli, r.instance.Namespace,
+		&r.instance.Spec, &r.instance.Status)
+	if err != nil {
+		r.log.Error(err, "Failed to get the configmap of the resource files")
+		return err
+	}
+	if !ok {
+		r.recorder.Event(r.instance, v1.EventTypeWarning, reasonInvalidResourceFiles,
+			getCondition(&r.instance.Status, v1alpha1.JupyterKernelSpecConfigMapCurrent).Message)
+		return nil
+	}
+	setCondition(&r.instance.Status, v1alpha1.JupyterKernelSpecConfigMapCurrent,
+		v1.ConditionTrue, reasonRendered, "")
+	return nil
+}
+
+// reconcileTemplateStatus checks the kernel template used by the kernel spec.
+func (r Reconciler) reconcileTemplateStatus() error {
+	status := &r.instance.Status
+	status.Template = r.gen.templateRef()
+	if hasInlineTemplate(&r.instance.Spec) {
+		if _, err := desiredTemplateSpec(&r.instance.Spec); err != nil {
+			setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+				v1.ConditionFalse, reasonInvalidTemplate, err.Error())
+			return nil
+		}
+	}
+	var owner metav1.Object
+	if hasInlineTemplate(&r.instance.Spec) {
+		owner = r.instance
+	}
+	if err := checkTemplate(r.cli, status, owner); err != nil {
+		r.log.Error(err, "Failed to get the kernel template")
 		return err
 	}
+	return nil
+}
+
+// reconcileGateways records the gateways which include the kernel spec.
+func (r Reconciler) reconcileGateways() error {
+	gateways := &v1alpha1.JupyterGatewayList{}
+	if err := r.cli.List(context.TODO(), gateways,
+		client.InNamespace(r.instance.Namespace)); err != nil {
+		r.log.Error(err, "Failed to list the gateways")
+		return err
+	}
+
+	names := []string{}
+	for i := range gateways.Items {
+		gw := &gateways.Items[i]
+		for _, k := range gw.Spec.Kernels {
+			if k == r.instance.Name {
+				names = append(names, gatewayName(gw))
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	r.instance.Status.Gateways = names
+	return nil
+}
+
+func (r Reconciler) updateStatus(original *v1alpha1.JupyterKernelSpecStatus) error {
+	setReady(&r.instance.Status)
+	r.instance.Status.ObservedGeneration = r.instance.Generation
+	if equality.Semantic.DeepEqual(original, &r.instance.Status) {
+		return nil
+	}
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the kernel spec")
+		return err
+	}
+	return nil
+}
 
-	if err = controllerutil.SetControllerReference(
-		r.instance, desired, r.scheme); err != nil {
-		r.log.Error(err,
+// reconcileConfigmap creates the desired configmap owned by the
+// given kernel spec if it does not exist, or updates it if the
+// content is out of date.
+func reconcileConfigmap(cli client.Client, log logr.Logger,
+	scheme *runtime.Scheme, owner metav1.Object, desired *v1.ConfigMap) error {
+	if err := controllerutil.SetControllerReference(
+		owner, desired, scheme); err != nil {
+		log.Error(err,
 			"Set controller reference error, requeuing the request")
 		return err
 	}
 
 	actual := &v1.ConfigMap{}
-	err = r.cli.Get(context.TODO(),
+	err := cli.Get(context.TODO(),
 		types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, actual)
 	if err != nil && errors.IsNotFound(err) {
-		r.log.Info("Creating confimap", "namespace", desired.Namespace, "name", desired.Name)
+		log.Info("Creating confimap", "namespace", desired.Namespace, "name", desired.Name)
 
-		if err := r.cli.Create(context.TODO(), desired); err != nil {
-			r.log.Error(err, "Failed to create the confimap",
+		if err := cli.Create(context.TODO(), desired); err != nil {
+			log.Error(err, "Failed to create the confimap",
 				"confimap", desired.Name)
 			return err
 		}
+		return nil
 	} else if err != nil {
-		r.log.Error(err, "failed to get the expected confimap",
+		log.Error(err, "failed to get the expected confimap",
+			"confimap", desired.Name)
+		return err
+	}
+
+	hash := desired.Annotations[AnnotationContentHash]
+	if actual.Annotations[AnnotationContentHash] == hash &&
+		contentHash(actual) == hash {
+		return nil
+	}
+	log.Info("Updating confimap", "namespace", desired.Namespace, "name", desired.Name)
+	actual.Data = desired.Data
+	actual.BinaryData = desired.BinaryData
+	if actual.Annotations == nil {
+		actual.Annotations = make(map[string]string)
+	}
+	actual.Annotations[AnnotationContentHash] = hash
+	if err := cli.Update(context.TODO(), actual); err != nil {
+		log.Error(err, "Failed to update the confimap",
 			"confimap", desired.Name)
 		return err
 	}
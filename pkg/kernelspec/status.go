@@ -0,0 +1,256 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kernelspec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	reasonRendered         = "Rendered"
+	reasonFailedToRender   = "FailedToRender"
+	reasonNoTemplate       = "NoTemplate"
+	reasonTemplateFound    = "TemplateFound"
+	reasonTemplateNotFound = "TemplateNotFound"
+	reasonInvalidTemplate  = "InvalidTemplate"
+	reasonTemplateNotOwned = "TemplateNotOwned"
+	reasonReady            = "Ready"
+
+	reasonInvalidResourceFiles = "InvalidResourceFiles"
+)
+
+// contentHash returns the hash of the data in the configmap.
+func contentHash(cm *v1.ConfigMap) string {
+	h := sha256.New()
+	keys := make([]string, 0, len(cm.Data))
+	for k := range cm.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00", k, cm.Data[k])
+	}
+
+	keys = keys[:0]
+	for k := range cm.BinaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00", k)
+		h.Write(cm.BinaryData[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// setCondition sets the condition in the status. The transition time is
+// only updated when the condition status changes.
+func setCondition(status *v1alpha1.JupyterKernelSpecStatus,
+	t v1alpha1.JupyterKernelSpecConditionType, s v1.ConditionStatus,
+	reason, message string) {
+	for i := range status.Conditions {
+		c := &status.Conditions[i]
+		if c.Type != t {
+			continue
+		}
+		if c.Status != s {
+			c.LastTransitionTime = metav1.Now()
+		}
+		c.Status = s
+		c.Reason = reason
+		c.Message = message
+		return
+	}
+	status.Conditions = append(status.Conditions, v1alpha1.JupyterKernelSpecCondition{
+		Type:               t,
+		Status:             s,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// getCondition returns the condition with the given type, or nil if
+// it is not found.
+func getCondition(status *v1alpha1.JupyterKernelSpecStatus,
+	t v1alpha1.JupyterKernelSpecConditionType) *v1alpha1.JupyterKernelSpecCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == t {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setReady sets the Ready condition, which is true only if the configmap
+// is current and the template is valid.
+func setReady(status *v1alpha1.JupyterKernelSpecStatus) {
+	for _, t := range []v1alpha1.JupyterKernelSpecConditionType{
+		v1alpha1.JupyterKernelSpecConfigMapCurrent,
+		v1alpha1.JupyterKernelSpecTemplateValid,
+	} {
+		c := getCondition(status, t)
+		if c == nil {
+			setCondition(status, v1alpha1.JupyterKernelSpecReady,
+				v1.ConditionUnknown, "", "")
+			return
+		}
+		if c.Status != v1.ConditionTrue {
+			setCondition(status, v1alpha1.JupyterKernelSpecReady,
+				v1.ConditionFalse, c.Reason, c.Message)
+			return
+		}
+	}
+	setCondition(status, v1alpha1.JupyterKernelSpecReady,
+		v1.ConditionTrue, reasonReady, "")
+}
+
+// checkTemplate checks whether the kernel template in the status exists
+// and is valid, and sets the TemplateValid condition. The template must be
+// controlled by the owner if it is not nil, i.e. the template is generated
+// from the inline template of the kernel spec.
+func checkTemplate(cli client.Client, status *v1alpha1.JupyterKernelSpecStatus,
+	owner metav1.Object) error {
+	ref := status.Template
+	if ref == nil {
+		setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+			v1.ConditionTrue, reasonNoTemplate, "The kernel spec does not use a kernel template")
+		return nil
+	}
+
+	var spec v1alpha1.JupyterKernelTemplateSpec
+	var meta metav1.Object
+	key := types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}
+	var err error
+	if ref.Kind == v1alpha1.ClusterJupyterKernelTemplateKind {
+		t := &v1alpha1.ClusterJupyterKernelTemplate{}
+		err = cli.Get(context.TODO(), key, t)
+		spec, meta = t.Spec, t
+	} else {
+		t := &v1alpha1.JupyterKernelTemplate{}
+		err = cli.Get(context.TODO(), key, t)
+		spec, meta = t.Spec, t
+	}
+	if err != nil && errors.IsNotFound(err) {
+		setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+			v1.ConditionFalse, reasonTemplateNotFound,
+			fmt.Sprintf("%s %s is not found", ref.Kind, key))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if owner != nil && !metav1.IsControlledBy(meta, owner) {
+		setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+			v1.ConditionFalse, reasonTemplateNotOwned,
+			fmt.Sprintf("%s %s exists and is not owned by the kernel spec", ref.Kind, key))
+		return nil
+	}
+	if spec.Template == nil || len(spec.Template.Spec.Containers) == 0 {
+		setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+			v1.ConditionFalse, reasonInvalidTemplate,
+			fmt.Sprintf("No container found in %s %s", ref.Kind, key))
+		return nil
+	}
+	setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid,
+		v1.ConditionTrue, reasonTemplateFound, "")
+	return nil
+}
+
+// ConflictingResourceFiles returns the keys of the referenced configmap of
+// the resource files in the namespace, which are also rendered by the
+// operator, i.e. kernel.json and the inline resource files. They cannot be
+// projected into the same kernel directory. A missing configmap has no
+// conflicts.
+func ConflictingResourceFiles(cli client.Reader, namespace string,
+	spec *v1alpha1.JupyterKernelSpecSpec) ([]string, error) {
+	files := spec.ResourceFiles
+	if files == nil || files.ConfigMap == nil {
+		return nil, nil
+	}
+	cm := &v1.ConfigMap{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: namespace,
+		Name:      files.ConfigMap.Name,
+	}, cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	rendered := map[string]bool{fileName: true}
+	for name := range files.BinaryData {
+		rendered[name] = true
+	}
+	for name, content := range map[string][]byte{
+		fileLogo32x32: files.Logo32x32,
+		fileLogo64x64: files.Logo64x64,
+		fileLogoSVG:   files.LogoSVG,
+	} {
+		if len(content) != 0 {
+			rendered[name] = true
+		}
+	}
+	conflicts := []string{}
+	for name := range cm.Data {
+		if rendered[name] {
+			conflicts = append(conflicts, name)
+		}
+	}
+	for name := range cm.BinaryData {
+		if rendered[name] {
+			conflicts = append(conflicts, name)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// checkResourceFiles sets the ConfigMapCurrent condition to false if the
+// referenced configmap of the resource files conflicts with the rendered
+// files. It returns true if there are no conflicts.
+func checkResourceFiles(cli client.Reader, namespace string,
+	spec *v1alpha1.JupyterKernelSpecSpec, status *v1alpha1.JupyterKernelSpecStatus) (bool, error) {
+	conflicts, err := ConflictingResourceFiles(cli, namespace, spec)
+	if err != nil {
+		return false, err
+	}
+	if len(conflicts) == 0 {
+		return true, nil
+	}
+	setCondition(status, v1alpha1.JupyterKernelSpecConfigMapCurrent,
+		v1.ConditionFalse, reasonInvalidResourceFiles,
+		fmt.Sprintf("The configmap %s/%s of the resource files contains the rendered files %v",
+			namespace, spec.ResourceFiles.ConfigMap.Name, conflicts))
+	return false, nil
+}
+
+// gatewayName returns the name of the gateway in the status.
+func gatewayName(gw *v1alpha1.JupyterGateway) string {
+	return gw.Namespace + "/" + gw.Name
+}
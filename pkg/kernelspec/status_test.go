@@ -0,0 +1,251 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kernelspec
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestContentHash(t *testing.T) {
+	a := &v1.ConfigMap{
+		Data:       map[string]string{"kernel.json": "{}", "a": "b"},
+		BinaryData: map[string][]byte{"logo-32x32.png": []byte("png")},
+	}
+	b := a.DeepCopy()
+	if contentHash(a) != contentHash(b) {
+		t.Errorf("Expected the same hash for the same content")
+	}
+	b.BinaryData["logo-32x32.png"] = []byte("other")
+	if contentHash(a) == contentHash(b) {
+		t.Errorf("Expected different hashes for different content")
+	}
+}
+
+func TestSetReady(t *testing.T) {
+	type test struct {
+		name      string
+		configMap v1.ConditionStatus
+		template  v1.ConditionStatus
+		expected  v1.ConditionStatus
+	}
+	tests := []test{
+		{"ready", v1.ConditionTrue, v1.ConditionTrue, v1.ConditionTrue},
+		{"failed to render", v1.ConditionFalse, v1.ConditionTrue, v1.ConditionFalse},
+		{"template not found", v1.ConditionTrue, v1.ConditionFalse, v1.ConditionFalse},
+	}
+	for _, tc := range tests {
+		status := &v1alpha1.JupyterKernelSpecStatus{}
+		setCondition(status, v1alpha1.JupyterKernelSpecConfigMapCurrent, tc.configMap, "", "")
+		setCondition(status, v1alpha1.JupyterKernelSpecTemplateValid, tc.template, "", "")
+		setReady(status)
+		c := getCondition(status, v1alpha1.JupyterKernelSpecReady)
+		if c == nil || c.Status != tc.expected {
+			t.Errorf("%s: expected Ready %s, got %v", tc.name, tc.expected, c)
+		}
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	withContainer := &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "kernel"}}},
+	}
+	cli := fake.NewFakeClientWithScheme(newScheme(t),
+		&v1alpha1.JupyterKernelTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "valid"},
+			Spec:       v1alpha1.JupyterKernelTemplateSpec{Template: withContainer},
+		},
+		&v1alpha1.JupyterKernelTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "empty"},
+		},
+		&v1alpha1.ClusterJupyterKernelTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec:       v1alpha1.JupyterKernelTemplateSpec{Template: withContainer},
+		},
+	)
+
+	type test struct {
+		name     string
+		ref      *v1.ObjectReference
+		status   v1.ConditionStatus
+		expected string
+	}
+	tests := []test{
+		{
+			name:     "no template",
+			status:   v1.ConditionTrue,
+			expected: reasonNoTemplate,
+		},
+		{
+			name: "valid template",
+			ref: &v1.ObjectReference{
+				Kind: v1alpha1.JupyterKernelTemplateKind, Namespace: "default", Name: "valid",
+			},
+			status:   v1.ConditionTrue,
+			expected: reasonTemplateFound,
+		},
+		{
+			name: "template without containers",
+			ref: &v1.ObjectReference{
+				Kind: v1alpha1.JupyterKernelTemplateKind, Namespace: "default", Name: "empty",
+			},
+			status:   v1.ConditionFalse,
+			expected: reasonInvalidTemplate,
+		},
+		{
+			name: "missing template",
+			ref: &v1.ObjectReference{
+				Kind: v1alpha1.JupyterKernelTemplateKind, Namespace: "default", Name: "missing",
+			},
+			status:   v1.ConditionFalse,
+			expected: reasonTemplateNotFound,
+		},
+		{
+			name: "cluster template",
+			ref: &v1.ObjectReference{
+				Kind: v1alpha1.ClusterJupyterKernelTemplateKind, Name: "cluster",
+			},
+			status:   v1.ConditionTrue,
+			expected: reasonTemplateFound,
+		},
+	}
+	for _, tc := range tests {
+		status := &v1alpha1.JupyterKernelSpecStatus{Template: tc.ref}
+		if err := checkTemplate(cli, status, nil); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		c := getCondition(status, v1alpha1.JupyterKernelSpecTemplateValid)
+		if c == nil || c.Status != tc.status || c.Reason != tc.expected {
+			t.Errorf("%s: expected %s/%s, got %v", tc.name, tc.status, tc.expected, c)
+		}
+	}
+}
+
+func TestReconcileConfigmapUpdate(t *testing.T) {
+	scheme := newScheme(t)
+	ks := &v1alpha1.JupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "python"},
+		Spec: v1alpha1.JupyterKernelSpecSpec{
+			Language: "Python",
+			Image:    "elyra/kernel-py:2.2.0",
+		},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, ks)
+	log := ctrl.Log.WithName("test")
+
+	render := func() *v1.ConfigMap {
+		g, err := newGenerator(ks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cm, err := g.DesiredConfigmapWithoutOwner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cm
+	}
+
+	if err := reconcileConfigmap(cli, log, scheme, ks, render()); err != nil {
+		t.Fatal(err)
+	}
+	ks.Spec.Image = "elyra/kernel-py:2.3.0"
+	desired := render()
+	if err := reconcileConfigmap(cli, log, scheme, ks, desired); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := &v1.ConfigMap{}
+	if err := cli.Get(context.TODO(),
+		types.NamespacedName{Namespace: "default", Name: "python"}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Data[fileName] != desired.Data[fileName] {
+		t.Errorf("Expected the configmap to be updated, got %s", actual.Data[fileName])
+	}
+	if actual.Annotations[AnnotationContentHash] != contentHash(desired) {
+		t.Errorf("Expected the hash annotation to be updated")
+	}
+}
+
+func TestCheckResourceFiles(t *testing.T) {
+	cli := fake.NewFakeClientWithScheme(newScheme(t),
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "files"},
+			Data:       map[string]string{fileName: "{}", "kernel.js": ""},
+			BinaryData: map[string][]byte{fileLogo64x64: []byte("png")},
+		},
+	)
+	type test struct {
+		name     string
+		files    *v1alpha1.KernelResourceFiles
+		expected bool
+	}
+	ref := &v1.LocalObjectReference{Name: "files"}
+	tests := []test{
+		{"no configmap", &v1alpha1.KernelResourceFiles{}, true},
+		{"missing configmap", &v1alpha1.KernelResourceFiles{
+			ConfigMap: &v1.LocalObjectReference{Name: "missing"},
+		}, true},
+		{"kernel.json", &v1alpha1.KernelResourceFiles{ConfigMap: ref}, false},
+	}
+	for _, tc := range tests {
+		status := &v1alpha1.JupyterKernelSpecStatus{}
+		ok, err := checkResourceFiles(cli, "default",
+			&v1alpha1.JupyterKernelSpecSpec{ResourceFiles: tc.files}, status)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if ok != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, ok)
+		}
+		c := getCondition(status, v1alpha1.JupyterKernelSpecConfigMapCurrent)
+		if !ok && (c == nil || c.Reason != reasonInvalidResourceFiles) {
+			t.Errorf("%s: expected the condition %s, got %v", tc.name, reasonInvalidResourceFiles, c)
+		}
+	}
+
+	conflicts, err := ConflictingResourceFiles(cli, "default", &v1alpha1.JupyterKernelSpecSpec{
+		ResourceFiles: &v1alpha1.KernelResourceFiles{ConfigMap: ref, Logo64x64: []byte("png")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 2 || conflicts[0] != fileName || conflicts[1] != fileLogo64x64 {
+		t.Errorf("Expected the conflicts %s and %s, got %v", fileName, fileLogo64x64, conflicts)
+	}
+}
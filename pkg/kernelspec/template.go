@@ -0,0 +1,220 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kernelspec
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	defaultKernelContainerName = "kernel"
+)
+
+// hasInlineTemplate returns true if the kernel template is defined
+// inline by the pod template or the shortcut fields.
+func hasInlineTemplate(spec *v1alpha1.JupyterKernelSpecSpec) bool {
+	return spec.PodTemplate != nil || spec.Resources != nil ||
+		len(spec.NodeSelector) != 0 || len(spec.Tolerations) != 0 ||
+		len(spec.Volumes) != 0 || len(spec.VolumeMounts) != 0 ||
+		spec.ServiceAccountName != ""
+}
+
+// desiredTemplateSpec returns the kernel template spec generated from the
+// inline pod template and the shortcut fields.
+func desiredTemplateSpec(spec *v1alpha1.JupyterKernelSpecSpec) (
+	*v1alpha1.JupyterKernelTemplateSpec, error) {
+	if spec.Template != nil {
+		return nil, fmt.Errorf("template and podTemplate cannot be used together")
+	}
+
+	pod := &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyAlways,
+			Containers: []v1.Container{
+				{
+					Name: defaultKernelContainerName,
+				},
+			},
+		},
+	}
+	if spec.PodTemplate != nil {
+		pod = spec.PodTemplate.DeepCopy()
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("no container found in the pod template")
+	}
+
+	container := &pod.Spec.Containers[0]
+	if spec.Resources != nil {
+		container.Resources = *spec.Resources.DeepCopy()
+	}
+	for _, m := range spec.VolumeMounts {
+		container.VolumeMounts = append(container.VolumeMounts, *m.DeepCopy())
+	}
+	if len(spec.NodeSelector) != 0 && pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = make(map[string]string)
+	}
+	for k, v := range spec.NodeSelector {
+		pod.Spec.NodeSelector[k] = v
+	}
+	for _, t := range spec.Tolerations {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, *t.DeepCopy())
+	}
+	for _, v := range spec.Volumes {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, *v.DeepCopy())
+	}
+	if spec.ServiceAccountName != "" {
+		pod.Spec.ServiceAccountName = spec.ServiceAccountName
+	}
+
+	return &v1alpha1.JupyterKernelTemplateSpec{
+		Template: pod,
+	}, nil
+}
+
+// reconcileTemplate creates or updates the JupyterKernelTemplate generated
+// from the inline pod template of the kernel spec.
+func (r Reconciler) reconcileTemplate() error {
+	if !hasInlineTemplate(&r.instance.Spec) {
+		return nil
+	}
+	spec, err := desiredTemplateSpec(&r.instance.Spec)
+	if err != nil {
+		// The TemplateValid condition reports the error.
+		r.recorder.Event(r.instance, v1.EventTypeWarning, "FailedToGenerate", err.Error())
+		return nil
+	}
+
+	desired := &v1alpha1.JupyterKernelTemplate{}
+	desired.Namespace = r.instance.Namespace
+	desired.Name = r.instance.Name
+	desired.Labels = r.gen.labels
+	desired.Spec = *spec
+	if err := controllerutil.SetControllerReference(
+		r.instance, desired, r.scheme); err != nil {
+		r.log.Error(err,
+			"Set controller reference error, requeuing the request")
+		return err
+	}
+
+	actual := &v1alpha1.JupyterKernelTemplate{}
+	err = r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, actual)
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating kernel template", "namespace", desired.Namespace, "name", desired.Name)
+
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the kernel template",
+				"kerneltemplate", desired.Name)
+			return err
+		}
+		return nil
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected kernel template",
+			"kerneltemplate", desired.Name)
+		return err
+	}
+
+	// The template with the same name is not overwritten if it is
+	// created by others. The TemplateValid condition reports it.
+	if !metav1.IsControlledBy(actual, r.instance) {
+		r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonTemplateNotOwned,
+			"The kernel template %s exists and is not owned by the kernel spec", desired.Name)
+		return nil
+	}
+	if !equality.Semantic.DeepEqual(desired.Spec, actual.Spec) {
+		actual.Spec = desired.Spec
+		if err := r.cli.Update(context.TODO(), actual); err != nil {
+			r.log.Error(err, "Failed to update the kernel template",
+				"kerneltemplate", desired.Name)
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileTemplate creates or updates the ClusterJupyterKernelTemplate
+// generated from the inline pod template of the cluster kernel spec.
+func (r ClusterReconciler) reconcileTemplate() error {
+	if !hasInlineTemplate(&r.instance.Spec) {
+		return nil
+	}
+	spec, err := desiredTemplateSpec(&r.instance.Spec)
+	if err != nil {
+		// The TemplateValid condition reports the error.
+		r.recorder.Event(r.instance, v1.EventTypeWarning, "FailedToGenerate", err.Error())
+		return nil
+	}
+
+	desired := &v1alpha1.ClusterJupyterKernelTemplate{}
+	desired.Name = r.instance.Name
+	desired.Labels = map[string]string{
+		LabelClusterKernelSpec: r.instance.Name,
+	}
+	desired.Spec = *spec
+	if err := controllerutil.SetControllerReference(
+		r.instance, desired, r.scheme); err != nil {
+		r.log.Error(err,
+			"Set controller reference error, requeuing the request")
+		return err
+	}
+
+	actual := &v1alpha1.ClusterJupyterKernelTemplate{}
+	err = r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: desired.GetName()}, actual)
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating cluster kernel template", "name", desired.Name)
+
+		if err := r.cli.Create(context.TODO(), desired); err != nil {
+			r.log.Error(err, "Failed to create the cluster kernel template",
+				"clusterkerneltemplate", desired.Name)
+			return err
+		}
+		return nil
+	} else if err != nil {
+		r.log.Error(err, "failed to get the expected cluster kernel template",
+			"clusterkerneltemplate", desired.Name)
+		return err
+	}
+
+	// The template with the same name is not overwritten if it is
+	// created by others. The TemplateValid condition reports it.
+	if !metav1.IsControlledBy(actual, r.instance) {
+		r.recorder.Eventf(r.instance, v1.EventTypeWarning, reasonTemplateNotOwned,
+			"The cluster kernel template %s exists and is not owned by the kernel spec", desired.Name)
+		return nil
+	}
+	if !equality.Semantic.DeepEqual(desired.Spec, actual.Spec) {
+		actual.Spec = desired.Spec
+		if err := r.cli.Update(context.TODO(), actual); err != nil {
+			r.log.Error(err, "Failed to update the cluster kernel template",
+				"clusterkerneltemplate", desired.Name)
+			return err
+		}
+	}
+	return nil
+}
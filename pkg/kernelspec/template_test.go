@@ -0,0 +1,178 @@
+package kernelspec
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestDesiredTemplateSpec(t *testing.T) {
+	resources := &v1.ResourceRequirements{
+		Limits: v1.ResourceList{
+			v1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+	}
+	spec := &v1alpha1.JupyterKernelSpecSpec{
+		Resources:          resources,
+		NodeSelector:       map[string]string{"disk": "ssd"},
+		Tolerations:        []v1.Toleration{{Key: "kernel", Operator: v1.TolerationOpExists}},
+		Volumes:            []v1.Volume{{Name: "data"}},
+		VolumeMounts:       []v1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		ServiceAccountName: "kernel",
+	}
+	if !hasInlineTemplate(spec) {
+		t.Fatalf("expected inline template")
+	}
+
+	actual, err := desiredTemplateSpec(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := actual.Template
+	if len(pod.Spec.Containers) != 1 || pod.Spec.Containers[0].Name != defaultKernelContainerName {
+		t.Fatalf("expected the default container, got %v", pod.Spec.Containers)
+	}
+	if !reflect.DeepEqual(pod.Spec.Containers[0].Resources, *resources) {
+		t.Errorf("expected resources %v, got %v", resources, pod.Spec.Containers[0].Resources)
+	}
+	if !reflect.DeepEqual(pod.Spec.Containers[0].VolumeMounts, spec.VolumeMounts) {
+		t.Errorf("expected volume mounts %v, got %v", spec.VolumeMounts, pod.Spec.Containers[0].VolumeMounts)
+	}
+	if !reflect.DeepEqual(pod.Spec.NodeSelector, spec.NodeSelector) {
+		t.Errorf("expected node selector %v, got %v", spec.NodeSelector, pod.Spec.NodeSelector)
+	}
+	if !reflect.DeepEqual(pod.Spec.Tolerations, spec.Tolerations) {
+		t.Errorf("expected tolerations %v, got %v", spec.Tolerations, pod.Spec.Tolerations)
+	}
+	if !reflect.DeepEqual(pod.Spec.Volumes, spec.Volumes) {
+		t.Errorf("expected volumes %v, got %v", spec.Volumes, pod.Spec.Volumes)
+	}
+	if pod.Spec.ServiceAccountName != spec.ServiceAccountName {
+		t.Errorf("expected service account %s, got %s", spec.ServiceAccountName, pod.Spec.ServiceAccountName)
+	}
+
+	spec.Template = &v1.ObjectReference{Name: TemplateName}
+	if _, err := desiredTemplateSpec(spec); err == nil {
+		t.Errorf("expected error when both template and inline template are used")
+	}
+}
+
+func TestDesiredTemplateSpecFromPodTemplate(t *testing.T) {
+	spec := &v1alpha1.JupyterKernelSpecSpec{
+		PodTemplate: &v1.PodTemplateSpec{
+			Spec: v1.PodSpec{
+				NodeSelector: map[string]string{"zone": "a"},
+				Containers:   []v1.Container{{Name: "custom"}},
+			},
+		},
+		NodeSelector: map[string]string{"disk": "ssd"},
+	}
+	actual, err := desiredTemplateSpec(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual.Template.Spec.Containers[0].Name != "custom" {
+		t.Errorf("expected the container in the pod template, got %v", actual.Template.Spec.Containers)
+	}
+	expected := map[string]string{"zone": "a", "disk": "ssd"}
+	if !reflect.DeepEqual(actual.Template.Spec.NodeSelector, expected) {
+		t.Errorf("expected node selector %v, got %v", expected, actual.Template.Spec.NodeSelector)
+	}
+	if len(spec.PodTemplate.Spec.NodeSelector) != 1 {
+		t.Errorf("expected the pod template not to be modified")
+	}
+}
+
+func TestInlineTemplateArgs(t *testing.T) {
+	spec := v1alpha1.JupyterKernelSpecSpec{
+		ServiceAccountName: "kernel",
+	}
+	g, err := newGenerator(&v1alpha1.JupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KernelSpecName,
+			Namespace: KernelSpecNamespace,
+		},
+		Spec: spec,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		keyKernelTemplateName, KernelSpecName,
+		keyKernelTemplateNamespace, KernelSpecNamespace,
+	}
+	if actual := g.templateArgs(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+
+	g, err = newClusterGenerator(&v1alpha1.ClusterJupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: KernelSpecName,
+		},
+		Spec: spec,
+	}, GatewayNamespace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []string{keyKernelTemplateName, KernelSpecName}
+	if actual := g.templateArgs(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestReconcileTemplateNotOwned(t *testing.T) {
+	scheme := newScheme(t)
+	ks := &v1alpha1.JupyterKernelSpec{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "python", UID: "python-uid"},
+		Spec: v1alpha1.JupyterKernelSpecSpec{
+			Language:     "Python",
+			Image:        "elyra/kernel-py:2.2.0",
+			NodeSelector: map[string]string{"disk": "ssd"},
+		},
+	}
+	existing := &v1alpha1.JupyterKernelTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "python"},
+		Spec: v1alpha1.JupyterKernelTemplateSpec{
+			Template: &v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "other"}}},
+			},
+		},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, ks, existing)
+	r, err := NewReconciler(cli, ctrl.Log.WithName("test"),
+		record.NewFakeRecorder(10), scheme, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reconcileTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	actual := &v1alpha1.JupyterKernelTemplate{}
+	if err := cli.Get(context.TODO(),
+		types.NamespacedName{Namespace: "default", Name: "python"}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Spec.Template.Spec.Containers[0].Name != "other" {
+		t.Errorf("Expected the template not to be overwritten, got %v",
+			actual.Spec.Template.Spec.Containers)
+	}
+
+	if err := r.reconcileTemplateStatus(); err != nil {
+		t.Fatal(err)
+	}
+	c := getCondition(&ks.Status, v1alpha1.JupyterKernelSpecTemplateValid)
+	if c == nil || c.Status != v1.ConditionFalse || c.Reason != reasonTemplateNotOwned {
+		t.Errorf("Expected the condition %s, got %v", reasonTemplateNotOwned, c)
+	}
+}
@@ -1,21 +1,76 @@
 package kernelspec
 
+import (
+	"encoding/json"
+)
+
+const (
+	keyProcessProxy = "process_proxy"
+	keyDebugger     = "debugger"
+	keyImageName    = "image_name"
+)
+
+// kernelConfig is the kernel.json of the kernel spec.
+// Ref https://jupyter-client.readthedocs.io/en/stable/kernels.html#kernel-specs
 type kernelConfig struct {
-	Language    string   `json:"language,omitempty"`
-	DisplayName string   `json:"display_name,omitempty"`
-	Metadata    metadata `json:"metadata,omitempty"`
-	Argv        []string `json:"argv,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	DisplayName   string            `json:"display_name,omitempty"`
+	InterruptMode string            `json:"interrupt_mode,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	Metadata      metadata          `json:"metadata,omitempty"`
+	Argv          []string          `json:"argv,omitempty"`
 }
 
+// metadata is the metadata stanza of the kernel.json. The keys other
+// than process_proxy and debugger are kept in Extra.
 type metadata struct {
-	ProcessProxy processProxy `json:"process_proxy,omitempty"`
+	ProcessProxy *processProxy
+	Debugger     *bool
+	Extra        map[string]json.RawMessage
 }
 
 type processProxy struct {
 	ClassName string `json:"class_name,omitempty"`
-	Config    config `json:"config,omitempty"`
+	// Config is the process proxy config, e.g. image_name,
+	// executor_image_name and port_range.
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+func (m metadata) MarshalJSON() ([]byte, error) {
+	v := make(map[string]interface{}, len(m.Extra)+2)
+	for k, raw := range m.Extra {
+		v[k] = raw
+	}
+	if m.ProcessProxy != nil {
+		v[keyProcessProxy] = m.ProcessProxy
+	}
+	if m.Debugger != nil {
+		v[keyDebugger] = *m.Debugger
+	}
+	return json.Marshal(v)
 }
 
-type config struct {
-	ImageName string `json:"image_name,omitempty"`
+func (m *metadata) UnmarshalJSON(data []byte) error {
+	v := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if raw, ok := v[keyProcessProxy]; ok {
+		m.ProcessProxy = &processProxy{}
+		if err := json.Unmarshal(raw, m.ProcessProxy); err != nil {
+			return err
+		}
+		delete(v, keyProcessProxy)
+	}
+	if raw, ok := v[keyDebugger]; ok {
+		m.Debugger = new(bool)
+		if err := json.Unmarshal(raw, m.Debugger); err != nil {
+			return err
+		}
+		delete(v, keyDebugger)
+	}
+	if len(v) != 0 {
+		m.Extra = v
+	}
+	return nil
 }
@@ -0,0 +1,134 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package launcher contains the logic of the kernel launcher, which
+// creates the JupyterKernel from the kernel template.
+package launcher
+
+import (
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// EnvKernelGPUs is the number of the accelerators requested by the user.
+	EnvKernelGPUs = "KERNEL_GPUS"
+
+	defaultAcceleratorResource = v1.ResourceName("nvidia.com/gpu")
+	migResourcePrefix          = "nvidia.com/mig-"
+)
+
+// ApplyAccelerator requests the accelerators for the first container of
+// the pod. requested is the value of KERNEL_GPUS, which overrides the
+// count in the template if it is not empty.
+func ApplyAccelerator(pod *v1.PodTemplateSpec,
+	acc *v1alpha1.Accelerator, requested string) error {
+	if acc == nil {
+		if requested != "" && requested != "0" {
+			return fmt.Errorf("%s is set but the kernel template has no accelerator", EnvKernelGPUs)
+		}
+		return nil
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return fmt.Errorf("no container found in the kernel template")
+	}
+
+	count, err := acceleratorCount(acc, requested)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	c := &pod.Spec.Containers[0]
+	quantity := *resource.NewQuantity(int64(count), resource.DecimalSI)
+	name := acceleratorResource(acc)
+	if c.Resources.Limits == nil {
+		c.Resources.Limits = v1.ResourceList{}
+	}
+	c.Resources.Limits[name] = quantity
+	// The requests of extended resources must be equal to the limits.
+	if _, ok := c.Resources.Requests[name]; ok {
+		c.Resources.Requests[name] = quantity
+	}
+	setEnv(c, EnvKernelGPUs, strconv.Itoa(int(count)))
+
+	if len(acc.NodeSelector) != 0 && pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = make(map[string]string)
+	}
+	for k, v := range acc.NodeSelector {
+		pod.Spec.NodeSelector[k] = v
+	}
+	for _, t := range acc.Tolerations {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, *t.DeepCopy())
+	}
+	return nil
+}
+
+// acceleratorCount returns the number of the accelerators, which is
+// bounded by the max count in the template.
+func acceleratorCount(acc *v1alpha1.Accelerator, requested string) (int32, error) {
+	count := int32(1)
+	if acc.Count != nil {
+		count = *acc.Count
+	}
+	max := count
+	if acc.MaxCount != nil {
+		max = *acc.MaxCount
+	}
+	if requested == "" {
+		return count, nil
+	}
+
+	n, err := strconv.Atoi(requested)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", EnvKernelGPUs, requested)
+	}
+	if n > int(max) {
+		return 0, fmt.Errorf("%s %d exceeds the maximum %d in the kernel template",
+			EnvKernelGPUs, n, max)
+	}
+	return int32(n), nil
+}
+
+// acceleratorResource returns the extended resource of the accelerator.
+func acceleratorResource(acc *v1alpha1.Accelerator) v1.ResourceName {
+	if acc.MIGProfile != "" {
+		return v1.ResourceName(migResourcePrefix + acc.MIGProfile)
+	}
+	if acc.ResourceName != "" {
+		return acc.ResourceName
+	}
+	return defaultAcceleratorResource
+}
+
+// setEnv sets the env var of the container, which replaces the existing
+// one with the same name, e.g. the one in the template.
+func setEnv(c *v1.Container, name, value string) {
+	for i := range c.Env {
+		if c.Env[i].Name == name {
+			c.Env[i] = v1.EnvVar{Name: name, Value: value}
+			return
+		}
+	}
+	c.Env = append(c.Env, v1.EnvVar{Name: name, Value: value})
+}
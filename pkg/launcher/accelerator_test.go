@@ -0,0 +1,195 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// fakeGPU is a fake extended resource, thus no real GPU is required.
+const fakeGPU = v1.ResourceName("example.com/fake-gpu")
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func newPod() *v1.PodTemplateSpec {
+	return &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "kernel"}},
+		},
+	}
+}
+
+func TestApplyAccelerator(t *testing.T) {
+	type test struct {
+		name          string
+		acc           *v1alpha1.Accelerator
+		requested     string
+		expectedName  v1.ResourceName
+		expectedCount int64
+		expectedErr   bool
+	}
+	tests := []test{
+		{
+			name: "no accelerator",
+		},
+		{
+			name:        "requested without accelerator",
+			requested:   "1",
+			expectedErr: true,
+		},
+		{
+			name:          "default resource and count",
+			acc:           &v1alpha1.Accelerator{},
+			expectedName:  defaultAcceleratorResource,
+			expectedCount: 1,
+		},
+		{
+			name: "count in template",
+			acc: &v1alpha1.Accelerator{
+				ResourceName: fakeGPU,
+				Count:        int32Ptr(2),
+			},
+			expectedName:  fakeGPU,
+			expectedCount: 2,
+		},
+		{
+			name: "override within max count",
+			acc: &v1alpha1.Accelerator{
+				ResourceName: fakeGPU,
+				Count:        int32Ptr(1),
+				MaxCount:     int32Ptr(4),
+			},
+			requested:     "4",
+			expectedName:  fakeGPU,
+			expectedCount: 4,
+		},
+		{
+			name: "override exceeds max count",
+			acc: &v1alpha1.Accelerator{
+				ResourceName: fakeGPU,
+				Count:        int32Ptr(1),
+				MaxCount:     int32Ptr(2),
+			},
+			requested:   "3",
+			expectedErr: true,
+		},
+		{
+			name: "override exceeds count without max count",
+			acc: &v1alpha1.Accelerator{
+				ResourceName: fakeGPU,
+				Count:        int32Ptr(1),
+			},
+			requested:   "2",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid override",
+			acc:         &v1alpha1.Accelerator{},
+			requested:   "two",
+			expectedErr: true,
+		},
+		{
+			name: "no accelerator requested",
+			acc: &v1alpha1.Accelerator{
+				ResourceName: fakeGPU,
+			},
+			requested: "0",
+		},
+		{
+			name: "mig profile",
+			acc: &v1alpha1.Accelerator{
+				ResourceName: fakeGPU,
+				MIGProfile:   "1g.5gb",
+			},
+			expectedName:  "nvidia.com/mig-1g.5gb",
+			expectedCount: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		pod := newPod()
+		err := ApplyAccelerator(pod, tc.acc, tc.requested)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		limits := pod.Spec.Containers[0].Resources.Limits
+		if tc.expectedCount == 0 {
+			if len(limits) != 0 {
+				t.Errorf("%s: expected no limits, got %v", tc.name, limits)
+			}
+			continue
+		}
+		q, ok := limits[tc.expectedName]
+		if !ok || q.Value() != tc.expectedCount {
+			t.Errorf("%s: expected %d %s, got %v", tc.name,
+				tc.expectedCount, tc.expectedName, limits)
+		}
+	}
+}
+
+func TestApplyAcceleratorScheduling(t *testing.T) {
+	pod := newPod()
+	pod.Spec.NodeSelector = map[string]string{"zone": "a"}
+	pod.Spec.Containers[0].Resources.Requests = v1.ResourceList{
+		fakeGPU: resource.MustParse("1"),
+	}
+	acc := &v1alpha1.Accelerator{
+		ResourceName: fakeGPU,
+		MaxCount:     int32Ptr(2),
+		NodeSelector: map[string]string{"accelerator": "fake"},
+		Tolerations: []v1.Toleration{
+			{
+				Key:      string(fakeGPU),
+				Operator: v1.TolerationOpExists,
+				Effect:   v1.TaintEffectNoSchedule,
+			},
+		},
+	}
+
+	pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: EnvKernelGPUs, Value: "1"}}
+
+	if err := ApplyAccelerator(pod, acc, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if pod.Spec.NodeSelector["zone"] != "a" || pod.Spec.NodeSelector["accelerator"] != "fake" {
+		t.Errorf("Expected the node selectors to be merged, got %v", pod.Spec.NodeSelector)
+	}
+	if len(pod.Spec.Tolerations) != 1 {
+		t.Errorf("Expected the toleration, got %v", pod.Spec.Tolerations)
+	}
+	c := pod.Spec.Containers[0]
+	if q := c.Resources.Requests[fakeGPU]; q.Value() != 2 {
+		t.Errorf("Expected the requests to be equal to the limits, got %v", c.Resources.Requests)
+	}
+	if len(c.Env) != 1 || c.Env[0].Name != EnvKernelGPUs || c.Env[0].Value != "2" {
+		t.Errorf("Expected %s to be replaced in the env, got %v", EnvKernelGPUs, c.Env)
+	}
+}
@@ -0,0 +1,41 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// deniedPrefix precedes the reason in the error of the API server when the
+// admission webhook denies the request.
+const deniedPrefix = "denied the request: "
+
+// AdmissionDenied returns the reason if the kernel is rejected by the
+// admission webhook, e.g. because it exceeds the JupyterKernelQuota.
+func AdmissionDenied(err error) (string, bool) {
+	if !errors.IsForbidden(err) {
+		return "", false
+	}
+	msg := err.Error()
+	i := strings.Index(msg, deniedPrefix)
+	if i < 0 {
+		return "", false
+	}
+	return msg[i+len(deniedPrefix):], true
+}
@@ -0,0 +1,40 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAdmissionDenied(t *testing.T) {
+	gr := schema.GroupResource{Group: "kubeflow.tkestack.io", Resource: "jupyterkernels"}
+	denied := errors.NewForbidden(gr, "kernel", fmt.Errorf(
+		`admission webhook "vjupyterkernel.kubeflow.tkestack.io" denied the request: exceeded quota q for the user "alice": kernels: used 5, limited 5`))
+	reason, ok := AdmissionDenied(denied)
+	if !ok || reason != `exceeded quota q for the user "alice": kernels: used 5, limited 5` {
+		t.Errorf("Expected the reason of the quota, got %q", reason)
+	}
+
+	rbac := errors.NewForbidden(gr, "kernel", fmt.Errorf("no permission"))
+	if _, ok := AdmissionDenied(rbac); ok {
+		t.Errorf("Expected the RBAC error not to be a denial")
+	}
+}
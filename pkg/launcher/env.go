@@ -0,0 +1,65 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+var (
+	// reservedEnvPrefixes are the prefixes of the env vars set by the
+	// gateway, the kernel launcher and Kubernetes.
+	reservedEnvPrefixes = []string{"EG_", "KERNEL_", "KUBERNETES_"}
+	// reservedEnv are the env vars of the system set in the containers.
+	reservedEnv = map[string]bool{"PATH": true, "HOME": true, "HOSTNAME": true}
+)
+
+// ReservedEnv returns true if the env var cannot be set by the clients,
+// e.g. the ones set by the kernel launcher.
+func ReservedEnv(name string) bool {
+	if reservedEnv[name] {
+		return true
+	}
+	for _, prefix := range reservedEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedEnv returns the env of the launcher which is allowed by
+// kernelEnvAllowlist of the gateway, except the reserved ones. lookup
+// looks up the env of the launcher, which includes the env sent by the
+// clients and allowed by the gateway.
+func AllowedEnv(gateway *v1alpha1.JupyterGateway,
+	lookup func(string) (string, bool)) []v1.EnvVar {
+	env := []v1.EnvVar{}
+	for _, name := range gateway.Spec.KernelEnvAllowlist {
+		if ReservedEnv(name) {
+			continue
+		}
+		if value, ok := lookup(name); ok {
+			env = append(env, v1.EnvVar{Name: name, Value: value})
+		}
+	}
+	return env
+}
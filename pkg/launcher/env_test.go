@@ -0,0 +1,57 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestAllowedEnv(t *testing.T) {
+	gateway := &v1alpha1.JupyterGateway{
+		Spec: v1alpha1.JupyterGatewaySpec{
+			KernelEnvAllowlist: []string{"AWS_ACCESS_KEY_ID", "PIP_INDEX_URL",
+				"EG_RESPONSE_ADDRESS", "KERNEL_ID", "PATH", "NO_PROXY"},
+		},
+	}
+	environ := map[string]string{
+		"AWS_ACCESS_KEY_ID":   "key",
+		"PIP_INDEX_URL":       "https://pypi.example.com/simple?a=b",
+		"EG_RESPONSE_ADDRESS": "10.0.0.1:8877",
+		"KERNEL_ID":           "1",
+		"PATH":                "/usr/bin",
+		"HTTP_PROXY":          "http://proxy",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := environ[name]
+		return value, ok
+	}
+	expected := []v1.EnvVar{
+		{Name: "AWS_ACCESS_KEY_ID", Value: "key"},
+		{Name: "PIP_INDEX_URL", Value: "https://pypi.example.com/simple?a=b"},
+	}
+	if env := AllowedEnv(gateway, lookup); !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+	if env := AllowedEnv(&v1alpha1.JupyterGateway{}, lookup); len(env) != 0 {
+		t.Errorf("Expected no env without the allowlist, got %v", env)
+	}
+}
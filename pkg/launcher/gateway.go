@@ -0,0 +1,59 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// LabelGatewayName is the label of the kernel, which is set to the
+	// name of the gateway which launches the kernel.
+	LabelGatewayName = "gateway_name"
+	// LabelGatewayNamespace is the label of the kernel, which is set to
+	// the namespace of the gateway which launches the kernel.
+	LabelGatewayNamespace = "gateway_namespace"
+)
+
+// GatewayLabels returns the labels of the kernels launched by the gateway.
+func GatewayLabels(gateway *v1alpha1.JupyterGateway) map[string]string {
+	return map[string]string{
+		LabelGatewayName:      gateway.Name,
+		LabelGatewayNamespace: gateway.Namespace,
+	}
+}
+
+// SetGateway labels the kernel with the gateway. The gateway is set as the
+// controller of the kernel only if they are in the same namespace, since
+// owner references cannot cross the namespace boundary. The kernels in the
+// other namespaces are cleaned up by the finalizer of the gateway.
+func SetGateway(kernel *v1alpha1.JupyterKernel,
+	gateway *v1alpha1.JupyterGateway, scheme *runtime.Scheme) error {
+	if kernel.Labels == nil {
+		kernel.Labels = make(map[string]string)
+	}
+	for k, v := range GatewayLabels(gateway) {
+		kernel.Labels[k] = v
+	}
+	if kernel.Namespace != gateway.Namespace {
+		return nil
+	}
+	return controllerutil.SetControllerReference(gateway, kernel, scheme)
+}
@@ -0,0 +1,213 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	placeholderNotebook  = "{notebook}"
+	defaultLookupTimeout = 10 * time.Second
+)
+
+// Identity is the POSIX identity of the notebook.
+type Identity struct {
+	UID int64  `json:"uid"`
+	GID *int64 `json:"gid,omitempty"`
+}
+
+// IdentityResolver maps the notebook to the identity. It returns nil if
+// the notebook is not found.
+type IdentityResolver interface {
+	Resolve(ctx context.Context, nb *v1alpha1.JupyterNotebook) (*Identity, error)
+}
+
+// NewIdentityResolver creates the resolver from the identity spec in the
+// kernel template.
+func NewIdentityResolver(cli client.Reader,
+	spec *v1alpha1.KernelIdentity) (IdentityResolver, error) {
+	switch {
+	case spec.ConfigMap != nil:
+		return &configMapResolver{
+			cli:  cli,
+			name: spec.ConfigMap.Name,
+		}, nil
+	case spec.URL != "":
+		return &httpResolver{
+			url:    spec.URL,
+			client: &http.Client{Timeout: defaultLookupTimeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("either configMap or url should be set in the identity")
+	}
+}
+
+// configMapResolver looks up the identities in the configmap in the
+// namespace of the notebook.
+type configMapResolver struct {
+	cli  client.Reader
+	name string
+}
+
+func (r configMapResolver) Resolve(ctx context.Context, nb *v1alpha1.JupyterNotebook) (*Identity, error) {
+	cm := &v1.ConfigMap{}
+	if err := r.cli.Get(ctx, types.NamespacedName{
+		Namespace: nb.Namespace,
+		Name:      r.name,
+	}, cm); err != nil {
+		return nil, err
+	}
+	value, ok := cm.Data[nb.Name]
+	if !ok {
+		return nil, nil
+	}
+	return parseIdentity(value)
+}
+
+// httpResolver looks up the identities in the external service.
+type httpResolver struct {
+	url    string
+	client *http.Client
+}
+
+func (r httpResolver) Resolve(ctx context.Context, nb *v1alpha1.JupyterNotebook) (*Identity, error) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("namespace", nb.Namespace)
+	q.Set("notebook", nb.Name)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up the notebook %s/%s: %s", nb.Namespace, nb.Name, resp.Status)
+	}
+	id := &Identity{}
+	if err := json.NewDecoder(resp.Body).Decode(id); err != nil {
+		return nil, fmt.Errorf("failed to parse the identity of the notebook %s/%s: %v",
+			nb.Namespace, nb.Name, err)
+	}
+	return id, nil
+}
+
+// parseIdentity parses the identity in the form of <uid>[:<gid>].
+func parseIdentity(value string) (*Identity, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+	uid, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid in %q", value)
+	}
+	id := &Identity{UID: uid}
+	if len(parts) == 2 {
+		gid, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gid in %q", value)
+		}
+		id.GID = &gid
+	}
+	return id, nil
+}
+
+// ApplyIdentity runs the kernel as the identity of the notebook. nb is the
+// notebook verified by VerifyNotebook, since the user name of the kernel
+// (KERNEL_USERNAME) is sent by the client and cannot be trusted. The
+// security context and the service account of the kernel pod are set from
+// the identity spec.
+func ApplyIdentity(ctx context.Context, kernel *v1alpha1.JupyterKernel,
+	spec *v1alpha1.KernelIdentity, resolver IdentityResolver, nb *v1alpha1.JupyterNotebook) error {
+	if spec == nil {
+		return nil
+	}
+	if nb == nil {
+		if spec.Required {
+			return fmt.Errorf("the kernel is required to be launched by a notebook with %s and %s",
+				EnvKernelNotebook, EnvKernelNotebookToken)
+		}
+		return nil
+	}
+
+	pod := &kernel.Spec.Template
+	// The names of the notebooks are valid names of the service accounts.
+	if spec.ServiceAccountName != "" {
+		pod.Spec.ServiceAccountName = strings.ReplaceAll(
+			spec.ServiceAccountName, placeholderNotebook, nb.Name)
+	}
+
+	id, err := resolver.Resolve(ctx, nb)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if id == nil {
+		if spec.Required {
+			return fmt.Errorf("failed to find the identity of the notebook %s/%s", nb.Namespace, nb.Name)
+		}
+		return nil
+	}
+
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &v1.PodSecurityContext{}
+	}
+	pod.Spec.SecurityContext.RunAsUser = &id.UID
+	if id.GID != nil {
+		pod.Spec.SecurityContext.RunAsGroup = id.GID
+		pod.Spec.SecurityContext.FSGroup = id.GID
+	}
+	// The identity in the containers takes precedence over the pod, thus
+	// it is overridden too.
+	for i := range pod.Spec.Containers {
+		sc := pod.Spec.Containers[i].SecurityContext
+		if sc == nil {
+			continue
+		}
+		if sc.RunAsUser != nil {
+			sc.RunAsUser = &id.UID
+		}
+		if sc.RunAsGroup != nil && id.GID != nil {
+			sc.RunAsGroup = id.GID
+		}
+	}
+	return nil
+}
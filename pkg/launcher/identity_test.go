@@ -0,0 +1,230 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newNotebook(name string) *v1alpha1.JupyterNotebook {
+	return &v1alpha1.JupyterNotebook{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	type test struct {
+		value       string
+		uid         int64
+		gid         *int64
+		expectedErr bool
+	}
+	gid := int64(100)
+	tests := []test{
+		{value: "1000", uid: 1000},
+		{value: "1000:100", uid: 1000, gid: &gid},
+		{value: "alice", expectedErr: true},
+		{value: "1000:users", expectedErr: true},
+	}
+	for _, tc := range tests {
+		id, err := parseIdentity(tc.value)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.value, err)
+		}
+		if id.UID != tc.uid || (tc.gid == nil) != (id.GID == nil) ||
+			(tc.gid != nil && *tc.gid != *id.GID) {
+			t.Errorf("%s: unexpected identity %v", tc.value, id)
+		}
+	}
+}
+
+func TestConfigMapResolver(t *testing.T) {
+	cli := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "identities"},
+		Data: map[string]string{
+			"alice": "1000:100",
+		},
+	})
+	r, err := NewIdentityResolver(cli, &v1alpha1.KernelIdentity{
+		ConfigMap: &v1.LocalObjectReference{Name: "identities"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := r.Resolve(context.TODO(), newNotebook("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == nil || id.UID != 1000 || *id.GID != 100 {
+		t.Errorf("Expected 1000:100, got %v", id)
+	}
+	id, err = r.Resolve(context.TODO(), newNotebook("bob"))
+	if err != nil || id != nil {
+		t.Errorf("Expected no identity for bob, got %v, %v", id, err)
+	}
+}
+
+func TestHTTPResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("namespace") != "default" || q.Get("notebook") != "alice" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"uid": 1000, "gid": 100}`)
+	}))
+	defer server.Close()
+
+	r, err := NewIdentityResolver(nil, &v1alpha1.KernelIdentity{
+		URL: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := r.Resolve(context.TODO(), newNotebook("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == nil || id.UID != 1000 || *id.GID != 100 {
+		t.Errorf("Expected 1000:100, got %v", id)
+	}
+	id, err = r.Resolve(context.TODO(), newNotebook("bob"))
+	if err != nil || id != nil {
+		t.Errorf("Expected no identity for bob, got %v, %v", id, err)
+	}
+}
+
+type staticResolver map[string]*Identity
+
+func (r staticResolver) Resolve(ctx context.Context, nb *v1alpha1.JupyterNotebook) (*Identity, error) {
+	return r[nb.Name], nil
+}
+
+func TestApplyIdentity(t *testing.T) {
+	gid := int64(100)
+	resolver := staticResolver{
+		"alice": {UID: 1000, GID: &gid},
+	}
+	containerUID := int64(0)
+
+	type test struct {
+		name        string
+		spec        *v1alpha1.KernelIdentity
+		notebook    *v1alpha1.JupyterNotebook
+		expectedUID *int64
+		expectedSA  string
+		expectedErr bool
+	}
+	tests := []test{
+		{
+			name:     "no identity",
+			notebook: newNotebook("alice"),
+		},
+		{
+			name: "mapped notebook",
+			spec: &v1alpha1.KernelIdentity{
+				ServiceAccountName: "jupyter-{notebook}",
+			},
+			notebook:    newNotebook("alice"),
+			expectedUID: &resolver["alice"].UID,
+			expectedSA:  "jupyter-alice",
+		},
+		{
+			name:     "unmapped notebook",
+			spec:     &v1alpha1.KernelIdentity{},
+			notebook: newNotebook("bob"),
+		},
+		{
+			name:        "unmapped notebook is required",
+			spec:        &v1alpha1.KernelIdentity{Required: true},
+			notebook:    newNotebook("bob"),
+			expectedErr: true,
+		},
+		{
+			name:        "no notebook is required",
+			spec:        &v1alpha1.KernelIdentity{Required: true},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		kernel := &v1alpha1.JupyterKernel{
+			Spec: v1alpha1.JupyterKernelCRDSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{
+								Name: "kernel",
+								SecurityContext: &v1.SecurityContext{
+									RunAsUser: &containerUID,
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		err := ApplyIdentity(context.TODO(), kernel, tc.spec, resolver, tc.notebook)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		pod := kernel.Spec.Template
+		if tc.expectedUID == nil {
+			if pod.Spec.SecurityContext != nil {
+				t.Errorf("%s: expected no security context, got %v", tc.name, pod.Spec.SecurityContext)
+			}
+			continue
+		}
+		sc := pod.Spec.SecurityContext
+		if sc == nil || *sc.RunAsUser != *tc.expectedUID || *sc.FSGroup != gid {
+			t.Errorf("%s: unexpected security context %v", tc.name, sc)
+		}
+		if *pod.Spec.Containers[0].SecurityContext.RunAsUser != *tc.expectedUID {
+			t.Errorf("%s: expected the container to run as the notebook", tc.name)
+		}
+		if pod.Spec.ServiceAccountName != tc.expectedSA {
+			t.Errorf("%s: expected service account %s, got %s",
+				tc.name, tc.expectedSA, pod.Spec.ServiceAccountName)
+		}
+	}
+}
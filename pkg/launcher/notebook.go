@@ -0,0 +1,115 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// EnvKernelNotebook is the notebook which starts the kernel, in the
+	// form of <namespace>/<name>. It is set by the notebooks with the
+	// gateway.
+	EnvKernelNotebook = "KERNEL_NOTEBOOK"
+	// EnvKernelNotebookToken is the token of the notebook, which proves
+	// that the kernel is started by the notebook in KERNEL_NOTEBOOK. It is
+	// set by the notebooks with the gateway from the token Secret.
+	EnvKernelNotebookToken = "KERNEL_NOTEBOOK_TOKEN"
+
+	// NotebookTokenKey is the key of the token in the token Secret.
+	NotebookTokenKey = "token"
+
+	// LabelNotebook is the label of the kernel and the kernel pod, which
+	// is set to the name of the notebook verified by VerifyNotebook.
+	LabelNotebook = "kernel_notebook"
+)
+
+// NotebookTokenSecretName returns the name of the Secret created by the
+// operator for the notebook with the gateway, which keeps the token of
+// the notebook.
+func NotebookTokenSecretName(notebook string) string {
+	return notebook + "-kernel-token"
+}
+
+// VerifyNotebook returns the notebook which starts the kernel. notebook
+// and token are the values of KERNEL_NOTEBOOK and KERNEL_NOTEBOOK_TOKEN,
+// which are sent by the client, thus the token is checked against the
+// token Secret of the notebook. It returns nil if notebook is empty.
+func VerifyNotebook(ctx context.Context, cli client.Reader,
+	kernel *v1alpha1.JupyterKernel, notebook, token string) (*v1alpha1.JupyterNotebook, error) {
+	if notebook == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(notebook, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid %s %q, expected <namespace>/<name>", EnvKernelNotebook, notebook)
+	}
+	// The workspace claims cannot be mounted across the namespaces, and
+	// the identities are looked up in the namespace of the kernel.
+	if parts[0] != kernel.Namespace {
+		return nil, fmt.Errorf("the kernel of the notebook %s cannot be launched in the namespace %s",
+			notebook, kernel.Namespace)
+	}
+
+	secret := &v1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{
+		Namespace: parts[0],
+		Name:      NotebookTokenSecretName(parts[1]),
+	}, secret); err != nil {
+		return nil, err
+	}
+	expected := secret.Data[NotebookTokenKey]
+	if len(expected) == 0 || subtle.ConstantTimeCompare(expected, []byte(token)) != 1 {
+		return nil, fmt.Errorf("the kernel is not started by the notebook %s, invalid %s",
+			notebook, EnvKernelNotebookToken)
+	}
+
+	nb := &v1alpha1.JupyterNotebook{}
+	if err := cli.Get(ctx, types.NamespacedName{
+		Namespace: parts[0],
+		Name:      parts[1],
+	}, nb); err != nil {
+		return nil, err
+	}
+	return nb, nil
+}
+
+// ApplyNotebook labels the kernel and the kernel pod with the notebook
+// verified by VerifyNotebook. It does nothing if nb is nil.
+func ApplyNotebook(kernel *v1alpha1.JupyterKernel, nb *v1alpha1.JupyterNotebook) {
+	if nb == nil {
+		return
+	}
+	if kernel.Labels == nil {
+		kernel.Labels = make(map[string]string)
+	}
+	kernel.Labels[LabelNotebook] = nb.Name
+	pod := &kernel.Spec.Template
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[LabelNotebook] = nb.Name
+}
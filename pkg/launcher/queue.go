@@ -0,0 +1,99 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// EnvKernelLaunchTimeout is the seconds the gateway waits for the
+	// kernel to be started, which is set by the client.
+	EnvKernelLaunchTimeout = "KERNEL_LAUNCH_TIMEOUT"
+
+	// DefaultLaunchTimeout is the launch timeout of the gateway if the
+	// client does not set it, which is EG_KERNEL_LAUNCH_TIMEOUT of the
+	// gateways created by the operator.
+	DefaultLaunchTimeout = 60 * time.Second
+)
+
+// LaunchTimeout returns the launch timeout in the value of
+// KERNEL_LAUNCH_TIMEOUT, or DefaultLaunchTimeout if it is not a positive
+// number of seconds.
+func LaunchTimeout(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return DefaultLaunchTimeout
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// WaitAdmitted waits until the kernel leaves the queue of the operator.
+// The position is reported whenever it changes. If the queue is disabled,
+// it returns once the kernel is running or failed. An error is returned if
+// the kernel is still queued after the timeout, or it is deleted.
+func WaitAdmitted(cli client.Client, key types.NamespacedName,
+	interval, timeout time.Duration, report func(position int32)) error {
+	position := int32(0)
+	err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		k := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), key, k); err != nil {
+			return false, err
+		}
+		if p := k.Status.QueuePosition; p != nil && *p != position {
+			position = *p
+			report(position)
+		}
+		return leftQueue(k), nil
+	})
+	switch {
+	case err == wait.ErrWaitTimeout:
+		return fmt.Errorf("the kernel %s is not admitted in %s", key, timeout)
+	case errors.IsNotFound(err):
+		return fmt.Errorf("the kernel %s is deleted while waiting in the queue", key)
+	}
+	return err
+}
+
+// leftQueue returns true if the kernel is admitted, or it is started
+// without the queue.
+func leftQueue(k *v1alpha1.JupyterKernel) bool {
+	for _, c := range k.Status.Conditions {
+		switch c.Type {
+		case v1alpha1.JupyterKernelQueued:
+			if c.Status == v1.ConditionFalse {
+				return true
+			}
+		case v1alpha1.JupyterKernelRunning, v1alpha1.JupyterKernelFailed:
+			if c.Status == v1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
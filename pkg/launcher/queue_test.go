@@ -0,0 +1,88 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestLaunchTimeout(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":    DefaultLaunchTimeout,
+		"abc": DefaultLaunchTimeout,
+		"0":   DefaultLaunchTimeout,
+		"300": 300 * time.Second,
+		"1.5": 1500 * time.Millisecond,
+	}
+	for value, expected := range tests {
+		if actual := LaunchTimeout(value); actual != expected {
+			t.Errorf("%q: Expected %s, got %s", value, expected, actual)
+		}
+	}
+}
+
+func TestWaitAdmitted(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "kernel"}
+	newKernel := func(status v1.ConditionStatus) *v1alpha1.JupyterKernel {
+		position := int32(1)
+		return &v1alpha1.JupyterKernel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Status: v1alpha1.JupyterKernelStatus{
+				Conditions: []v1alpha1.JupyterKernelCondition{
+					{Type: v1alpha1.JupyterKernelQueued, Status: status},
+				},
+				QueuePosition: &position,
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		objs      []runtime.Object
+		positions int
+		err       bool
+	}{
+		{name: "admitted", objs: []runtime.Object{newKernel(v1.ConditionFalse)}, positions: 1},
+		{name: "queued", objs: []runtime.Object{newKernel(v1.ConditionTrue)}, positions: 1, err: true},
+		{name: "deleted", err: true},
+	}
+	for _, test := range tests {
+		cli := fake.NewFakeClientWithScheme(s, test.objs...)
+		positions := 0
+		err := WaitAdmitted(cli, key, time.Millisecond, 10*time.Millisecond,
+			func(int32) { positions++ })
+		if (err != nil) != test.err {
+			t.Errorf("%s: Expected error %v, got %v", test.name, test.err, err)
+		}
+		if positions != test.positions {
+			t.Errorf("%s: Expected %d reported positions, got %d", test.name, test.positions, positions)
+		}
+	}
+}
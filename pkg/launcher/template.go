@@ -0,0 +1,44 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// AnnotationTemplateName is the annotation of the kernel, which is set
+	// to the name of the kernel template the kernel is launched from.
+	AnnotationTemplateName = "kubeflow.tkestack.io/template-name"
+	// AnnotationTemplateNamespace is the annotation of the kernel, which is
+	// set to the namespace of the kernel template. It is not set if the
+	// kernel is launched from a ClusterJupyterKernelTemplate.
+	AnnotationTemplateNamespace = "kubeflow.tkestack.io/template-namespace"
+)
+
+// SetTemplate annotates the kernel with the kernel template it is launched
+// from, which is empty in the namespace for a ClusterJupyterKernelTemplate.
+// The names are not limited to 63 characters as the label values.
+func SetTemplate(kernel *v1alpha1.JupyterKernel, name, namespace string) {
+	if kernel.Annotations == nil {
+		kernel.Annotations = make(map[string]string)
+	}
+	kernel.Annotations[AnnotationTemplateName] = name
+	if namespace != "" {
+		kernel.Annotations[AnnotationTemplateNamespace] = namespace
+	}
+}
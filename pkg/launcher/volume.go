@@ -0,0 +1,131 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// EnvKernelVolumes is the names of the volumes in the catalog of the
+	// kernel template requested by the user, separated by commas.
+	EnvKernelVolumes = "KERNEL_VOLUMES"
+	// EnvKernelVolumeMounts is the mounts of the volumes in the kernel
+	// pod of Enterprise Gateway, which is rejected since the mount paths
+	// are set in the catalog.
+	EnvKernelVolumeMounts = "KERNEL_VOLUME_MOUNTS"
+)
+
+// ApplyVolumes mounts the volumes requested by the user into the first
+// container of the pod. requested is the value of KERNEL_VOLUMES, and the
+// volumes which are not in the catalog are rejected. mounts is the value
+// of KERNEL_VOLUME_MOUNTS, which should be empty.
+func ApplyVolumes(pod *v1.PodTemplateSpec, catalog []v1alpha1.KernelVolume,
+	requested, mounts string) error {
+	if mounts != "" {
+		return fmt.Errorf("%s is not supported, the volumes are mounted at the paths in the kernel template",
+			EnvKernelVolumeMounts)
+	}
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return fmt.Errorf("no container found in the kernel template")
+	}
+
+	volumes := make(map[string]*v1alpha1.KernelVolume, len(catalog))
+	for i := range catalog {
+		volumes[catalog[i].Name] = &catalog[i]
+	}
+	unknown := []string{}
+	for _, name := range names {
+		if _, ok := volumes[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) != 0 {
+		return fmt.Errorf("the volumes %s are not in the catalog of the kernel template",
+			strings.Join(unknown, ", "))
+	}
+
+	existing := map[string]bool{}
+	for _, v := range pod.Spec.Volumes {
+		existing[v.Name] = true
+	}
+	c := &pod.Spec.Containers[0]
+	for _, name := range names {
+		kv := volumes[name]
+		if existing[name] {
+			return fmt.Errorf("the volume %s conflicts with the volume in the kernel template", name)
+		}
+		source, err := volumeSource(kv)
+		if err != nil {
+			return err
+		}
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+			Name:         name,
+			VolumeSource: *source,
+		})
+		c.VolumeMounts = append(c.VolumeMounts, v1.VolumeMount{
+			Name:      name,
+			MountPath: kv.MountPath,
+			// The configmaps are always mounted read-only.
+			ReadOnly: kv.ReadOnly || kv.ConfigMap != nil,
+		})
+	}
+	return nil
+}
+
+// volumeSource returns the source of the kernel volume.
+func volumeSource(kv *v1alpha1.KernelVolume) (*v1.VolumeSource, error) {
+	source := &v1.VolumeSource{}
+	n := 0
+	if kv.PersistentVolumeClaim != nil {
+		source.PersistentVolumeClaim = kv.PersistentVolumeClaim.DeepCopy()
+		n++
+	}
+	if kv.NFS != nil {
+		source.NFS = kv.NFS.DeepCopy()
+		n++
+	}
+	if kv.ConfigMap != nil {
+		source.ConfigMap = kv.ConfigMap.DeepCopy()
+		n++
+	}
+	if kv.CSI != nil {
+		source.CSI = kv.CSI.DeepCopy()
+		n++
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("the volume %s in the catalog should have exactly one source", kv.Name)
+	}
+	return source, nil
+}
@@ -0,0 +1,106 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+var testCatalog = []v1alpha1.KernelVolume{
+	{
+		Name:      "home",
+		MountPath: "/home/jovyan/data",
+		KernelVolumeSource: v1alpha1.KernelVolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: "data",
+			},
+		},
+	},
+	{
+		Name:      "datasets",
+		MountPath: "/datasets",
+		KernelVolumeSource: v1alpha1.KernelVolumeSource{
+			NFS: &v1.NFSVolumeSource{Server: "nfs.example.com", Path: "/exports/datasets"},
+		},
+	},
+	{
+		Name:      "config",
+		MountPath: "/etc/kernel",
+		KernelVolumeSource: v1alpha1.KernelVolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: "kernel-config"},
+			},
+		},
+	},
+}
+
+func TestApplyVolumes(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		mounts    string
+		claims    map[string]string
+		readOnly  map[string]bool
+		err       bool
+	}{
+		{name: "none"},
+		{
+			name:      "claim",
+			requested: "home, datasets,home",
+			claims:    map[string]string{"home": "data"},
+			readOnly:  map[string]bool{"home": false, "datasets": false},
+		},
+		{
+			name:      "configmap",
+			requested: "config",
+			readOnly:  map[string]bool{"config": true},
+		},
+		{name: "not in the catalog", requested: "datasets,secrets", err: true},
+		{name: "raw mounts", mounts: "[{name: data, mountPath: /data}]", err: true},
+	}
+	for _, test := range tests {
+		pod := newPod()
+		err := ApplyVolumes(pod, testCatalog, test.requested, test.mounts)
+		if (err != nil) != test.err {
+			t.Fatalf("%s: Expected error %v, got %v", test.name, test.err, err)
+		}
+		if test.err {
+			if len(pod.Spec.Volumes) != 0 {
+				t.Errorf("%s: Expected no volume, got %v", test.name, pod.Spec.Volumes)
+			}
+			continue
+		}
+		if len(pod.Spec.Volumes) != len(test.readOnly) {
+			t.Fatalf("%s: Expected %d volumes, got %v", test.name, len(test.readOnly), pod.Spec.Volumes)
+		}
+		for _, v := range pod.Spec.Volumes {
+			if claim, ok := test.claims[v.Name]; ok && v.PersistentVolumeClaim.ClaimName != claim {
+				t.Errorf("%s: Expected the claim %s, got %s", test.name, claim, v.PersistentVolumeClaim.ClaimName)
+			}
+		}
+		for _, m := range pod.Spec.Containers[0].VolumeMounts {
+			if readOnly, ok := test.readOnly[m.Name]; !ok || m.ReadOnly != readOnly {
+				t.Errorf("%s: Expected the volume %s mounted with read-only %v, got %v",
+					test.name, m.Name, readOnly, m.ReadOnly)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// EnvKernelWorkingDir is the working directory of the notebook, which is
+// passed by the gateway with EG_MIRROR_WORKING_DIRS.
+const EnvKernelWorkingDir = "KERNEL_WORKING_DIR"
+
+// ApplyWorkspace mounts the workspace claim of the notebook at the same
+// path in the kernel container, and runs the kernel in the working
+// directory of the notebook. nb is the notebook verified by
+// VerifyNotebook, and nothing is mounted if it is nil or has no workspace.
+func ApplyWorkspace(ctx context.Context, cli client.Reader,
+	kernel *v1alpha1.JupyterKernel, nb *v1alpha1.JupyterNotebook, workingDir string) error {
+	if nb == nil || nb.Spec.Workspace == nil {
+		return nil
+	}
+	if len(kernel.Spec.Template.Spec.Containers) == 0 {
+		return fmt.Errorf("no container found in the kernel template")
+	}
+	volume, mount, err := workspace(nb)
+	if err != nil {
+		return err
+	}
+
+	pvc := &v1.PersistentVolumeClaim{}
+	if err := cli.Get(ctx, types.NamespacedName{
+		Namespace: nb.Namespace,
+		Name:      volume.PersistentVolumeClaim.ClaimName,
+	}, pvc); err != nil {
+		return err
+	}
+	readOnly, err := workspaceReadOnly(pvc)
+	if err != nil {
+		return err
+	}
+	readOnly = readOnly || volume.PersistentVolumeClaim.ReadOnly || mount.ReadOnly
+
+	pod := &kernel.Spec.Template.Spec
+	for _, v := range pod.Volumes {
+		if v.Name == volume.Name {
+			return fmt.Errorf("the workspace volume %s conflicts with the volume in the kernel template",
+				volume.Name)
+		}
+	}
+	pod.Volumes = append(pod.Volumes, v1.Volume{
+		Name: volume.Name,
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: pvc.Name,
+				ReadOnly:  readOnly,
+			},
+		},
+	})
+	c := &pod.Containers[0]
+	c.VolumeMounts = append(c.VolumeMounts, v1.VolumeMount{
+		Name:      volume.Name,
+		MountPath: mount.MountPath,
+		SubPath:   mount.SubPath,
+		ReadOnly:  readOnly,
+	})
+	if workingDir != "" && within(workingDir, mount.MountPath) {
+		c.WorkingDir = workingDir
+	}
+	return nil
+}
+
+// workspace returns the workspace volume of the notebook, and its mount
+// in the notebook container.
+func workspace(nb *v1alpha1.JupyterNotebook) (*v1.Volume, *v1.VolumeMount, error) {
+	name := nb.Spec.Workspace.Volume
+	if nb.Spec.Template == nil || len(nb.Spec.Template.Spec.Containers) == 0 {
+		return nil, nil, fmt.Errorf("the workspace volume %s is not found in the notebook %s", name, nb.Name)
+	}
+	var volume *v1.Volume
+	for i, v := range nb.Spec.Template.Spec.Volumes {
+		if v.Name == name {
+			volume = &nb.Spec.Template.Spec.Volumes[i]
+			break
+		}
+	}
+	if volume == nil {
+		return nil, nil, fmt.Errorf("the workspace volume %s is not found in the notebook %s", name, nb.Name)
+	}
+	if volume.PersistentVolumeClaim == nil {
+		return nil, nil, fmt.Errorf("the workspace volume %s of the notebook %s is not a PersistentVolumeClaim",
+			name, nb.Name)
+	}
+	for i, m := range nb.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if m.Name == name {
+			return volume, &nb.Spec.Template.Spec.Containers[0].VolumeMounts[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("the workspace volume %s is not mounted in the notebook %s", name, nb.Name)
+}
+
+// workspaceReadOnly returns true if the claim can only be shared across
+// the nodes read-only. The claims which cannot be shared across the nodes
+// are rejected, since the kernel may run on another node than the
+// notebook.
+func workspaceReadOnly(pvc *v1.PersistentVolumeClaim) (bool, error) {
+	modes := pvc.Status.AccessModes
+	if len(modes) == 0 {
+		modes = pvc.Spec.AccessModes
+	}
+	readOnly := false
+	for _, m := range modes {
+		switch m {
+		case v1.ReadWriteMany:
+			return false, nil
+		case v1.ReadOnlyMany:
+			readOnly = true
+		}
+	}
+	if readOnly {
+		return true, nil
+	}
+	return false, fmt.Errorf("the workspace claim %s with the access modes %v cannot be shared across the nodes",
+		pvc.Name, modes)
+}
+
+// within returns true if dir is the directory p or under it.
+func within(dir, p string) bool {
+	dir, p = path.Clean(dir), path.Clean(p)
+	return dir == p || strings.HasPrefix(dir, strings.TrimSuffix(p, "/")+"/")
+}
@@ -0,0 +1,165 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package launcher
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func TestApplyWorkspace(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	nb := &v1alpha1.JupyterNotebook{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "notebook"},
+		Spec: v1alpha1.JupyterNotebookSpec{
+			Workspace: &v1alpha1.JupyterNotebookWorkspace{Volume: "work"},
+			Template: &v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{
+						Name: "notebook",
+						VolumeMounts: []v1.VolumeMount{
+							{Name: "work", MountPath: "/home/jovyan/work"},
+						},
+					}},
+					Volumes: []v1.Volume{{
+						Name: "work",
+						VolumeSource: v1.VolumeSource{
+							PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+								ClaimName: "work",
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: NotebookTokenSecretName("notebook")},
+		Data:       map[string][]byte{NotebookTokenKey: []byte("secret")},
+	}
+
+	tests := []struct {
+		name     string
+		notebook string
+		token    string
+		modes    []v1.PersistentVolumeAccessMode
+		readOnly bool
+		err      bool
+	}{
+		{
+			name:     "read write many",
+			notebook: "default/notebook",
+			token:    "secret",
+			modes:    []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+		},
+		{
+			name:     "read only many",
+			notebook: "default/notebook",
+			token:    "secret",
+			modes:    []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce, v1.ReadOnlyMany},
+			readOnly: true,
+		},
+		{
+			name:     "read write once",
+			notebook: "default/notebook",
+			token:    "secret",
+			modes:    []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			err:      true,
+		},
+		{
+			name:     "another namespace",
+			notebook: "team/notebook",
+			token:    "secret",
+			modes:    []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			err:      true,
+		},
+		{
+			name:     "invalid token",
+			notebook: "default/notebook",
+			token:    "guess",
+			modes:    []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			err:      true,
+		},
+		{
+			name:     "no token",
+			notebook: "default/notebook",
+			modes:    []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			err:      true,
+		},
+		{name: "disabled"},
+	}
+	for _, test := range tests {
+		pvc := &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "work"},
+			Spec:       v1.PersistentVolumeClaimSpec{AccessModes: test.modes},
+		}
+		cli := fake.NewFakeClientWithScheme(s, nb.DeepCopy(), secret.DeepCopy(), pvc)
+		kernel := &v1alpha1.JupyterKernel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kernel"},
+			Spec: v1alpha1.JupyterKernelCRDSpec{
+				Template: *newPod(),
+			},
+		}
+
+		verified, err := VerifyNotebook(context.TODO(), cli, kernel, test.notebook, test.token)
+		if err == nil {
+			ApplyNotebook(kernel, verified)
+			err = ApplyWorkspace(context.TODO(), cli, kernel, verified, "/home/jovyan/work/project")
+		}
+		if (err != nil) != test.err {
+			t.Fatalf("%s: Expected error %v, got %v", test.name, test.err, err)
+		}
+		pod := kernel.Spec.Template.Spec
+		if test.err || test.notebook == "" {
+			if len(pod.Volumes) != 0 {
+				t.Errorf("%s: Expected no volume, got %v", test.name, pod.Volumes)
+			}
+			continue
+		}
+		if len(pod.Volumes) != 1 || pod.Volumes[0].PersistentVolumeClaim.ClaimName != "work" {
+			t.Fatalf("%s: Expected the workspace claim, got %v", test.name, pod.Volumes)
+		}
+		mounts := pod.Containers[0].VolumeMounts
+		if len(mounts) != 1 || mounts[0].MountPath != "/home/jovyan/work" ||
+			mounts[0].ReadOnly != test.readOnly {
+			t.Errorf("%s: Expected the workspace mounted with read-only %v, got %v",
+				test.name, test.readOnly, mounts)
+		}
+		if dir := pod.Containers[0].WorkingDir; dir != "/home/jovyan/work/project" {
+			t.Errorf("%s: Expected the working directory of the notebook, got %q", test.name, dir)
+		}
+		if kernel.Labels[LabelNotebook] != "notebook" || kernel.Spec.Template.Labels[LabelNotebook] != "notebook" {
+			t.Errorf("%s: Expected the kernel labeled with the notebook, got %v", test.name, kernel.Labels)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/notebook"
+)
+
+const (
+	notebookStatusPath = "/api/status"
+	defaultTimeout     = 5 * time.Second
+)
+
+// notebookStatus is the response of the status API of the notebook.
+type notebookStatus struct {
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// idleTracker polls the status API of the notebooks periodically, and
+// records the idle time of the notebooks.
+type idleTracker struct {
+	cli      client.Client
+	log      logr.Logger
+	interval time.Duration
+	http     *http.Client
+}
+
+func newIdleTracker(cli client.Client, l logr.Logger, interval time.Duration) *idleTracker {
+	return &idleTracker{
+		cli:      cli,
+		log:      l,
+		interval: interval,
+		http:     &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Start implements manager.Runnable.
+func (t *idleTracker) Start(stop <-chan struct{}) error {
+	wait.Until(t.track, t.interval, stop)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every
+// replica exports the idle time of the notebooks.
+func (t *idleTracker) NeedLeaderElection() bool {
+	return false
+}
+
+func (t *idleTracker) track() {
+	notebooks := &v1alpha1.JupyterNotebookList{}
+	if err := t.cli.List(context.TODO(), notebooks); err != nil {
+		t.log.Error(err, "Failed to list the notebooks")
+		return
+	}
+
+	notebookIdle.Reset()
+	for i := range notebooks.Items {
+		nb := &notebooks.Items[i]
+		idle, err := t.idleSeconds(nb)
+		if err != nil {
+			t.log.V(1).Info("Failed to get the status of the notebook",
+				"namespace", nb.Namespace, "notebook", nb.Name, "error", err.Error())
+			continue
+		}
+		notebookIdle.WithLabelValues(nb.Namespace, nb.Name).Set(idle)
+	}
+}
+
+// idleSeconds returns the time since the last activity of the notebook,
+// which is reported by the status API of the running notebook pod.
+func (t *idleTracker) idleSeconds(nb *v1alpha1.JupyterNotebook) (float64, error) {
+	pods := &v1.PodList{}
+	if err := t.cli.List(context.TODO(), pods,
+		client.InNamespace(nb.Namespace),
+		client.MatchingLabels{
+			notebook.LabelNS:       nb.Namespace,
+			notebook.LabelNotebook: nb.Name,
+		}); err != nil {
+		return 0, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		status, err := t.status(nb, pod.Status.PodIP,
+			notebook.ContainerPort(&pod.Spec.Containers[0]))
+		if err != nil {
+			return 0, err
+		}
+		return time.Since(status.LastActivity).Seconds(), nil
+	}
+	return 0, fmt.Errorf("no running pod")
+}
+
+func (t *idleTracker) status(nb *v1alpha1.JupyterNotebook,
+	ip string, port int32) (*notebookStatus, error) {
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("http://%s:%d%s", ip, port, notebookStatusPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	if nb.Spec.Auth != nil && nb.Spec.Auth.Token != nil {
+		req.Header.Set("Authorization", "token "+*nb.Spec.Auth.Token)
+	}
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	status := &notebookStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,264 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package metrics defines the Prometheus metrics of the kernels and the
+// notebooks, which are exported by the metrics endpoint of the manager.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+const (
+	namespace = "jupyter"
+
+	labelGateway    = "gateway"
+	labelKernelSpec = "kernelspec"
+	labelNamespace  = "namespace"
+	labelNotebook   = "notebook"
+	labelReason     = "reason"
+
+	labelGatewayNamespace = "gateway_namespace"
+
+	envKernelName = "KERNEL_NAME"
+	gatewayKind   = "JupyterGateway"
+
+	// otherKernelSpec is the kernel spec label of the kernels whose
+	// KERNEL_NAME is not a kernel of the gateway.
+	otherKernelSpec = "other"
+)
+
+var (
+	kernelsLaunched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kernels_launched_total",
+		Help:      "Number of the kernels launched.",
+	}, []string{labelGateway, labelKernelSpec})
+
+	kernelsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kernels_failed_total",
+		Help:      "Number of the kernels which failed to start.",
+	}, []string{labelGateway, labelKernelSpec})
+
+	kernelsCulled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kernels_culled_total",
+		Help:      "Number of the kernels culled by the operator.",
+	}, []string{labelGateway, labelKernelSpec, labelReason})
+
+	kernelsPreempted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kernels_preempted_total",
+		Help:      "Number of the idle kernels preempted for the kernels with higher priority.",
+	}, []string{labelGateway, labelKernelSpec})
+
+	kernelLaunchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kernel_launch_duration_seconds",
+		Help:      "Time from the creation of the kernel by the launcher to the kernel pod running.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{labelGateway, labelKernelSpec})
+
+	kernelsOrphaned = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kernels_orphaned",
+		Help:      "Number of the kernels which are not tracked by the gateway.",
+	}, []string{labelGatewayNamespace, labelGateway})
+
+	notebookIdle = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "notebook_idle_seconds",
+		Help:      "Time since the last activity of the notebook.",
+	}, []string{labelNamespace, labelNotebook})
+
+	// gateways reads the gateways from the cache, which bounds the kernel
+	// spec label to the kernels of the gateway.
+	gateways client.Reader
+)
+
+// Register registers the metrics to the registry of the manager, and
+// adds the collector of the active kernels and notebooks and the tracker
+// of the idle notebooks to the manager.
+func Register(mgr ctrl.Manager, idleInterval time.Duration) error {
+	gateways = mgr.GetClient()
+	ctrlmetrics.Registry.MustRegister(
+		kernelsLaunched,
+		kernelsFailed,
+		kernelsCulled,
+		kernelsPreempted,
+		kernelLaunchDuration,
+		kernelsOrphaned,
+		notebookIdle,
+		newActiveCollector(mgr.GetClient()),
+	)
+	return mgr.Add(newIdleTracker(mgr.GetClient(),
+		ctrl.Log.WithName("metrics").WithName("idle"), idleInterval))
+}
+
+// KernelLaunched records the kernel launched by the gateway.
+func KernelLaunched(k *v1alpha1.JupyterKernel) {
+	kernelsLaunched.WithLabelValues(kernelLabels(k)...).Inc()
+}
+
+// KernelFailed records the kernel which failed to start.
+func KernelFailed(k *v1alpha1.JupyterKernel) {
+	kernelsFailed.WithLabelValues(kernelLabels(k)...).Inc()
+}
+
+// KernelCulled records the kernel culled by the operator with the reason.
+func KernelCulled(k *v1alpha1.JupyterKernel, reason string) {
+	kernelsCulled.WithLabelValues(append(kernelLabels(k), reason)...).Inc()
+}
+
+// KernelPreempted records the kernel preempted by the operator.
+func KernelPreempted(k *v1alpha1.JupyterKernel) {
+	kernelsPreempted.WithLabelValues(kernelLabels(k)...).Inc()
+}
+
+// KernelRunning records the launch latency of the kernel, which is
+// measured from the creation of the kernel to the kernel pod running.
+func KernelRunning(k *v1alpha1.JupyterKernel, running metav1.Time) {
+	kernelLaunchDuration.WithLabelValues(kernelLabels(k)...).Observe(
+		running.Sub(k.CreationTimestamp.Time).Seconds())
+}
+
+// KernelsOrphaned records the number of the kernels which are not tracked
+// by the gateway.
+func KernelsOrphaned(gw *v1alpha1.JupyterGateway, n int) {
+	kernelsOrphaned.WithLabelValues(gw.Namespace, gw.Name).Set(float64(n))
+}
+
+// kernelLabels returns the gateway and the kernel spec of the kernel. The
+// gateway is labeled by the launcher, or is the owner of the kernel. The
+// kernel spec is passed to the kernel by the launcher in the env, which
+// is set by the client thus it is replaced with "other" if it is not a
+// kernel of the gateway. The user name is not a label since it is set by
+// the client and unbounded.
+func kernelLabels(k *v1alpha1.JupyterKernel) []string {
+	gateway := k.Labels[launcher.LabelGatewayName]
+	gatewayNamespace := k.Labels[launcher.LabelGatewayNamespace]
+	if ref := metav1.GetControllerOf(k); gateway == "" && ref != nil && ref.Kind == gatewayKind {
+		gateway = ref.Name
+	}
+	if gatewayNamespace == "" {
+		gatewayNamespace = k.Namespace
+	}
+	kernelSpec := ""
+	if containers := k.Spec.Template.Spec.Containers; len(containers) != 0 {
+		for _, env := range containers[0].Env {
+			if env.Name == envKernelName {
+				kernelSpec = env.Value
+			}
+		}
+	}
+	if kernelSpec != "" && !isGatewayKernel(gatewayNamespace, gateway, kernelSpec) {
+		kernelSpec = otherKernelSpec
+	}
+	return []string{gateway, kernelSpec}
+}
+
+// isGatewayKernel returns true if the kernel spec is one of the kernels
+// or the cluster kernels of the gateway.
+func isGatewayKernel(namespace, name, kernelSpec string) bool {
+	if gateways == nil || name == "" {
+		return false
+	}
+	gw := &v1alpha1.JupyterGateway{}
+	if err := gateways.Get(context.TODO(), types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}, gw); err != nil {
+		return false
+	}
+	for _, kernels := range [][]string{gw.Spec.Kernels, gw.Spec.ClusterKernels} {
+		for _, k := range kernels {
+			if k == kernelSpec {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// activeCollector collects the number of the active kernels and notebooks
+// per namespace from the cache when it is scraped.
+type activeCollector struct {
+	cli client.Reader
+
+	kernels   *prometheus.Desc
+	notebooks *prometheus.Desc
+}
+
+func newActiveCollector(cli client.Reader) *activeCollector {
+	return &activeCollector{
+		cli: cli,
+		kernels: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "kernels_active"),
+			"Number of the active kernels.",
+			[]string{labelNamespace}, nil),
+		notebooks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "notebooks_active"),
+			"Number of the active notebooks.",
+			[]string{labelNamespace}, nil),
+	}
+}
+
+func (c *activeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.kernels
+	ch <- c.notebooks
+}
+
+func (c *activeCollector) Collect(ch chan<- prometheus.Metric) {
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := c.cli.List(context.TODO(), kernels); err == nil {
+		count := map[string]int{}
+		for _, k := range kernels.Items {
+			if k.DeletionTimestamp == nil {
+				count[k.Namespace]++
+			}
+		}
+		for ns, n := range count {
+			ch <- prometheus.MustNewConstMetric(c.kernels,
+				prometheus.GaugeValue, float64(n), ns)
+		}
+	}
+
+	notebooks := &v1alpha1.JupyterNotebookList{}
+	if err := c.cli.List(context.TODO(), notebooks); err == nil {
+		count := map[string]int{}
+		for _, nb := range notebooks.Items {
+			if nb.DeletionTimestamp == nil {
+				count[nb.Namespace]++
+			}
+		}
+		for ns, n := range count {
+			ch <- prometheus.MustNewConstMetric(c.notebooks,
+				prometheus.GaugeValue, float64(n), ns)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package metrics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+func newKernel(namespace, name string) *v1alpha1.JupyterKernel {
+	controller := true
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: gatewayKind, Name: "gateway", Controller: &controller},
+			},
+		},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "kernel",
+							Env: []v1.EnvVar{
+								{Name: envKernelName, Value: "python-kubernetes"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newGateway(kernels ...string) *v1alpha1.JupyterGateway {
+	return &v1alpha1.JupyterGateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"},
+		Spec:       v1alpha1.JupyterGatewaySpec{Kernels: kernels},
+	}
+}
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKernelLabels(t *testing.T) {
+	gateways = fake.NewFakeClientWithScheme(newScheme(t), newGateway("python-kubernetes"))
+	defer func() { gateways = nil }()
+
+	k := newKernel("default", "kernel")
+	expected := []string{"gateway", "python-kubernetes"}
+	if actual := kernelLabels(k); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	k.Spec.Template.Spec.Containers[0].Env[0].Value = "unknown"
+	expected = []string{"gateway", otherKernelSpec}
+	if actual := kernelLabels(k); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	k.OwnerReferences = nil
+	k.Spec.Template.Spec.Containers = nil
+	expected = []string{"", ""}
+	if actual := kernelLabels(k); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestKernelCounters(t *testing.T) {
+	gateways = fake.NewFakeClientWithScheme(newScheme(t), newGateway("python-kubernetes"))
+	defer func() { gateways = nil }()
+
+	k := newKernel("default", "kernel")
+	KernelLaunched(k)
+	KernelFailed(k)
+	KernelCulled(k, "Culled")
+
+	if v := testutil.ToFloat64(kernelsLaunched.WithLabelValues(
+		"gateway", "python-kubernetes")); v != 1 {
+		t.Errorf("Expected 1 launched kernel, got %v", v)
+	}
+	if v := testutil.ToFloat64(kernelsFailed.WithLabelValues(
+		"gateway", "python-kubernetes")); v != 1 {
+		t.Errorf("Expected 1 failed kernel, got %v", v)
+	}
+	if v := testutil.ToFloat64(kernelsCulled.WithLabelValues(
+		"gateway", "python-kubernetes", "Culled")); v != 1 {
+		t.Errorf("Expected 1 culled kernel, got %v", v)
+	}
+}
+
+func TestActiveCollector(t *testing.T) {
+	s := newScheme(t)
+	deleted := newKernel("default", "deleted")
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	cli := fake.NewFakeClientWithScheme(s,
+		newKernel("default", "a"),
+		newKernel("default", "b"),
+		newKernel("team", "c"),
+		deleted,
+		&v1alpha1.JupyterNotebook{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "notebook"},
+		},
+	)
+
+	expected := `
+# HELP jupyter_kernels_active Number of the active kernels.
+# TYPE jupyter_kernels_active gauge
+jupyter_kernels_active{namespace="default"} 2
+jupyter_kernels_active{namespace="team"} 1
+# HELP jupyter_notebooks_active Number of the active notebooks.
+# TYPE jupyter_notebooks_active gauge
+jupyter_notebooks_active{namespace="team"} 1
+`
+	if err := testutil.CollectAndCompare(newActiveCollector(cli),
+		strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
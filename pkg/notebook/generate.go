@@ -17,7 +17,10 @@
 package notebook
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -25,34 +28,52 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/podcache"
 )
 
 const (
-	defaultImage         = "jupyter/base-notebook:python-3.9.7"
 	defaultContainerName = "notebook"
 	defaultPortName      = "notebook"
 	defaultPort          = 8888
+	probePathStatus      = "/api/status"
+	probePathVersion     = "/api"
 
 	LabelNotebook = "notebook"
 	LabelNS       = "namespace"
 
 	argumentGatewayURL       = "--gateway-url"
+	argumentGatewayEnv       = "--GatewayClient.env_whitelist"
 	argumentNotebookToken    = "--NotebookApp.token"
 	argumentNotebookPassword = "--NotebookApp.password"
 )
 
+// DefaultNotebookImage is the default image of the notebooks without the
+// template.
+const DefaultNotebookImage = "jupyter/base-notebook:python-3.9.7"
+
+// Options are the options of the notebooks, which are set by the operator
+// configuration.
+type Options struct {
+	// Image is the image of the notebooks without the template. It
+	// defaults to DefaultNotebookImage.
+	Image string
+}
+
 type generator struct {
-	nb *v1alpha1.JupyterNotebook
+	nb   *v1alpha1.JupyterNotebook
+	opts Options
 }
 
 // newGenerator creates a new Generator.
-func newGenerator(nb *v1alpha1.JupyterNotebook) (
+func newGenerator(nb *v1alpha1.JupyterNotebook, opts Options) (
 	*generator, error) {
 	if nb == nil {
 		return nil, fmt.Errorf("the notebook is null")
 	}
 	g := &generator{
-		nb: nb,
+		nb:   nb,
+		opts: opts,
 	}
 
 	return g, nil
@@ -90,7 +111,7 @@ func (g generator) DesiredDeploymentWithoutOwner() (*appsv1.Deployment, error) {
 			Containers: []v1.Container{
 				{
 					Name:                     defaultContainerName,
-					Image:                    defaultImage,
+					Image:                    g.image(),
 					ImagePullPolicy:          v1.PullIfNotPresent,
 					TerminationMessagePath:   v1.TerminationMessagePathDefault,
 					TerminationMessagePolicy: v1.TerminationMessageReadFile,
@@ -113,6 +134,7 @@ func (g generator) DesiredDeploymentWithoutOwner() (*appsv1.Deployment, error) {
 			SchedulerName:                 v1.DefaultSchedulerName,
 		}
 	}
+	podLabels[podcache.LabelManaged] = podcache.ValueNotebook
 
 	replicas := int32(1)
 	revisionHistoryLimit := int32(10)
@@ -155,6 +177,42 @@ func (g generator) DesiredDeploymentWithoutOwner() (*appsv1.Deployment, error) {
 			d.Spec.Template.Spec.Containers[0].Args, argumentGatewayURL, gatewayURL)
 	}
 
+	// Set the kernel env to the notebook, which sends the allowed env to
+	// the gateway when it starts the kernels.
+	if len(g.nb.Spec.KernelEnv) != 0 {
+		c := &d.Spec.Template.Spec.Containers[0]
+		names := []string{}
+		for _, env := range g.nb.Spec.KernelEnv {
+			c.Env = append(c.Env, *env.DeepCopy())
+			names = append(names, env.Name)
+		}
+		if g.nb.Spec.Gateway != nil {
+			c.Args = append(c.Args, argumentGatewayEnv, strings.Join(names, ","))
+		}
+	}
+
+	// Tell the kernel launcher the notebook, which mounts the workspace of
+	// the notebook into the kernels and runs them as the identity of the
+	// notebook. The token from the token Secret proves to the launcher
+	// that the kernels are started by the notebook.
+	if g.nb.Spec.Gateway != nil {
+		d.Spec.Template.Spec.Containers[0].Env = append(
+			d.Spec.Template.Spec.Containers[0].Env, v1.EnvVar{
+				Name:  launcher.EnvKernelNotebook,
+				Value: g.nb.Namespace + "/" + g.nb.Name,
+			}, v1.EnvVar{
+				Name: launcher.EnvKernelNotebookToken,
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: launcher.NotebookTokenSecretName(g.nb.Name),
+						},
+						Key: launcher.NotebookTokenKey,
+					},
+				},
+			})
+	}
+
 	// Set the auth configuration to notebook instance.
 	if g.nb.Spec.Auth != nil {
 		auth := g.nb.Spec.Auth
@@ -181,9 +239,74 @@ func (g generator) DesiredDeploymentWithoutOwner() (*appsv1.Deployment, error) {
 		}
 	}
 
+	// Set the default probes, which are overridden by the template.
+	c := &d.Spec.Template.Spec.Containers[0]
+	if c.LivenessProbe == nil {
+		c.LivenessProbe = g.defaultProbe(c, 10)
+	}
+	if c.ReadinessProbe == nil {
+		c.ReadinessProbe = g.defaultProbe(c, 0)
+	}
+
 	return d, nil
 }
 
+// DesiredTokenSecretWithoutOwner returns the token Secret of the notebook
+// with a random token, which is only created once.
+func (g generator) DesiredTokenSecretWithoutOwner() (*v1.Secret, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      launcher.NotebookTokenSecretName(g.nb.Name),
+			Namespace: g.nb.Namespace,
+			Labels:    g.labels(),
+		},
+		Data: map[string][]byte{
+			launcher.NotebookTokenKey: []byte(hex.EncodeToString(token)),
+		},
+	}, nil
+}
+
+// defaultProbe returns the probe which requests the status API of the
+// notebook. The API requires the token, thus the version API, which does
+// not, is used instead if the token is unknown. All the fields are set to
+// keep the deployment stable after defaulting. The probe requests the
+// port named notebook if the container has one.
+func (g generator) defaultProbe(c *v1.Container, initialDelaySeconds int32) *v1.Probe {
+	port := intstr.FromInt(int(ContainerPort(c)))
+	for _, p := range c.Ports {
+		if p.Name == defaultPortName {
+			port = intstr.FromString(defaultPortName)
+		}
+	}
+	action := &v1.HTTPGetAction{
+		Path:   probePathVersion,
+		Port:   port,
+		Scheme: v1.URISchemeHTTP,
+	}
+	if auth := g.nb.Spec.Auth; auth != nil {
+		if auth.Mode == v1alpha1.ModeJupyterAuthDisable {
+			action.Path = probePathStatus
+		} else if auth.Token != nil {
+			action.Path = probePathStatus
+			action.HTTPHeaders = []v1.HTTPHeader{
+				{Name: "Authorization", Value: "token " + *auth.Token},
+			}
+		}
+	}
+	return &v1.Probe{
+		Handler:             v1.Handler{HTTPGet: action},
+		InitialDelaySeconds: initialDelaySeconds,
+		TimeoutSeconds:      5,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
 func (g generator) labels() map[string]string {
 	return map[string]string{
 		LabelNS:       g.nb.Namespace,
@@ -232,3 +355,25 @@ func completePodSpec(old *v1.PodSpec) v1.PodSpec {
 
 	return *new
 }
+
+// ContainerPort returns the port of the notebook in the container, which
+// is the port named notebook, or the first port of the container. It
+// defaults to 8888 if the container has no port.
+func ContainerPort(c *v1.Container) int32 {
+	for _, p := range c.Ports {
+		if p.Name == defaultPortName {
+			return p.ContainerPort
+		}
+	}
+	if len(c.Ports) != 0 {
+		return c.Ports[0].ContainerPort
+	}
+	return defaultPort
+}
+
+func (g generator) image() string {
+	if g.opts.Image != "" {
+		return g.opts.Image
+	}
+	return DefaultNotebookImage
+}
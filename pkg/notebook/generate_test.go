@@ -7,8 +7,10 @@ import (
 	"testing"
 
 	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
@@ -124,7 +126,7 @@ func TestGenerate(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		gen, err := newGenerator(tc.input)
+		gen, err := newGenerator(tc.input, Options{})
 		if !reflect.DeepEqual(tc.expectedErr, err) {
 			t.Errorf("expected: %v, got: %v", tc.expectedErr, err)
 		}
@@ -228,3 +230,143 @@ func TestLable(t *testing.T) {
 		}
 	}
 }
+
+func TestProbes(t *testing.T) {
+	token := "token"
+	notebookWithToken := notebookWithGateway.DeepCopy()
+	notebookWithToken.Spec.Auth = &v1alpha1.JupyterAuth{Token: &token}
+	notebookWithProbe := notebookWithTemplate.DeepCopy()
+	notebookWithProbe.Spec.Template.Spec.Containers[0].ReadinessProbe = &v1.Probe{
+		Handler: v1.Handler{Exec: &v1.ExecAction{Command: []string{"true"}}},
+	}
+	notebookWithPort := notebookWithTemplate.DeepCopy()
+	notebookWithPort.Spec.Template.Spec.Containers[0].Ports = []v1.ContainerPort{
+		{ContainerPort: 9999},
+	}
+	named := intstr.FromString(defaultPortName)
+
+	type test struct {
+		gen             *generator
+		expectedPort    intstr.IntOrString
+		expectedPath    string
+		expectedHeaders []v1.HTTPHeader
+		expectedExec    bool
+	}
+	tests := []test{
+		{gen: &generator{nb: notebookWithGateway}, expectedPort: named, expectedPath: probePathVersion},
+		{gen: &generator{nb: notebookWithAuthPassword}, expectedPort: intstr.FromInt(defaultPort), expectedPath: probePathVersion},
+		{
+			gen:             &generator{nb: notebookWithToken},
+			expectedPort:    named,
+			expectedPath:    probePathStatus,
+			expectedHeaders: []v1.HTTPHeader{{Name: "Authorization", Value: "token token"}},
+		},
+		{
+			gen:          &generator{nb: notebookWithProbe},
+			expectedPort: intstr.FromInt(defaultPort),
+			expectedPath: probePathVersion,
+			expectedExec: true,
+		},
+		{gen: &generator{nb: notebookWithPort}, expectedPort: intstr.FromInt(9999), expectedPath: probePathVersion},
+	}
+
+	for i, tc := range tests {
+		d, err := tc.gen.DesiredDeploymentWithoutOwner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := d.Spec.Template.Spec.Containers[0]
+		if c.LivenessProbe == nil || c.LivenessProbe.HTTPGet == nil {
+			t.Fatalf("i= %d expected the default liveness probe, got: %v", i, c.LivenessProbe)
+		}
+		if c.LivenessProbe.HTTPGet.Port != tc.expectedPort {
+			t.Errorf("i= %d expected: %v, got: %v", i, tc.expectedPort, c.LivenessProbe.HTTPGet.Port)
+		}
+		if c.LivenessProbe.HTTPGet.Path != tc.expectedPath {
+			t.Errorf("i= %d expected: %v, got: %v", i, tc.expectedPath, c.LivenessProbe.HTTPGet.Path)
+		}
+		if !reflect.DeepEqual(tc.expectedHeaders, c.LivenessProbe.HTTPGet.HTTPHeaders) {
+			t.Errorf("i= %d expected: %v, got: %v", i, tc.expectedHeaders, c.LivenessProbe.HTTPGet.HTTPHeaders)
+		}
+		if tc.expectedExec != (c.ReadinessProbe.Exec != nil) {
+			t.Errorf("i= %d expected the readiness probe from the template: %v", i, tc.expectedExec)
+		}
+	}
+	if notebookWithTemplate.Spec.Template.Spec.Containers[0].LivenessProbe != nil {
+		t.Errorf("expected the template not to be mutated")
+	}
+}
+
+func TestKernelEnv(t *testing.T) {
+	notebookWithEnv := completeNotebook.DeepCopy()
+	notebookWithEnv.Spec.KernelEnv = []v1.EnvVar{
+		{Name: "KERNEL_VOLUMES", Value: "data"},
+		{
+			Name: "AWS_SECRET_ACCESS_KEY",
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: "aws"},
+					Key:                  "secret-access-key",
+				},
+			},
+		},
+	}
+
+	d, err := (&generator{nb: notebookWithEnv}).DesiredDeploymentWithoutOwner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := d.Spec.Template.Spec.Containers[0]
+	// The notebook and its token follow the kernel env.
+	if env := c.Env[:len(c.Env)-2]; !reflect.DeepEqual(env, notebookWithEnv.Spec.KernelEnv) {
+		t.Errorf("expected: %v, got: %v", notebookWithEnv.Spec.KernelEnv, env)
+	}
+	expectedArgs := []string{
+		argumentGatewayURL,
+		fmt.Sprintf("http://%s.%s:%d", GatewayName, GatewayNamespace, 8888),
+		argumentGatewayEnv, "KERNEL_VOLUMES,AWS_SECRET_ACCESS_KEY",
+	}
+	if !reflect.DeepEqual(c.Args, expectedArgs) {
+		t.Errorf("expected: %v, got: %v", expectedArgs, c.Args)
+	}
+	if len(notebookWithEnv.Spec.Template.Spec.Containers[0].Env) != 0 {
+		t.Errorf("expected the template not to be mutated")
+	}
+}
+
+func TestNotebookToken(t *testing.T) {
+	d, err := (&generator{nb: completeNotebook}).DesiredDeploymentWithoutOwner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1.EnvVar{{
+		Name:  launcher.EnvKernelNotebook,
+		Value: JupyterNotebookNamespace + "/" + JupyterNotebookName,
+	}, {
+		Name: launcher.EnvKernelNotebookToken,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: launcher.NotebookTokenSecretName(JupyterNotebookName),
+				},
+				Key: launcher.NotebookTokenKey,
+			},
+		},
+	}}
+	if env := d.Spec.Template.Spec.Containers[0].Env; !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected: %v, got: %v", expected, env)
+	}
+
+	secret, err := (&generator{nb: completeNotebook}).DesiredTokenSecretWithoutOwner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	another, err := (&generator{nb: completeNotebook}).DesiredTokenSecretWithoutOwner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := secret.Data[launcher.NotebookTokenKey]
+	if len(token) != 64 || reflect.DeepEqual(token, another.Data[launcher.NotebookTokenKey]) {
+		t.Errorf("expected a random token, got %q", token)
+	}
+}
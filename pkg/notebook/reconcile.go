@@ -18,11 +18,14 @@ package notebook
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -45,8 +48,8 @@ type Reconciler struct {
 func NewReconciler(cli client.Client,
 	l logr.Logger,
 	r record.EventRecorder, s *runtime.Scheme,
-	i *v1alpha1.JupyterNotebook) (*Reconciler, error) {
-	g, err := newGenerator(i)
+	i *v1alpha1.JupyterNotebook, opts Options) (*Reconciler, error) {
+	g, err := newGenerator(i, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +64,9 @@ func NewReconciler(cli client.Client,
 }
 
 func (r Reconciler) Reconcile() error {
+	if err := r.reconcileTokenSecret(); err != nil {
+		return err
+	}
 	if err := r.reconcileDeployment(); err != nil {
 		return err
 	}
@@ -110,3 +116,36 @@ func (r Reconciler) reconcileDeployment() error {
 
 	return nil
 }
+
+// reconcileTokenSecret creates the token Secret of the notebook with the
+// gateway. The token is kept once created, since the running notebook has
+// read it.
+func (r Reconciler) reconcileTokenSecret() error {
+	if r.instance.Spec.Gateway == nil {
+		return nil
+	}
+	desired, err := r.gen.DesiredTokenSecretWithoutOwner()
+	if err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(
+		r.instance, desired, r.scheme); err != nil {
+		return err
+	}
+
+	actual := &v1.Secret{}
+	err = r.cli.Get(context.TODO(),
+		types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, actual)
+	if err != nil && errors.IsNotFound(err) {
+		r.log.Info("Creating the token secret", "namespace", desired.Namespace, "name", desired.Name)
+		return r.cli.Create(context.TODO(), desired)
+	} else if err != nil {
+		return err
+	}
+	// The token of the other Secret may be known to the other users.
+	if !metav1.IsControlledBy(actual, r.instance) {
+		return fmt.Errorf("the secret %s already exists and is not owned by the notebook %s",
+			actual.Name, r.instance.Name)
+	}
+	return nil
+}
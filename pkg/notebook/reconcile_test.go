@@ -92,7 +92,7 @@ var _ = Describe("JupyterNotebook controller", func() {
 
 	Context("Nil JupyterNotebook", func() {
 		It("Should fail to NewReconciler", func() {
-			_, err := NewReconciler(k8sClient, log, rec, s, nil)
+			_, err := NewReconciler(k8sClient, log, rec, s, nil, Options{})
 			Expect(err).To(HaveOccurred())
 		})
 	})
@@ -101,7 +101,7 @@ var _ = Describe("JupyterNotebook controller", func() {
 		It("Should fail to reconcileDeployment", func() {
 			var r *Reconciler
 			var err error
-			r, err = NewReconciler(k8sClient, log, rec, s, emptyNotebook)
+			r, err = NewReconciler(k8sClient, log, rec, s, emptyNotebook, Options{})
 			Expect(err).ToNot(HaveOccurred())
 			Expect(r).ToNot(BeNil())
 			err = r.reconcileDeployment()
@@ -113,7 +113,7 @@ var _ = Describe("JupyterNotebook controller", func() {
 		It("Should reconcile deployment as desired", func() {
 			var r *Reconciler
 			var err error
-			r, err = NewReconciler(k8sClient, log, rec, s, notebookWithTemplate)
+			r, err = NewReconciler(k8sClient, log, rec, s, notebookWithTemplate, Options{})
 			Expect(err).ToNot(HaveOccurred())
 			Expect(r).ToNot(BeNil())
 
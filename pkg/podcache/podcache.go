@@ -0,0 +1,81 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package podcache restricts the pods in the cache of the operator to the
+// pods of the notebooks and the kernels, instead of every pod in the
+// cluster.
+package podcache
+
+import (
+	"net/http"
+	"regexp"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+const (
+	// LabelManaged is the label of the pods managed by the operator, which
+	// are the only pods in the cache. The value is the kind of the pod,
+	// i.e. ValueKernel or ValueNotebook.
+	LabelManaged = "kubeflow.tkestack.io/managed-pod"
+
+	// ValueKernel is the value of the kernel pods, including the pods of
+	// the replica groups and the Spark executors.
+	ValueKernel = "kernel"
+	// ValueNotebook is the value of the notebook pods.
+	ValueNotebook = "notebook"
+)
+
+// podCollection matches the paths to list and watch the pods, in all or
+// one namespace.
+var podCollection = regexp.MustCompile(`^/api/v1/(namespaces/[^/]+/)?pods$`)
+
+// NewCacheFunc returns the function to create the cache by newCache, which
+// only lists and watches the pods with LabelManaged. The manager of
+// controller-runtime does not support the selectors of the cache, thus the
+// selector is added to the requests of the pods. The other objects and the
+// reads without the cache are not affected.
+func NewCacheFunc(newCache cache.NewCacheFunc) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		config = rest.CopyConfig(config)
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &selector{rt: rt}
+		})
+		return newCache(config, opts)
+	}
+}
+
+// selector adds the selector of LabelManaged to the requests which list
+// and watch the pods.
+type selector struct {
+	rt http.RoundTripper
+}
+
+func (s *selector) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || !podCollection.MatchString(req.URL.Path) {
+		return s.rt.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	q := req.URL.Query()
+	if existing := q.Get("labelSelector"); existing != "" {
+		q.Set("labelSelector", existing+","+LabelManaged)
+	} else {
+		q.Set("labelSelector", LabelManaged)
+	}
+	req.URL.RawQuery = q.Encode()
+	return s.rt.RoundTrip(req)
+}
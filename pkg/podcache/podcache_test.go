@@ -0,0 +1,59 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package podcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSelector(t *testing.T) {
+	type test struct {
+		method   string
+		url      string
+		expected string
+	}
+	tests := []test{
+		{http.MethodGet, "/api/v1/pods?watch=true", LabelManaged},
+		{http.MethodGet, "/api/v1/namespaces/default/pods", LabelManaged},
+		{http.MethodGet, "/api/v1/namespaces/default/pods?labelSelector=a%3Db", "a=b," + LabelManaged},
+		{http.MethodGet, "/api/v1/namespaces/default/pods/kernel", ""},
+		{http.MethodGet, "/api/v1/nodes", ""},
+		{http.MethodPost, "/api/v1/namespaces/default/pods", ""},
+	}
+	for _, tc := range tests {
+		actual := ""
+		s := &selector{rt: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			actual = req.URL.Query().Get("labelSelector")
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})}
+		req := httptest.NewRequest(tc.method, "https://127.0.0.1"+tc.url, nil)
+		if _, err := s.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if actual != tc.expected {
+			t.Errorf("%s %s: expected the selector %q, got %q", tc.method, tc.url, tc.expected, actual)
+		}
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
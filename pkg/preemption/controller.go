@@ -0,0 +1,194 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package preemption
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/gateway"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/metrics"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/queue"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Options are the options of the preemption.
+type Options struct {
+	// Interval is the interval to check the pending kernels.
+	Interval time.Duration
+	// MinIdleTime is the minimum duration since the last activity of the
+	// kernels which can be preempted.
+	MinIdleTime time.Duration
+	// PriorityClasses maps the priority tiers of the kernels to the
+	// PriorityClasses.
+	PriorityClasses map[v1alpha1.KernelPriorityTier]string
+}
+
+// Controller checks the kernels which are queued or cannot be scheduled
+// periodically, and preempts the idle kernels with lower priority for
+// them. Whether a kernel is idle is told by the kernels API of its
+// gateway. The kernels whose gateways are not reachable are not preempted.
+type Controller struct {
+	cli      client.Client
+	reader   client.Reader
+	log      logr.Logger
+	recorder record.EventRecorder
+	opts     Options
+	http     *http.Client
+
+	// url returns the URL of the gateway, which is replaced in the tests.
+	url func(*v1alpha1.JupyterGateway) string
+}
+
+// NewController creates the controller of the preemption. The reader
+// reads the pods of the whole cluster without the cache.
+func NewController(cli client.Client, reader client.Reader, l logr.Logger,
+	recorder record.EventRecorder, opts Options) *Controller {
+	return &Controller{
+		cli:      cli,
+		reader:   reader,
+		log:      l,
+		recorder: recorder,
+		opts:     opts,
+		http:     &http.Client{Timeout: defaultTimeout},
+		url:      gateway.URL,
+	}
+}
+
+// Start implements manager.Runnable.
+func (c *Controller) Start(stop <-chan struct{}) error {
+	wait.Until(c.Preempt, c.opts.Interval, stop)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the
+// leader preempts the kernels.
+func (c *Controller) NeedLeaderElection() bool {
+	return true
+}
+
+// Preempt preempts the idle kernels for the pending kernels once.
+func (c *Controller) Preempt() {
+	s, err := queue.NewSnapshot(c.cli, c.reader, c.opts.PriorityClasses)
+	if err != nil {
+		c.log.Error(err, "Failed to get the snapshot of the cluster")
+		return
+	}
+	pending := append(append([]*v1alpha1.JupyterKernel(nil),
+		s.Queued...), s.Unschedulable...)
+	if len(pending) == 0 {
+		return
+	}
+
+	for _, p := range SelectVictims(s, pending, c.candidates(s)) {
+		if err := c.preempt(p); err != nil {
+			c.log.Error(err, "Failed to preempt the kernels",
+				"namespace", p.Preemptor.Namespace, "kernel", p.Preemptor.Name)
+		}
+	}
+}
+
+// candidates returns the scheduled kernels which have been idle for
+// MinIdleTime in their gateways.
+func (c *Controller) candidates(s *queue.Snapshot) []Candidate {
+	now := time.Now()
+	// activities are the kernels of the gateways by the IDs, which are
+	// nil if the gateway is not reachable.
+	activities := map[types.NamespacedName]map[string]gateway.Kernel{}
+	candidates := []Candidate{}
+	for _, k := range s.Active {
+		node := s.KernelNodes[queue.Key(k)]
+		id := kernel.ID(k)
+		gw := types.NamespacedName{
+			Namespace: k.Labels[launcher.LabelGatewayNamespace],
+			Name:      k.Labels[launcher.LabelGatewayName],
+		}
+		if node == "" || id == "" || gw.Name == "" {
+			continue
+		}
+		if _, ok := activities[gw]; !ok {
+			activities[gw] = c.activities(gw)
+		}
+		a, ok := activities[gw][id]
+		if !ok || a.ExecutionState != gateway.ExecutionStateIdle ||
+			now.Sub(a.LastActivity) < c.opts.MinIdleTime {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			Kernel:       k,
+			Node:         node,
+			LastActivity: a.LastActivity,
+		})
+	}
+	return candidates
+}
+
+func (c *Controller) activities(key types.NamespacedName) map[string]gateway.Kernel {
+	gw := &v1alpha1.JupyterGateway{}
+	if err := c.cli.Get(context.TODO(), key, gw); err != nil {
+		c.log.V(1).Info("Failed to get the gateway", "gateway", key, "error", err.Error())
+		return nil
+	}
+	kernels, err := gateway.ListKernels(c.http, c.url(gw))
+	if err != nil {
+		c.log.V(1).Info("Failed to get the kernels of the gateway",
+			"gateway", key, "error", err.Error())
+		return nil
+	}
+	activities := make(map[string]gateway.Kernel, len(kernels))
+	for _, k := range kernels {
+		activities[k.ID] = k
+	}
+	return activities
+}
+
+// preempt records the preemption in the status of the victims, whose
+// deployments are deleted by the kernel reconciler.
+func (c *Controller) preempt(p Preemption) error {
+	now := metav1.Now()
+	names := []string{}
+	for _, v := range p.Victims {
+		c.log.Info("Preempting kernel", "namespace", v.Namespace, "kernel", v.Name,
+			"preemptor", queue.Key(p.Preemptor))
+		kernel.Preempt(v, p.Preemptor, now)
+		if err := c.cli.Status().Update(context.TODO(), v); err != nil {
+			return err
+		}
+		metrics.KernelPreempted(v)
+		c.recorder.Eventf(v, v1.EventTypeWarning, kernel.ReasonPreempted,
+			"The idle kernel is preempted for the kernel %s with higher priority",
+			queue.Key(p.Preemptor))
+		names = append(names, queue.Key(v))
+	}
+	c.recorder.Eventf(p.Preemptor, v1.EventTypeNormal, "PreemptedKernels",
+		"Preempted the idle kernels %s", strings.Join(names, ", "))
+	return nil
+}
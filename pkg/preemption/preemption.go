@@ -0,0 +1,155 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package preemption preempts the idle kernels with lower priority for the
+// kernels which cannot be scheduled, e.g. the interactive kernels waiting
+// for the resources held by the idle batch kernels.
+package preemption
+
+import (
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/queue"
+)
+
+// Candidate is an idle kernel which can be preempted.
+type Candidate struct {
+	Kernel *v1alpha1.JupyterKernel
+	// Node is the node of the kernel pod.
+	Node string
+	// LastActivity is the time of the last activity of the kernel in the
+	// gateway.
+	LastActivity time.Time
+}
+
+// Preemption is the decision to preempt the victims for the preemptor.
+type Preemption struct {
+	Preemptor *v1alpha1.JupyterKernel
+	Victims   []*v1alpha1.JupyterKernel
+}
+
+// SelectVictims selects the victims among the candidates for the pending
+// kernels, which are considered in the order of the priority. The victims
+// of a pending kernel are on the same node, and have lower priority than
+// it. The candidates with the lowest priority and then the longest idle
+// time are preempted first, and the node with the fewest victims is
+// chosen. No kernel is preempted for a pending kernel which fits into the
+// free resources of any node.
+func SelectVictims(s *queue.Snapshot, pending []*v1alpha1.JupyterKernel,
+	candidates []Candidate) []Preemption {
+	nodes := make([]*queue.Node, 0, len(s.Nodes))
+	for _, n := range s.Nodes {
+		c := *n
+		c.Free = n.Free.DeepCopy()
+		nodes = append(nodes, &c)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	priority := func(k *v1alpha1.JupyterKernel) int32 {
+		return queue.Priority(k, s)
+	}
+	candidates = append([]Candidate(nil), candidates...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if pa, pb := priority(a.Kernel), priority(b.Kernel); pa != pb {
+			return pa < pb
+		}
+		if !a.LastActivity.Equal(b.LastActivity) {
+			return a.LastActivity.Before(b.LastActivity)
+		}
+		return queue.Key(a.Kernel) < queue.Key(b.Kernel)
+	})
+	pending = append([]*v1alpha1.JupyterKernel(nil), pending...)
+	sort.SliceStable(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		if pa, pb := priority(a), priority(b); pa != pb {
+			return pa > pb
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return queue.Key(a) < queue.Key(b)
+	})
+
+	preempted := make([]bool, len(candidates))
+	preemptions := []Preemption{}
+	for _, p := range pending {
+		requests := queue.Requests(p)
+		var best *queue.Node
+		var victims []int
+		fits := false
+		for _, n := range nodes {
+			if !queue.Schedulable(n, &p.Spec.Template.Spec) {
+				continue
+			}
+			if queue.Fits(n.Free, requests) {
+				best, victims, fits = n, nil, true
+				break
+			}
+			free := n.Free.DeepCopy()
+			selected := []int{}
+			for i, c := range candidates {
+				if preempted[i] || c.Node != n.Name || priority(c.Kernel) >= priority(p) {
+					continue
+				}
+				add(free, queue.Requests(c.Kernel))
+				selected = append(selected, i)
+				if queue.Fits(free, requests) {
+					break
+				}
+			}
+			if !queue.Fits(free, requests) {
+				continue
+			}
+			if best == nil || len(selected) < len(victims) {
+				best, victims = n, selected
+			}
+		}
+		if best == nil {
+			continue
+		}
+
+		// The kernel is expected to be scheduled to the node, after the
+		// victims are preempted.
+		preemption := Preemption{Preemptor: p}
+		for _, i := range victims {
+			preempted[i] = true
+			add(best.Free, queue.Requests(candidates[i].Kernel))
+			preemption.Victims = append(preemption.Victims, candidates[i].Kernel)
+		}
+		for name, q := range requests {
+			free := best.Free[name]
+			free.Sub(q)
+			best.Free[name] = free
+		}
+		if !fits {
+			preemptions = append(preemptions, preemption)
+		}
+	}
+	return preemptions
+}
+
+func add(l, r v1.ResourceList) {
+	for name, q := range r {
+		total := l[name].DeepCopy()
+		total.Add(q)
+		l[name] = total
+	}
+}
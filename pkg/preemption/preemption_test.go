@@ -0,0 +1,295 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package preemption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/gateway"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/queue"
+)
+
+var classes = map[string]int32{
+	"jupyter-kernel-interactive": 1000,
+	"jupyter-kernel-batch":       500,
+	"jupyter-kernel-best-effort": 100,
+}
+
+func newNode(name, cpu string) *queue.Node {
+	l := v1.ResourceList{
+		v1.ResourceCPU:  resource.MustParse(cpu),
+		v1.ResourcePods: resource.MustParse("110"),
+	}
+	return &queue.Node{Name: name, Allocatable: l, Free: l.DeepCopy()}
+}
+
+func newKernel(name string, tier v1alpha1.KernelPriorityTier, cpu string) *v1alpha1.JupyterKernel {
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			PriorityTier: tier,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "kernel",
+							Env:  []v1.EnvVar{{Name: "KERNEL_ID", Value: name}},
+							Resources: v1.ResourceRequirements{
+								Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSelectVictims(t *testing.T) {
+	now := time.Now()
+	// full returns the node whose cpus are used by the candidates.
+	full := func(name, cpu string) *queue.Node {
+		n := newNode(name, cpu)
+		n.Free[v1.ResourceCPU] = resource.MustParse("0")
+		return n
+	}
+	candidate := func(name string, tier v1alpha1.KernelPriorityTier, cpu, node string,
+		idle time.Duration) Candidate {
+		return Candidate{
+			Kernel:       newKernel(name, tier, cpu),
+			Node:         node,
+			LastActivity: now.Add(-idle),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		nodes      []*queue.Node
+		pending    []*v1alpha1.JupyterKernel
+		candidates []Candidate
+		expected   map[string][]string
+	}{
+		{
+			name:    "fits without preemption",
+			nodes:   []*queue.Node{newNode("a", "2")},
+			pending: []*v1alpha1.JupyterKernel{newKernel("p", v1alpha1.KernelPriorityInteractive, "1")},
+			candidates: []Candidate{
+				candidate("v", v1alpha1.KernelPriorityBatch, "1", "a", time.Hour),
+			},
+			expected: map[string][]string{},
+		},
+		{
+			name:    "lowest priority first",
+			nodes:   []*queue.Node{full("a", "2")},
+			pending: []*v1alpha1.JupyterKernel{newKernel("p", v1alpha1.KernelPriorityInteractive, "1")},
+			candidates: []Candidate{
+				candidate("batch", v1alpha1.KernelPriorityBatch, "1", "a", 2*time.Hour),
+				candidate("best-effort", v1alpha1.KernelPriorityBestEffort, "1", "a", time.Hour),
+			},
+			expected: map[string][]string{"p": {"best-effort"}},
+		},
+		{
+			name:    "longest idle first",
+			nodes:   []*queue.Node{full("a", "2")},
+			pending: []*v1alpha1.JupyterKernel{newKernel("p", v1alpha1.KernelPriorityInteractive, "1")},
+			candidates: []Candidate{
+				candidate("recent", v1alpha1.KernelPriorityBatch, "1", "a", time.Hour),
+				candidate("oldest", v1alpha1.KernelPriorityBatch, "1", "a", 2*time.Hour),
+			},
+			expected: map[string][]string{"p": {"oldest"}},
+		},
+		{
+			name:    "same or higher priority",
+			nodes:   []*queue.Node{full("a", "2")},
+			pending: []*v1alpha1.JupyterKernel{newKernel("p", v1alpha1.KernelPriorityBatch, "1")},
+			candidates: []Candidate{
+				candidate("batch", v1alpha1.KernelPriorityBatch, "1", "a", time.Hour),
+				candidate("interactive", v1alpha1.KernelPriorityInteractive, "1", "a", time.Hour),
+			},
+			expected: map[string][]string{},
+		},
+		{
+			name:    "fewest victims",
+			nodes:   []*queue.Node{full("a", "2"), full("b", "2")},
+			pending: []*v1alpha1.JupyterKernel{newKernel("p", v1alpha1.KernelPriorityInteractive, "2")},
+			candidates: []Candidate{
+				candidate("a-1", v1alpha1.KernelPriorityBatch, "1", "a", 2*time.Hour),
+				candidate("a-2", v1alpha1.KernelPriorityBatch, "1", "a", 2*time.Hour),
+				candidate("b-1", v1alpha1.KernelPriorityBatch, "2", "b", time.Hour),
+			},
+			expected: map[string][]string{"p": {"b-1"}},
+		},
+		{
+			name:  "victims are not shared",
+			nodes: []*queue.Node{full("a", "2")},
+			pending: []*v1alpha1.JupyterKernel{
+				newKernel("p-1", v1alpha1.KernelPriorityInteractive, "1"),
+				newKernel("p-2", v1alpha1.KernelPriorityInteractive, "1"),
+				newKernel("p-3", v1alpha1.KernelPriorityInteractive, "1"),
+			},
+			candidates: []Candidate{
+				candidate("v-1", v1alpha1.KernelPriorityBatch, "1", "a", 2*time.Hour),
+				candidate("v-2", v1alpha1.KernelPriorityBatch, "1", "a", time.Hour),
+			},
+			expected: map[string][]string{"p-1": {"v-1"}, "p-2": {"v-2"}},
+		},
+	}
+	for _, test := range tests {
+		s := &queue.Snapshot{
+			Nodes:           test.nodes,
+			PriorityClasses: classes,
+			PriorityTiers:   kernel.DefaultPriorityClasses(),
+		}
+		actual := map[string][]string{}
+		for _, p := range SelectVictims(s, test.pending, test.candidates) {
+			for _, v := range p.Victims {
+				actual[p.Preemptor.Name] = append(actual[p.Preemptor.Name], v.Name)
+			}
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestPreempt(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode([]gateway.Kernel{
+			{ID: "idle", ExecutionState: gateway.ExecutionStateIdle, LastActivity: now.Add(-time.Hour)},
+			{ID: "busy", ExecutionState: "busy", LastActivity: now.Add(-2 * time.Hour)},
+		})
+	}))
+	defer server.Close()
+
+	gw := &v1alpha1.JupyterGateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway"},
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:  resource.MustParse("2"),
+				v1.ResourcePods: resource.MustParse("110"),
+			},
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	}
+	objects := []runtime.Object{gw, node}
+	for name, c := range classes {
+		objects = append(objects, &schedulingv1.PriorityClass{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Value:      c,
+		})
+	}
+	for _, name := range []string{"idle", "busy"} {
+		k := newKernel(name, v1alpha1.KernelPriorityBatch, "1")
+		k.Labels = launcher.GatewayLabels(gw)
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels:    map[string]string{kernel.LabelKernel: name},
+			},
+			Spec:   *k.Spec.Template.Spec.DeepCopy(),
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		}
+		pod.Spec.NodeName = "node"
+		objects = append(objects, k, pod)
+	}
+	pending := newKernel("pending", v1alpha1.KernelPriorityInteractive, "1")
+	objects = append(objects, pending, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "pending",
+			Labels:    map[string]string{kernel.LabelKernel: "pending"},
+		},
+		Spec: *pending.Spec.Template.Spec.DeepCopy(),
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable},
+			},
+		},
+	})
+
+	cli := fake.NewFakeClientWithScheme(s, objects...)
+	recorder := record.NewFakeRecorder(10)
+	c := NewController(cli, cli, logr.Logger(logf.NullLogger{}), recorder,
+		Options{
+			Interval:        time.Minute,
+			MinIdleTime:     5 * time.Minute,
+			PriorityClasses: kernel.DefaultPriorityClasses(),
+		})
+	c.url = func(*v1alpha1.JupyterGateway) string { return server.URL }
+	c.Preempt()
+
+	get := func(name string) *v1alpha1.JupyterKernel {
+		k := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: name}, k); err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	idle := get("idle")
+	if !kernel.Preempted(idle) || idle.Status.Preemption.Preemptor != "default/pending" {
+		t.Errorf("Expected the idle kernel to be preempted, got %v", idle.Status)
+	}
+	if busy := get("busy"); kernel.Preempted(busy) {
+		t.Errorf("Expected the busy kernel not to be preempted")
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(recorder.Events))
+	}
+
+	// The pod of the preempted kernel is treated as released, thus no more
+	// kernel is preempted.
+	c.Preempt()
+	if len(recorder.Events) != 2 {
+		t.Errorf("Expected no more preemption, got %d events", len(recorder.Events))
+	}
+}
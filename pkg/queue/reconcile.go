@@ -0,0 +1,86 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package queue
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+// Reconciler admits the queued kernels of the whole cluster.
+type Reconciler struct {
+	cli      client.Client
+	reader   client.Reader
+	log      logr.Logger
+	recorder record.EventRecorder
+	tiers    map[v1alpha1.KernelPriorityTier]string
+}
+
+// NewReconciler creates the reconciler of the queue. The reader reads the
+// pods of the whole cluster without the cache, and tiers maps the priority
+// tiers of the kernels to the PriorityClasses.
+func NewReconciler(cli client.Client, reader client.Reader, l logr.Logger,
+	r record.EventRecorder, tiers map[v1alpha1.KernelPriorityTier]string) *Reconciler {
+	return &Reconciler{
+		cli:      cli,
+		reader:   reader,
+		log:      l,
+		recorder: r,
+		tiers:    tiers,
+	}
+}
+
+// Reconcile admits the queued kernels which fit into the cluster, and
+// updates the positions of the others in the queue.
+func (r Reconciler) Reconcile() error {
+	s, err := NewSnapshot(r.cli, r.reader, r.tiers)
+	if err != nil {
+		r.log.Error(err, "Failed to get the snapshot of the cluster")
+		return err
+	}
+	result := Schedule(s)
+
+	for _, k := range result.Admitted {
+		r.log.Info("Admitting kernel", "namespace", k.Namespace, "name", k.Name)
+		kernel.Admit(k)
+		if err := r.cli.Status().Update(context.TODO(), k); err != nil {
+			r.log.Error(err, "Failed to update the status of the kernel")
+			return err
+		}
+		r.recorder.Event(k, v1.EventTypeNormal, kernel.ReasonAdmitted,
+			"The kernel is admitted by the queue")
+	}
+	for i, k := range result.Waiting {
+		position := int32(i + 1)
+		if k.Status.QueuePosition != nil && *k.Status.QueuePosition == position {
+			continue
+		}
+		kernel.SetQueuePosition(k, position)
+		if err := r.cli.Status().Update(context.TODO(), k); err != nil {
+			r.log.Error(err, "Failed to update the status of the kernel")
+			return err
+		}
+	}
+	return nil
+}
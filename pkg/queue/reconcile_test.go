@@ -0,0 +1,129 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+func queued(k *v1alpha1.JupyterKernel) *v1alpha1.JupyterKernel {
+	k.Status.Conditions = []v1alpha1.JupyterKernelCondition{
+		{
+			Type:   v1alpha1.JupyterKernelQueued,
+			Status: v1.ConditionTrue,
+			Reason: kernel.ReasonWaiting,
+		},
+	}
+	return k
+}
+
+func TestReconcile(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:  resource.MustParse("4"),
+				v1.ResourcePods: resource.MustParse("110"),
+			},
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	}
+	notReady := node.DeepCopy()
+	notReady.Name = "not-ready"
+	notReady.Status.Conditions[0].Status = v1.ConditionFalse
+	// The pod of another workload uses 2 cpus on the node.
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"},
+		Spec: v1.PodSpec{
+			NodeName: "node",
+			Containers: []v1.Container{
+				{
+					Name: "app",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+					},
+				},
+			},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+
+	cli := fake.NewFakeClientWithScheme(s, node, notReady, pod,
+		queued(newKernel("default", "first", "alice", "2", 1)),
+		queued(newKernel("default", "second", "alice", "1", 2)),
+		queued(newKernel("default", "third", "alice", "1", 3)))
+	recorder := record.NewFakeRecorder(10)
+	r := NewReconciler(cli, cli, logr.Logger(logf.NullLogger{}), recorder,
+		kernel.DefaultPriorityClasses())
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(name string) *v1alpha1.JupyterKernel {
+		k := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: "default", Name: name}, k); err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	if k := get("first"); !kernel.Admitted(k) || k.Status.QueuePosition != nil {
+		t.Errorf("Expected the first kernel to be admitted, got %v", k.Status)
+	}
+	for name, position := range map[string]int32{"second": 1, "third": 2} {
+		k := get(name)
+		if !kernel.Queued(k) || k.Status.QueuePosition == nil ||
+			*k.Status.QueuePosition != position {
+			t.Errorf("Expected %s at position %d, got %v", name, position, k.Status)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(recorder.Events))
+	}
+
+	// The admitted kernel is reserved until its pod is scheduled, thus the
+	// others keep waiting.
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if k := get("second"); !kernel.Queued(k) {
+		t.Errorf("Expected the second kernel to wait for the reserved resources")
+	}
+}
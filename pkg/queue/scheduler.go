@@ -0,0 +1,307 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package queue admits the queued kernels in the priority and fair-share
+// order, when the free capacity of the cluster is enough for them.
+package queue
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+// Node is a schedulable node in the snapshot.
+type Node struct {
+	Name   string
+	Labels map[string]string
+	Taints []v1.Taint
+	// Allocatable is the allocatable resources of the node.
+	Allocatable v1.ResourceList
+	// Free is the allocatable resources which are not requested by the
+	// pods on the node.
+	Free v1.ResourceList
+}
+
+// Snapshot is the state of the cluster, from which the queued kernels are
+// admitted.
+type Snapshot struct {
+	Nodes []*Node
+	// Queued are the kernels which wait in the queue.
+	Queued []*v1alpha1.JupyterKernel
+	// Active are the kernels which are not queued, whose usage is counted
+	// in the fair share.
+	Active []*v1alpha1.JupyterKernel
+	// Reserved are the admitted kernels whose pods are not scheduled yet.
+	// Their requests are not counted in the free resources of the nodes.
+	Reserved []*v1alpha1.JupyterKernel
+	// Unschedulable are the active kernels whose pods cannot be scheduled.
+	Unschedulable []*v1alpha1.JupyterKernel
+	// KernelNodes are the nodes of the scheduled kernel pods, keyed by
+	// <namespace>/<name> of the kernels.
+	KernelNodes map[string]string
+	// PriorityClasses are the values of the PriorityClasses by the names.
+	PriorityClasses map[string]int32
+	// PriorityTiers maps the priority tiers of the kernels to the
+	// PriorityClasses.
+	PriorityTiers map[v1alpha1.KernelPriorityTier]string
+}
+
+// Result is the decision of the scheduler.
+type Result struct {
+	// Admitted are the kernels which are admitted.
+	Admitted []*v1alpha1.JupyterKernel
+	// Waiting are the kernels which are not admitted, in the order of the
+	// queue.
+	Waiting []*v1alpha1.JupyterKernel
+}
+
+// Schedule admits the queued kernels which fit into the free resources of
+// the nodes. The kernels are ordered by the priority first. The kernels
+// with the same priority are ordered by the dominant resource share of
+// their namespaces, and then of their notebooks, thus the namespaces and
+// the notebooks using fewer resources go first. The order is updated after every
+// kernel is admitted. A kernel which does not fit does not block the
+// kernels after it.
+func Schedule(s *Snapshot) Result {
+	nodes := make([]*Node, 0, len(s.Nodes))
+	for _, n := range s.Nodes {
+		c := *n
+		c.Free = n.Free.DeepCopy()
+		nodes = append(nodes, &c)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, k := range s.Reserved {
+		place(nodes, k)
+	}
+
+	capacity := v1.ResourceList{}
+	for _, n := range nodes {
+		for name, q := range n.Allocatable {
+			addQuantity(capacity, name, q)
+		}
+	}
+	usage := newUsage(capacity)
+	for _, k := range s.Active {
+		usage.add(k)
+	}
+
+	pending := append([]*v1alpha1.JupyterKernel(nil), s.Queued...)
+	result := Result{}
+	for len(pending) != 0 {
+		sortQueue(pending, usage, s)
+		admitted := -1
+		for i, k := range pending {
+			if place(nodes, k) {
+				admitted = i
+				break
+			}
+		}
+		if admitted < 0 {
+			break
+		}
+		k := pending[admitted]
+		result.Admitted = append(result.Admitted, k)
+		usage.add(k)
+		pending = append(pending[:admitted], pending[admitted+1:]...)
+	}
+	result.Waiting = pending
+	return result
+}
+
+// Priority returns the priority of the kernel pod, which is set in the pod
+// template, or by the PriorityClass of the pod template or the priority
+// tier.
+func Priority(k *v1alpha1.JupyterKernel, s *Snapshot) int32 {
+	if p := k.Spec.Template.Spec.Priority; p != nil {
+		return *p
+	}
+	return s.PriorityClasses[kernel.PriorityClassName(k, s.PriorityTiers)]
+}
+
+func sortQueue(kernels []*v1alpha1.JupyterKernel, u *usage, s *Snapshot) {
+	sort.SliceStable(kernels, func(i, j int) bool {
+		a, b := kernels[i], kernels[j]
+		if pa, pb := Priority(a, s), Priority(b, s); pa != pb {
+			return pa > pb
+		}
+		if sa, sb := u.namespaceShare(a), u.namespaceShare(b); sa != sb {
+			return sa < sb
+		}
+		if sa, sb := u.notebookShare(a), u.notebookShare(b); sa != sb {
+			return sa < sb
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+}
+
+// place reserves the requests of each pod of the kernel on the first node
+// which fits. The pods of a gang are placed all or nothing.
+func place(nodes []*Node, k *v1alpha1.JupyterKernel) bool {
+	free := make([]v1.ResourceList, len(nodes))
+	for i, n := range nodes {
+		free[i] = n.Free.DeepCopy()
+	}
+	for _, spec := range kernel.PodSpecs(k) {
+		requests := kernel.PodRequests(spec)
+		requests[v1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+		placed := false
+		for i, n := range nodes {
+			if !Schedulable(n, spec) || !Fits(free[i], requests) {
+				continue
+			}
+			for name, q := range requests {
+				f := free[i][name]
+				f.Sub(q)
+				free[i][name] = f
+			}
+			placed = true
+			break
+		}
+		if !placed {
+			return false
+		}
+	}
+	for i, n := range nodes {
+		n.Free = free[i]
+	}
+	return true
+}
+
+// Requests returns the requests of the kernel, including the pods
+// themselves.
+func Requests(k *v1alpha1.JupyterKernel) v1.ResourceList {
+	r := kernel.Requests(k)
+	r[v1.ResourcePods] = *resource.NewQuantity(int64(len(kernel.PodSpecs(k))), resource.DecimalSI)
+	return r
+}
+
+// Fits returns true if the free resources are enough for the requests.
+func Fits(free, requests v1.ResourceList) bool {
+	for name, q := range requests {
+		if q.IsZero() {
+			continue
+		}
+		f, ok := free[name]
+		if !ok || f.Cmp(q) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Schedulable checks the node selector and the taints of the node.
+func Schedulable(n *Node, spec *v1.PodSpec) bool {
+	for k, v := range spec.NodeSelector {
+		if n.Labels[k] != v {
+			return false
+		}
+	}
+	for i := range n.Taints {
+		taint := &n.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range spec.Tolerations {
+			if spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// usage is the resources requested by the active kernels per namespace
+// and per notebook.
+type usage struct {
+	capacity   v1.ResourceList
+	namespaces map[string]v1.ResourceList
+	notebooks  map[string]v1.ResourceList
+}
+
+func newUsage(capacity v1.ResourceList) *usage {
+	return &usage{
+		capacity:   capacity,
+		namespaces: map[string]v1.ResourceList{},
+		notebooks:  map[string]v1.ResourceList{},
+	}
+}
+
+func (u *usage) add(k *v1alpha1.JupyterKernel) {
+	requests := kernel.Requests(k)
+	add := func(m map[string]v1.ResourceList, key string) {
+		if m[key] == nil {
+			m[key] = v1.ResourceList{}
+		}
+		for name, q := range requests {
+			addQuantity(m[key], name, q)
+		}
+	}
+	add(u.namespaces, k.Namespace)
+	add(u.notebooks, notebookKey(k))
+}
+
+func (u *usage) namespaceShare(k *v1alpha1.JupyterKernel) float64 {
+	return dominantShare(u.namespaces[k.Namespace], u.capacity)
+}
+
+func (u *usage) notebookShare(k *v1alpha1.JupyterKernel) float64 {
+	return dominantShare(u.notebooks[notebookKey(k)], u.capacity)
+}
+
+// notebookKey identifies the notebook which launches the kernel in the
+// namespace. The label is set by the launcher after the token of the
+// notebook is verified, while KERNEL_USERNAME is set by the client and
+// cannot be trusted. The kernels which are not launched by the notebooks
+// share the same key in the namespace.
+func notebookKey(k *v1alpha1.JupyterKernel) string {
+	return k.Namespace + "/" + k.Labels[launcher.LabelNotebook]
+}
+
+// dominantShare returns the maximum share of the used resources in the
+// capacity of the cluster.
+func dominantShare(used, capacity v1.ResourceList) float64 {
+	share := 0.0
+	for name, q := range used {
+		c, ok := capacity[name]
+		if !ok || c.IsZero() {
+			continue
+		}
+		if s := float64(q.MilliValue()) / float64(c.MilliValue()); s > share {
+			share = s
+		}
+	}
+	return share
+}
+
+func addQuantity(l v1.ResourceList, name v1.ResourceName, q resource.Quantity) {
+	total := l[name].DeepCopy()
+	total.Add(q)
+	l[name] = total
+}
@@ -0,0 +1,225 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package queue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+var created = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newNode(name, cpu string) *Node {
+	l := v1.ResourceList{
+		v1.ResourceCPU:  resource.MustParse(cpu),
+		v1.ResourcePods: resource.MustParse("110"),
+	}
+	return &Node{Name: name, Allocatable: l, Free: l.DeepCopy()}
+}
+
+// newKernel creates the kernel of the notebook in the namespace, which is
+// created after the given minutes.
+func newKernel(namespace, name, notebook, cpu string, minute int) *v1alpha1.JupyterKernel {
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			Labels:            map[string]string{launcher.LabelNotebook: notebook},
+			CreationTimestamp: metav1.NewTime(created.Add(time.Duration(minute) * time.Minute)),
+		},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "kernel",
+							Resources: v1.ResourceRequirements{
+								Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func names(kernels []*v1alpha1.JupyterKernel) []string {
+	n := []string{}
+	for _, k := range kernels {
+		n = append(n, k.Name)
+	}
+	return n
+}
+
+func TestSchedule(t *testing.T) {
+	withClass := func(k *v1alpha1.JupyterKernel, class string) *v1alpha1.JupyterKernel {
+		k.Spec.Template.Spec.PriorityClassName = class
+		return k
+	}
+	withSelector := func(k *v1alpha1.JupyterKernel) *v1alpha1.JupyterKernel {
+		k.Spec.Template.Spec.NodeSelector = map[string]string{"gpu": "true"}
+		k.Spec.Template.Spec.Tolerations = []v1.Toleration{
+			{Key: "gpu", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		}
+		return k
+	}
+	withWorkers := func(k *v1alpha1.JupyterKernel, n int32, cpu string) *v1alpha1.JupyterKernel {
+		worker := newKernel(k.Namespace, "worker", "", cpu, 0).Spec.Template
+		k.Spec.ReplicaGroups = []v1alpha1.KernelReplicaGroup{
+			{Name: "worker", Replicas: &n, Template: worker},
+		}
+		return k
+	}
+	gpuNode := newNode("gpu", "4")
+	gpuNode.Labels = map[string]string{"gpu": "true"}
+	gpuNode.Taints = []v1.Taint{{Key: "gpu", Effect: v1.TaintEffectNoSchedule}}
+
+	tests := []struct {
+		name     string
+		snapshot *Snapshot
+		admitted []string
+		waiting  []string
+	}{
+		{
+			name: "creation order",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "2")},
+				Queued: []*v1alpha1.JupyterKernel{
+					newKernel("ns", "second", "alice", "1", 2),
+					newKernel("ns", "first", "alice", "1", 1),
+					newKernel("ns", "third", "alice", "1", 3),
+				},
+			},
+			admitted: []string{"first", "second"},
+			waiting:  []string{"third"},
+		},
+		{
+			name: "priority",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "1")},
+				Queued: []*v1alpha1.JupyterKernel{
+					newKernel("ns", "low", "alice", "1", 1),
+					withClass(newKernel("ns", "high", "bob", "1", 2), "interactive"),
+				},
+				PriorityClasses: map[string]int32{"interactive": 1000},
+			},
+			admitted: []string{"high"},
+			waiting:  []string{"low"},
+		},
+		{
+			name: "namespace share",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "4")},
+				Active: []*v1alpha1.JupyterKernel{
+					newKernel("busy", "running", "alice", "2", 0),
+				},
+				Queued: []*v1alpha1.JupyterKernel{
+					newKernel("busy", "busy-kernel", "alice", "1", 1),
+					newKernel("idle", "idle-kernel", "bob", "1", 2),
+				},
+				Reserved: []*v1alpha1.JupyterKernel{
+					newKernel("busy", "running", "alice", "2", 0),
+				},
+			},
+			admitted: []string{"idle-kernel", "busy-kernel"},
+			waiting:  []string{},
+		},
+		{
+			name: "notebook share",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "3")},
+				Queued: []*v1alpha1.JupyterKernel{
+					newKernel("ns", "alice-1", "alice", "1", 1),
+					newKernel("ns", "alice-2", "alice", "1", 2),
+					newKernel("ns", "bob-1", "bob", "1", 3),
+					newKernel("ns", "bob-2", "bob", "1", 4),
+				},
+			},
+			admitted: []string{"alice-1", "bob-1", "alice-2"},
+			waiting:  []string{"bob-2"},
+		},
+		{
+			name: "backfill",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "2"), newNode("b", "2")},
+				Queued: []*v1alpha1.JupyterKernel{
+					newKernel("ns", "large", "alice", "3", 1),
+					newKernel("ns", "small", "alice", "2", 2),
+				},
+			},
+			admitted: []string{"small"},
+			waiting:  []string{"large"},
+		},
+		{
+			name: "node selector and taint",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "4"), gpuNode},
+				Queued: []*v1alpha1.JupyterKernel{
+					withSelector(newKernel("ns", "gpu", "alice", "4", 1)),
+					newKernel("ns", "cpu-1", "bob", "4", 2),
+					newKernel("ns", "cpu-2", "bob", "4", 3),
+				},
+			},
+			admitted: []string{"gpu", "cpu-1"},
+			waiting:  []string{"cpu-2"},
+		},
+		{
+			// The gang fits into the total free resources, but not the
+			// pods on the nodes.
+			name: "gang",
+			snapshot: &Snapshot{
+				Nodes: []*Node{newNode("a", "3"), newNode("b", "3")},
+				Queued: []*v1alpha1.JupyterKernel{
+					withWorkers(newKernel("ns", "gang", "alice", "1", 1), 2, "2500m"),
+					newKernel("ns", "small", "bob", "3", 2),
+				},
+			},
+			admitted: []string{"small"},
+			waiting:  []string{"gang"},
+		},
+	}
+	for _, test := range tests {
+		result := Schedule(test.snapshot)
+		if got := names(result.Admitted); !reflect.DeepEqual(got, test.admitted) {
+			t.Errorf("%s: Expected admitted %v, got %v", test.name, test.admitted, got)
+		}
+		if got := names(result.Waiting); !reflect.DeepEqual(got, test.waiting) {
+			t.Errorf("%s: Expected waiting %v, got %v", test.name, test.waiting, got)
+		}
+	}
+}
+
+func TestScheduleKeepsSnapshot(t *testing.T) {
+	s := &Snapshot{
+		Nodes:  []*Node{newNode("a", "1")},
+		Queued: []*v1alpha1.JupyterKernel{newKernel("ns", "k", "alice", "1", 1)},
+	}
+	Schedule(s)
+	if free := s.Nodes[0].Free[v1.ResourceCPU]; free.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("Expected the free resources of the snapshot not to be changed, got %s",
+			free.String())
+	}
+}
@@ -0,0 +1,172 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package queue
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+// NewSnapshot gets the snapshot of the cluster. The unschedulable and not
+// ready nodes are ignored, and so are the preempted kernels and their pods.
+// The pods are read by the reader, since all the pods on the nodes are
+// required while the cache of the operator only has the managed pods.
+// tiers maps the priority tiers of the kernels to the PriorityClasses.
+func NewSnapshot(cli client.Client, reader client.Reader,
+	tiers map[v1alpha1.KernelPriorityTier]string) (*Snapshot, error) {
+	s := &Snapshot{
+		KernelNodes:     map[string]string{},
+		PriorityClasses: map[string]int32{},
+		PriorityTiers:   tiers,
+	}
+
+	nodes := &v1.NodeList{}
+	if err := cli.List(context.TODO(), nodes); err != nil {
+		return nil, err
+	}
+	byName := map[string]*Node{}
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		if n.Spec.Unschedulable || !ready(n) {
+			continue
+		}
+		node := &Node{
+			Name:        n.Name,
+			Labels:      n.Labels,
+			Taints:      n.Spec.Taints,
+			Allocatable: n.Status.Allocatable,
+			Free:        n.Status.Allocatable.DeepCopy(),
+		}
+		s.Nodes = append(s.Nodes, node)
+		byName[n.Name] = node
+	}
+
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := cli.List(context.TODO(), kernels); err != nil {
+		return nil, err
+	}
+	// preempted are the kernels whose pods are being deleted, which are
+	// treated as released.
+	preempted := map[string]bool{}
+	for i := range kernels.Items {
+		if k := &kernels.Items[i]; kernel.Preempted(k) {
+			preempted[Key(k)] = true
+		}
+	}
+
+	pods := &v1.PodList{}
+	if err := reader.List(context.TODO(), pods); err != nil {
+		return nil, err
+	}
+	unschedulable := map[string]bool{}
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		// The pods of the replica groups only have the gang label.
+		key := ""
+		if name, ok := p.Labels[kernel.LabelGang]; ok {
+			key = p.Namespace + "/" + name
+		}
+		if name, ok := p.Labels[kernel.LabelKernel]; ok {
+			key = p.Namespace + "/" + name
+		}
+		if preempted[key] {
+			continue
+		}
+		if p.Spec.NodeName == "" {
+			if key != "" && podUnschedulable(p) {
+				unschedulable[key] = true
+			}
+			continue
+		}
+		if _, ok := p.Labels[kernel.LabelKernel]; ok {
+			s.KernelNodes[key] = p.Spec.NodeName
+		}
+		node, ok := byName[p.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		requests := kernel.PodRequests(&p.Spec)
+		requests[v1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+		for name, q := range requests {
+			free := node.Free[name]
+			free.Sub(q)
+			node.Free[name] = free
+		}
+	}
+
+	for i := range kernels.Items {
+		k := &kernels.Items[i]
+		if k.DeletionTimestamp != nil || preempted[Key(k)] {
+			continue
+		}
+		switch {
+		case kernel.Queued(k):
+			s.Queued = append(s.Queued, k)
+		case kernel.Admitted(k) && s.KernelNodes[Key(k)] == "":
+			s.Reserved = append(s.Reserved, k)
+			s.Active = append(s.Active, k)
+		default:
+			s.Active = append(s.Active, k)
+		}
+		if unschedulable[Key(k)] {
+			s.Unschedulable = append(s.Unschedulable, k)
+		}
+	}
+
+	classes := &schedulingv1.PriorityClassList{}
+	if err := cli.List(context.TODO(), classes); err != nil {
+		return nil, err
+	}
+	for _, c := range classes.Items {
+		s.PriorityClasses[c.Name] = c.Value
+	}
+	return s, nil
+}
+
+// Key returns <namespace>/<name> of the kernel.
+func Key(k *v1alpha1.JupyterKernel) string {
+	return k.Namespace + "/" + k.Name
+}
+
+// podUnschedulable returns true if the scheduler fails to schedule the pod.
+func podUnschedulable(p *v1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == v1.PodScheduled {
+			return c.Status == v1.ConditionFalse && c.Reason == v1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
+
+func ready(n *v1.Node) bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package quota enforces the JupyterKernelQuotas, which limit the number
+// and the resources of the kernels in the namespace and per kernel spec.
+package quota
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+// Usage returns the usage of the quota by the kernels in the namespace.
+// The kernels which are being deleted are not counted.
+func Usage(q *v1alpha1.JupyterKernelQuota,
+	kernels []v1alpha1.JupyterKernel) v1alpha1.JupyterKernelQuotaStatus {
+	status := v1alpha1.JupyterKernelQuotaStatus{}
+	if q.Spec.Hard != nil {
+		status.Used = &v1alpha1.KernelQuotaUsage{}
+	}
+	if len(q.Spec.KernelSpecs) != 0 {
+		status.KernelSpecs = map[string]v1alpha1.KernelQuotaUsage{}
+	}
+
+	for i := range kernels {
+		k := &kernels[i]
+		if k.DeletionTimestamp != nil {
+			continue
+		}
+		if status.Used != nil {
+			add(status.Used, k)
+		}
+		if spec := kernel.Env(k, kernel.EnvKernelName); status.KernelSpecs != nil {
+			if _, ok := q.Spec.KernelSpecs[spec]; ok {
+				u := status.KernelSpecs[spec]
+				add(&u, k)
+				status.KernelSpecs[spec] = u
+			}
+		}
+	}
+	return status
+}
+
+// Check returns an error if the kernel exceeds the quota together with
+// the existing kernels in the namespace. Only the limits which apply to
+// the kernel are checked.
+func Check(q *v1alpha1.JupyterKernelQuota,
+	kernels []v1alpha1.JupyterKernel, k *v1alpha1.JupyterKernel) error {
+	usage := Usage(q, kernels)
+	if l := q.Spec.Hard; l != nil {
+		if err := check(l, *usage.Used, k); err != nil {
+			return fmt.Errorf("exceeded quota %s for the namespace: %v", q.Name, err)
+		}
+	}
+	spec := kernel.Env(k, kernel.EnvKernelName)
+	if l, ok := q.Spec.KernelSpecs[spec]; ok {
+		if err := check(&l, usage.KernelSpecs[spec], k); err != nil {
+			return fmt.Errorf("exceeded quota %s for the kernel spec %q: %v", q.Name, spec, err)
+		}
+	}
+	return nil
+}
+
+func check(l *v1alpha1.KernelQuotaLimits, used v1alpha1.KernelQuotaUsage,
+	k *v1alpha1.JupyterKernel) error {
+	if l.Kernels != nil && used.Kernels+1 > *l.Kernels {
+		return fmt.Errorf("kernels: used %d, limited %d", used.Kernels, *l.Kernels)
+	}
+	requested := kernel.Requests(k)
+	for name, limit := range l.Resources {
+		r, ok := requested[name]
+		if !ok || r.IsZero() {
+			continue
+		}
+		total := used.Resources[name].DeepCopy()
+		total.Add(r)
+		if total.Cmp(limit) > 0 {
+			u := used.Resources[name]
+			return fmt.Errorf("%s: requested %s, used %s, limited %s",
+				name, r.String(), u.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+func add(u *v1alpha1.KernelQuotaUsage, k *v1alpha1.JupyterKernel) {
+	u.Kernels++
+	for name, q := range kernel.Requests(k) {
+		if u.Resources == nil {
+			u.Resources = v1.ResourceList{}
+		}
+		addQuantity(u.Resources, name, q)
+	}
+}
+
+func addQuantity(l v1.ResourceList, name v1.ResourceName, q resource.Quantity) {
+	total := l[name].DeepCopy()
+	total.Add(q)
+	l[name] = total
+}
@@ -0,0 +1,279 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+func newKernel(name, spec, gpus string) *v1alpha1.JupyterKernel {
+	c := v1.Container{
+		Name: "kernel",
+		Env:  []v1.EnvVar{{Name: kernel.EnvKernelName, Value: spec}},
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+		},
+	}
+	if gpus != "" {
+		c.Resources.Limits = v1.ResourceList{"nvidia.com/gpu": resource.MustParse(gpus)}
+	}
+	return &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1alpha1.JupyterKernelCRDSpec{
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{c}}},
+		},
+	}
+}
+
+func newQuota() *v1alpha1.JupyterKernelQuota {
+	return &v1alpha1.JupyterKernelQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "quota"},
+		Spec: v1alpha1.JupyterKernelQuotaSpec{
+			Hard: &v1alpha1.KernelQuotaLimits{
+				Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			},
+			KernelSpecs: map[string]v1alpha1.KernelQuotaLimits{
+				"python-gpu": {
+					Resources: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+				},
+			},
+		},
+	}
+}
+
+func TestUsage(t *testing.T) {
+	deleted := newKernel("deleted", "python", "")
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	kernels := []v1alpha1.JupyterKernel{
+		*newKernel("a", "python", ""),
+		*newKernel("b", "python-gpu", "1"),
+		*newKernel("c", "python", ""),
+		*deleted,
+	}
+
+	status := Usage(newQuota(), kernels)
+	if status.Used.Kernels != 3 {
+		t.Errorf("Expected 3 kernels, got %d", status.Used.Kernels)
+	}
+	if cpu := status.Used.Resources[v1.ResourceCPU]; cpu.Cmp(resource.MustParse("1500m")) != 0 {
+		t.Errorf("Expected 1500m cpu, got %s", cpu.String())
+	}
+	if _, ok := status.KernelSpecs["python"]; ok {
+		t.Errorf("Expected the kernel spec without the limits not to be counted")
+	}
+	if gpu := status.KernelSpecs["python-gpu"].Resources["nvidia.com/gpu"]; gpu.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("Expected 1 gpu of python-gpu, got %s", gpu.String())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	kernels := []v1alpha1.JupyterKernel{
+		*newKernel("a", "python", ""),
+		*newKernel("b", "python-gpu", "1"),
+	}
+	tests := []struct {
+		name   string
+		kernel *v1alpha1.JupyterKernel
+		denied bool
+	}{
+		{name: "allowed", kernel: newKernel("c", "python-gpu", "1")},
+		{name: "kernel spec", kernel: newKernel("c", "python-gpu", "2"), denied: true},
+		{name: "namespace", kernel: func() *v1alpha1.JupyterKernel {
+			k := newKernel("c", "python", "")
+			k.Spec.Template.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("2")
+			return k
+		}(), denied: true},
+	}
+	for _, test := range tests {
+		err := Check(newQuota(), kernels, test.kernel)
+		if (err != nil) != test.denied {
+			t.Errorf("%s: Expected denied %v, got %v", test.name, test.denied, err)
+		}
+	}
+}
+
+func TestValidator(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	cli := fake.NewFakeClientWithScheme(s, newQuota(),
+		newKernel("a", "python", ""),
+		newKernel("b", "python-gpu", "1"))
+	v := NewValidator(cli, logr.Logger(logf.NullLogger{}))
+	decoder, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	// The quota of python-gpu is 2 gpus, and b requests 1 gpu.
+	for gpus, allowed := range map[string]bool{"1": true, "2": false} {
+		raw, err := json.Marshal(newKernel("c", "python-gpu", gpus))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := v.Handle(context.TODO(), admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Namespace: "default",
+				Operation: admissionv1beta1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		})
+		if resp.Allowed != allowed {
+			t.Errorf("%s: Expected allowed %v, got %v", gpus, allowed, resp.Result)
+		}
+	}
+
+	reserved := newKernel("d", "python", "")
+	reserved.Spec.ReplicaGroups = []v1alpha1.KernelReplicaGroup{{Name: kernel.DriverGroup}}
+	raw, err := json.Marshal(reserved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := v.Handle(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Namespace: "default",
+			Operation: admissionv1beta1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	})
+	if resp.Allowed {
+		t.Errorf("Expected the reserved replica group name to be denied")
+	}
+}
+
+func TestValidatorConcurrent(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	// The created kernels are not added to the fake client, as if they
+	// were not seen by the cache yet.
+	cli := fake.NewFakeClientWithScheme(s, newQuota(), newKernel("a", "python", ""))
+	v := NewValidator(cli, logr.Logger(logf.NullLogger{}))
+	decoder, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	// The quota of the namespace is 2 cpus, and every kernel requests 500m.
+	var wg sync.WaitGroup
+	var allowed int32
+	for _, name := range []string{"b", "c", "d", "e", "f"} {
+		raw, err := json.Marshal(newKernel(name, "python", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := v.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Namespace: "default",
+					Operation: admissionv1beta1.Create,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+			if resp.Allowed {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 3 {
+		t.Errorf("Expected 3 kernels to be allowed, got %d", allowed)
+	}
+}
+
+func TestValidatorResize(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	old := newKernel("a", "python", "")
+	cli := fake.NewFakeClientWithScheme(s, newQuota(), old,
+		newKernel("b", "python", ""))
+	v := NewValidator(cli, logr.Logger(logf.NullLogger{}))
+	decoder, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+	oldRaw, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The quota of the namespace is 2 cpus, and b requests 500m.
+	for cpu, allowed := range map[string]bool{"1500m": true, "2": false} {
+		k := old.DeepCopy()
+		k.Spec.Resources = &v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+		}
+		raw, err := json.Marshal(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := v.Handle(context.TODO(), admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Namespace: "default",
+				Operation: admissionv1beta1.Update,
+				Object:    runtime.RawExtension{Raw: raw},
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			},
+		})
+		if resp.Allowed != allowed {
+			t.Errorf("%s: Expected allowed %v, got %v", cpu, allowed, resp.Result)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package quota
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+// Reconciler updates the usage in the status of the quota.
+type Reconciler struct {
+	cli client.Client
+	log logr.Logger
+
+	instance *v1alpha1.JupyterKernelQuota
+}
+
+// NewReconciler creates the reconciler of the quota.
+func NewReconciler(cli client.Client, l logr.Logger,
+	i *v1alpha1.JupyterKernelQuota) *Reconciler {
+	return &Reconciler{
+		cli:      cli,
+		log:      l,
+		instance: i,
+	}
+}
+
+// Reconcile computes the usage of the quota from the kernels in the
+// namespace.
+func (r Reconciler) Reconcile() error {
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := r.cli.List(context.TODO(), kernels,
+		client.InNamespace(r.instance.Namespace)); err != nil {
+		r.log.Error(err, "Failed to list the kernels")
+		return err
+	}
+
+	status := Usage(r.instance, kernels.Items)
+	if equality.Semantic.DeepEqual(status, r.instance.Status) {
+		return nil
+	}
+	r.instance.Status = status
+	if err := r.cli.Status().Update(context.TODO(), r.instance); err != nil {
+		r.log.Error(err, "Failed to update the status of the quota")
+		return err
+	}
+	return nil
+}
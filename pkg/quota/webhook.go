@@ -0,0 +1,195 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+// WebhookPath is the path of the validating webhook of the kernels.
+const WebhookPath = "/validate-kubeflow-tkestack-io-v1alpha1-jupyterkernel"
+
+// +kubebuilder:webhook:path=/validate-kubeflow-tkestack-io-v1alpha1-jupyterkernel,mutating=false,failurePolicy=fail,sideEffects=None,admissionReviewVersions=v1;v1beta1,groups=kubeflow.tkestack.io,resources=jupyterkernels,verbs=create;update,versions=v1alpha1,name=vjupyterkernel.kubeflow.tkestack.io
+
+// ReservationPeriod is how long an admitted kernel is counted by the
+// validator itself, until the kernel is seen by the cache of the client.
+const ReservationPeriod = 30 * time.Second
+
+// Validator rejects the kernels which exceed any JupyterKernelQuota in
+// the namespace when they are created, or resized with more resources. The
+// kernels with the reserved or duplicate replica group names are rejected
+// when they are created, no matter if there is any quota.
+//
+// The kernels in the same namespace are validated one by one, and the
+// admitted kernels are reserved for ReservationPeriod, thus the concurrent
+// kernels cannot exceed the quota before they are seen by the cache.
+type Validator struct {
+	cli     client.Client
+	log     logr.Logger
+	decoder *admission.Decoder
+
+	mu         sync.Mutex
+	namespaces map[string]*namespaceReservations
+}
+
+// namespaceReservations are the admitted kernels in a namespace, keyed by
+// the names of the kernels.
+type namespaceReservations struct {
+	sync.Mutex
+	kernels map[string]reservation
+}
+
+type reservation struct {
+	kernel  v1alpha1.JupyterKernel
+	expires time.Time
+}
+
+// NewValidator creates the validator.
+func NewValidator(cli client.Client, l logr.Logger) *Validator {
+	return &Validator{
+		cli:        cli,
+		log:        l,
+		namespaces: map[string]*namespaceReservations{},
+	}
+}
+
+// Handle implements admission.Handler.
+func (v *Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	k := &v1alpha1.JupyterKernel{}
+	if err := v.decoder.Decode(req, k); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if k.Namespace == "" {
+		k.Namespace = req.Namespace
+	}
+	if req.Operation == admissionv1beta1.Create {
+		if err := kernel.ValidateReplicaGroups(k.Spec.ReplicaGroups); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	if req.Operation == admissionv1beta1.Update {
+		old := &v1alpha1.JupyterKernel{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if !grows(kernel.Requests(old), kernel.Requests(k)) {
+			return admission.Allowed("")
+		}
+	}
+
+	reservations := v.reservations(k.Namespace)
+	reservations.Lock()
+	defer reservations.Unlock()
+
+	quotas := &v1alpha1.JupyterKernelQuotaList{}
+	if err := v.cli.List(ctx, quotas, client.InNamespace(k.Namespace)); err != nil {
+		v.log.Error(err, "Failed to list the quotas", "namespace", k.Namespace)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(quotas.Items) == 0 {
+		return admission.Allowed("")
+	}
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := v.cli.List(ctx, kernels, client.InNamespace(k.Namespace)); err != nil {
+		v.log.Error(err, "Failed to list the kernels", "namespace", k.Namespace)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	others := reservations.merge(kernels.Items, k.Name, time.Now())
+	for i := range quotas.Items {
+		if err := Check(&quotas.Items[i], others, k); err != nil {
+			v.log.Info("Rejected kernel", "namespace", k.Namespace,
+				"kernel", k.Name, "reason", err.Error())
+			return admission.Denied(err.Error())
+		}
+	}
+	if req.DryRun == nil || !*req.DryRun {
+		reservations.kernels[k.Name] = reservation{
+			kernel:  *k,
+			expires: time.Now().Add(ReservationPeriod),
+		}
+	}
+	return admission.Allowed("")
+}
+
+// reservations returns the reservations of the namespace.
+func (v *Validator) reservations(namespace string) *namespaceReservations {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	r, ok := v.namespaces[namespace]
+	if !ok {
+		r = &namespaceReservations{kernels: map[string]reservation{}}
+		v.namespaces[namespace] = r
+	}
+	return r
+}
+
+// merge returns the kernels in the cache and the reserved kernels, except
+// the kernel itself which is validated. A reserved kernel replaces the
+// kernel with the same name in the cache, which may be out of date. The
+// expired reservations are removed.
+func (r *namespaceReservations) merge(cached []v1alpha1.JupyterKernel,
+	name string, now time.Time) []v1alpha1.JupyterKernel {
+	for n, reserved := range r.kernels {
+		if now.After(reserved.expires) {
+			delete(r.kernels, n)
+		}
+	}
+	kernels := make([]v1alpha1.JupyterKernel, 0, len(cached)+len(r.kernels))
+	for _, k := range cached {
+		if _, ok := r.kernels[k.Name]; !ok && k.Name != name {
+			kernels = append(kernels, k)
+		}
+	}
+	for n, reserved := range r.kernels {
+		if n != name {
+			kernels = append(kernels, reserved.kernel)
+		}
+	}
+	return kernels
+}
+
+// grows returns true if any resource in the new requests is more than the
+// old requests.
+func grows(old, new v1.ResourceList) bool {
+	for name, q := range new {
+		if o, ok := old[name]; !ok || q.Cmp(o) > 0 {
+			if !q.IsZero() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *Validator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,126 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recommender
+
+import (
+	"math"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+)
+
+const (
+	// targetPercentile is the percentile of the peak usages, which is
+	// recommended as the requests.
+	targetPercentile = 0.9
+	// safetyMargin is added to the recommended resources.
+	safetyMargin = 0.15
+
+	mebibyte = 1024 * 1024
+)
+
+// resources are the resources recorded and recommended.
+var resources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+
+// Recommend returns the recommended resources from the peak usages of the
+// finished kernels, which is nil if there are fewer than minSamples. The
+// requests are the 90th percentile of the peaks, and the memory limit is
+// the maximum peak, both with a 15% safety margin. The CPU is not limited
+// to avoid throttling the kernels.
+func Recommend(peaks []v1alpha1.KernelPeakUsage, minSamples int) *v1alpha1.KernelResourceRecommendation {
+	if len(peaks) == 0 || len(peaks) < minSamples {
+		return nil
+	}
+	r := &v1alpha1.KernelResourceRecommendation{
+		Requests: v1.ResourceList{},
+		Limits:   v1.ResourceList{},
+		Samples:  int32(len(peaks)),
+	}
+	for _, name := range resources {
+		values := []int64{}
+		for _, p := range peaks {
+			if q, ok := p.Usage[name]; ok {
+				values = append(values, q.MilliValue())
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		target := values[int(math.Ceil(targetPercentile*float64(len(values))))-1]
+		r.Requests[name] = quantity(name, target)
+		if name == v1.ResourceMemory {
+			r.Limits[name] = quantity(name, values[len(values)-1])
+		}
+	}
+	return r
+}
+
+// quantity adds the safety margin to the milli value, and rounds it up to
+// millicores or mebibytes.
+func quantity(name v1.ResourceName, milli int64) resource.Quantity {
+	v := float64(milli) * (1 + safetyMargin)
+	if name == v1.ResourceMemory {
+		mi := int64(math.Ceil(v / 1000 / mebibyte))
+		return *resource.NewQuantity(mi*mebibyte, resource.BinarySI)
+	}
+	return *resource.NewMilliQuantity(int64(math.Ceil(v)), resource.DecimalSI)
+}
+
+// Apply sets the recommended requests, within the bounds of the policy,
+// to the kernel container in the template. The recommended limits are not
+// applied, since the peaks of the sampled usage may be lower than the
+// actual ones, and the kernels would be killed by a low memory limit. The
+// requests do not exceed the limits in the template. It returns true if
+// the template is changed.
+func Apply(spec *v1alpha1.JupyterKernelTemplateSpec,
+	r *v1alpha1.KernelResourceRecommendation) bool {
+	if spec.Template == nil || len(spec.Template.Spec.Containers) == 0 || r == nil {
+		return false
+	}
+	c := &spec.Template.Spec.Containers[0]
+	original := c.Resources.DeepCopy()
+	if c.Resources.Requests == nil {
+		c.Resources.Requests = v1.ResourceList{}
+	}
+	for name, q := range r.Requests {
+		q = bound(spec.ResourcePolicy, name, q)
+		if l, ok := c.Resources.Limits[name]; ok && q.Cmp(l) > 0 {
+			q = l
+		}
+		c.Resources.Requests[name] = q
+	}
+	return !equality.Semantic.DeepEqual(original, &c.Resources)
+}
+
+func bound(p *v1alpha1.KernelResourcePolicy, name v1.ResourceName,
+	q resource.Quantity) resource.Quantity {
+	if p == nil {
+		return q
+	}
+	if min, ok := p.MinAllowed[name]; ok && q.Cmp(min) < 0 {
+		return min
+	}
+	if max, ok := p.MaxAllowed[name]; ok && q.Cmp(max) > 0 {
+		return max
+	}
+	return q
+}
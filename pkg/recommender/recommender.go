@@ -0,0 +1,260 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package recommender recommends the resources of the kernel templates
+// from the peak usages of the finished kernels launched from them.
+package recommender
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+// maxPeakUsages is the maximum number of the peak usages kept in the
+// status of a template.
+const maxPeakUsages = 100
+
+const reasonResourcesApplied = "ResourcesApplied"
+
+// Finalizer is the finalizer of the kernels launched from the templates,
+// which keeps the kernels until their peak usage is recorded in the
+// templates.
+const Finalizer = "kubeflow.tkestack.io/peak-usage"
+
+// Options are the options of the recommender.
+type Options struct {
+	// Interval is the interval to sample the usage of the kernels.
+	Interval time.Duration
+	// MinSamples is the minimum number of the finished kernels of a
+	// template to recommend the resources.
+	MinSamples int
+}
+
+// +kubebuilder:rbac:groups=kubeflow.tkestack.io,resources=clusterjupyterkerneltemplates/status,verbs=get;update;patch
+
+// Recommender samples the usage of the running kernels periodically, and
+// records their peak usage in the status. The kernels launched from the
+// templates have the finalizer, thus once a kernel is deleted, the peak
+// usage in its status is added to the status of its template, and the
+// recommendation of the template is updated.
+type Recommender struct {
+	cli      client.Client
+	log      logr.Logger
+	recorder record.EventRecorder
+	source   Source
+	opts     Options
+}
+
+// NewRecommender creates the recommender.
+func NewRecommender(cli client.Client, l logr.Logger, recorder record.EventRecorder,
+	source Source, opts Options) *Recommender {
+	return &Recommender{
+		cli:      cli,
+		log:      l,
+		recorder: recorder,
+		source:   source,
+		opts:     opts,
+	}
+}
+
+// Start implements manager.Runnable.
+func (r *Recommender) Start(stop <-chan struct{}) error {
+	wait.Until(r.Recommend, r.opts.Interval, stop)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the
+// leader records the usage.
+func (r *Recommender) NeedLeaderElection() bool {
+	return true
+}
+
+// Recommend samples the usage of the running kernels once, and updates
+// their peak usage.
+func (r *Recommender) Recommend() {
+	usages, err := r.source.Usage(context.TODO())
+	if err != nil {
+		r.log.Error(err, "Failed to get the usage of the kernels")
+		return
+	}
+	kernels := &v1alpha1.JupyterKernelList{}
+	if err := r.cli.List(context.TODO(), kernels); err != nil {
+		r.log.Error(err, "Failed to list the kernels")
+		return
+	}
+	pods := &v1.PodList{}
+	if err := r.cli.List(context.TODO(), pods, client.HasLabels{kernel.LabelKernel}); err != nil {
+		r.log.Error(err, "Failed to list the kernel pods")
+		return
+	}
+
+	// samples are the usage of the kernel containers by the kernels.
+	samples := map[types.NamespacedName]v1.ResourceList{}
+	for _, pod := range pods.Items {
+		if len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		u, ok := usages[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}]
+		if !ok {
+			continue
+		}
+		key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Labels[kernel.LabelKernel]}
+		samples[key] = max(samples[key], u[pod.Spec.Containers[0].Name])
+	}
+
+	for i := range kernels.Items {
+		k := &kernels.Items[i]
+		if _, ok := Template(k); !ok || k.DeletionTimestamp != nil {
+			continue
+		}
+		key := types.NamespacedName{Namespace: k.Namespace, Name: k.Name}
+		peak := max(k.Status.PeakUsage, samples[key])
+		if len(peak) == 0 || !grows(k.Status.PeakUsage, peak) {
+			continue
+		}
+		k.Status.PeakUsage = peak
+		if err := r.cli.Status().Update(context.TODO(), k); err != nil && !errors.IsConflict(err) {
+			r.log.Error(err, "Failed to update the peak usage of the kernel",
+				"namespace", k.Namespace, "kernel", k.Name)
+		}
+	}
+}
+
+// Template returns the template which the kernel is launched from.
+func Template(k *v1alpha1.JupyterKernel) (types.NamespacedName, bool) {
+	name, ok := k.Annotations[launcher.AnnotationTemplateName]
+	return types.NamespacedName{
+		Namespace: k.Annotations[launcher.AnnotationTemplateNamespace],
+		Name:      name,
+	}, ok
+}
+
+// Record adds the peak usage in the status of the deleted kernel to its
+// template. It is called by the finalizer of the kernel, thus the kernels
+// deleted while the recommender is not running are recorded once it runs
+// again.
+func (r *Recommender) Record(k *v1alpha1.JupyterKernel) error {
+	template, ok := Template(k)
+	if !ok || len(k.Status.PeakUsage) == 0 {
+		return nil
+	}
+	return r.record(template, v1alpha1.KernelPeakUsage{
+		Kernel: types.NamespacedName{Namespace: k.Namespace, Name: k.Name}.String(),
+		Usage:  k.Status.PeakUsage,
+	})
+}
+
+// record adds the peak usage of the finished kernel to the template, and
+// updates the recommendation.
+func (r *Recommender) record(key types.NamespacedName, peak v1alpha1.KernelPeakUsage) error {
+	var obj runtime.Object
+	var spec *v1alpha1.JupyterKernelTemplateSpec
+	var status *v1alpha1.JupyterKernelTemplateStatus
+	if key.Namespace == "" {
+		t := &v1alpha1.ClusterJupyterKernelTemplate{}
+		obj, spec, status = t, &t.Spec, &t.Status
+	} else {
+		t := &v1alpha1.JupyterKernelTemplate{}
+		obj, spec, status = t, &t.Spec, &t.Status
+	}
+	if err := r.cli.Get(context.TODO(), key, obj); err != nil {
+		// The kernels of the deleted templates are not recorded.
+		return client.IgnoreNotFound(err)
+	}
+
+	status.PeakUsages = appendPeakUsage(status.PeakUsages, peak)
+	status.Recommendation = Recommend(status.PeakUsages, r.opts.MinSamples)
+	if status.Recommendation != nil {
+		status.Recommendation.LastUpdateTime = metav1.Now()
+	}
+	if err := r.cli.Status().Update(context.TODO(), obj); err != nil {
+		return err
+	}
+	r.log.Info("Recorded the peak usage of the finished kernel",
+		"template", key, "kernel", peak.Kernel)
+
+	if spec.ResourcePolicy == nil || !spec.ResourcePolicy.AutoApply ||
+		!Apply(spec, status.Recommendation) {
+		return nil
+	}
+	if err := r.cli.Update(context.TODO(), obj); err != nil {
+		return err
+	}
+	r.log.Info("Applied the recommended resources to the template", "template", key)
+	r.recorder.Eventf(obj, v1.EventTypeNormal, reasonResourcesApplied,
+		"Applied the recommended resources from %d finished kernels",
+		status.Recommendation.Samples)
+	return nil
+}
+
+// appendPeakUsage appends the peak usage, which replaces the one of the
+// kernel with the same name, and drops the oldest ones beyond
+// maxPeakUsages.
+func appendPeakUsage(peaks []v1alpha1.KernelPeakUsage,
+	p v1alpha1.KernelPeakUsage) []v1alpha1.KernelPeakUsage {
+	result := make([]v1alpha1.KernelPeakUsage, 0, len(peaks)+1)
+	for _, existing := range peaks {
+		if existing.Kernel != p.Kernel {
+			result = append(result, existing)
+		}
+	}
+	result = append(result, p)
+	if len(result) > maxPeakUsages {
+		result = result[len(result)-maxPeakUsages:]
+	}
+	return result
+}
+
+// max returns the maximum of the recorded resources in the lists.
+func max(l, r v1.ResourceList) v1.ResourceList {
+	result := v1.ResourceList{}
+	for _, name := range resources {
+		a, aok := l[name]
+		b, bok := r[name]
+		switch {
+		case aok && (!bok || a.Cmp(b) >= 0):
+			result[name] = a.DeepCopy()
+		case bok:
+			result[name] = b.DeepCopy()
+		}
+	}
+	return result
+}
+
+// grows returns true if any resource in desired is greater than the one in
+// actual.
+func grows(actual, desired v1.ResourceList) bool {
+	for name, q := range desired {
+		if a, ok := actual[name]; !ok || q.Cmp(a) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tkestack/elastic-jupyter-operator/api/v1alpha1"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+	"github.com/tkestack/elastic-jupyter-operator/pkg/launcher"
+)
+
+// fakeSource returns the usage of the kernel containers by the pods.
+type fakeSource map[types.NamespacedName]v1.ResourceList
+
+func (s fakeSource) Usage(ctx context.Context) (map[types.NamespacedName]Usage, error) {
+	usages := map[types.NamespacedName]Usage{}
+	for key, l := range s {
+		usages[key] = Usage{"kernel": l}
+	}
+	return usages, nil
+}
+
+func usage(cpu, memory string) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func newKernel(name string) (*v1alpha1.JupyterKernel, *v1.Pod) {
+	k := &v1alpha1.JupyterKernel{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			UID:       types.UID(name + "-uid"),
+		},
+	}
+	launcher.SetTemplate(k, "python", "default")
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name + "-pod",
+			Labels:    map[string]string{kernel.LabelKernel: name},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "kernel"}}},
+	}
+	return k, pod
+}
+
+func TestRecommend(t *testing.T) {
+	peaks := []v1alpha1.KernelPeakUsage{}
+	for i := 1; i <= 10; i++ {
+		peaks = append(peaks, v1alpha1.KernelPeakUsage{
+			Kernel: fmt.Sprintf("default/kernel-%d", i),
+			Usage:  usage(fmt.Sprintf("%dm", i*100), "1Gi"),
+		})
+	}
+	if r := Recommend(peaks, 11); r != nil {
+		t.Errorf("Expected no recommendation below the minimum samples, got %v", r)
+	}
+
+	r := Recommend(peaks, 5)
+	if r == nil || r.Samples != 10 {
+		t.Fatalf("Expected the recommendation from 10 samples, got %v", r)
+	}
+	expected := map[string]resource.Quantity{
+		"cpu request":    resource.MustParse("1035m"),
+		"memory request": resource.MustParse("1178Mi"),
+		"memory limit":   resource.MustParse("1178Mi"),
+	}
+	actual := map[string]resource.Quantity{
+		"cpu request":    r.Requests[v1.ResourceCPU],
+		"memory request": r.Requests[v1.ResourceMemory],
+		"memory limit":   r.Limits[v1.ResourceMemory],
+	}
+	for name, q := range expected {
+		if a := actual[name]; a.Cmp(q) != 0 {
+			t.Errorf("Expected the %s %s, got %s", name, q.String(), a.String())
+		}
+	}
+	if _, ok := r.Limits[v1.ResourceCPU]; ok {
+		t.Errorf("Expected no CPU limit")
+	}
+}
+
+func TestRecommender(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	template := &v1alpha1.JupyterKernelTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "python"},
+		Spec: v1alpha1.JupyterKernelTemplateSpec{
+			Template: &v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{
+					Name: "kernel",
+					Resources: v1.ResourceRequirements{
+						Requests: usage("100m", "256Mi"),
+						Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+				}}},
+			},
+			ResourcePolicy: &v1alpha1.KernelResourcePolicy{
+				AutoApply:  true,
+				MinAllowed: usage("500m", "128Mi"),
+				MaxAllowed: usage("4", "2Gi"),
+			},
+		},
+	}
+	a, podA := newKernel("a")
+	b, podB := newKernel("b")
+	cli := fake.NewFakeClientWithScheme(s, template, a, podA, b, podB)
+	source := fakeSource{
+		{Namespace: "default", Name: "a-pod"}: usage("200m", "1Gi"),
+		{Namespace: "default", Name: "b-pod"}: usage("100m", "4Gi"),
+	}
+	r := NewRecommender(cli, logr.Logger(logf.NullLogger{}), record.NewFakeRecorder(10),
+		source, Options{Interval: time.Minute, MinSamples: 2})
+
+	r.Recommend()
+	// The usage drops, but the peaks are kept.
+	source[types.NamespacedName{Namespace: "default", Name: "a-pod"}] = usage("50m", "512Mi")
+	r.Recommend()
+
+	actual := &v1alpha1.JupyterKernel{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "a"}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if q := actual.Status.PeakUsage[v1.ResourceMemory]; q.Cmp(resource.MustParse("1Gi")) != 0 {
+		t.Errorf("Expected the peak memory 1Gi, got %s", q.String())
+	}
+
+	// The kernels are recorded from the peak usage in their status, even
+	// if they are deleted after the recommender restarts.
+	r = NewRecommender(cli, logr.Logger(logf.NullLogger{}), record.NewFakeRecorder(10),
+		fakeSource{}, Options{Interval: time.Minute, MinSamples: 2})
+	for _, name := range []string{"a", "b"} {
+		k := &v1alpha1.JupyterKernel{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, k); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Record(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cli.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "python"}, template); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(template.Status.PeakUsages); n != 2 {
+		t.Fatalf("Expected the peak usages of 2 kernels, got %d", n)
+	}
+	if template.Status.Recommendation == nil {
+		t.Fatalf("Expected the recommendation")
+	}
+	// The requests are bounded by the policy and the limits, and the
+	// limits are kept.
+	resources := template.Spec.Template.Spec.Containers[0].Resources
+	expected := map[string]resource.Quantity{
+		"cpu request":    resource.MustParse("500m"),
+		"memory request": resource.MustParse("1Gi"),
+		"memory limit":   resource.MustParse("1Gi"),
+	}
+	applied := map[string]resource.Quantity{
+		"cpu request":    resources.Requests[v1.ResourceCPU],
+		"memory request": resources.Requests[v1.ResourceMemory],
+		"memory limit":   resources.Limits[v1.ResourceMemory],
+	}
+	for name, q := range expected {
+		if a := applied[name]; a.Cmp(q) != 0 {
+			t.Errorf("Expected the applied %s %s, got %s", name, q.String(), a.String())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Tencent is pleased to support the open source community by making TKEStack
+// available.
+//
+// Copyright (C) 2012-2020 Tencent. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+// this file except in compliance with the License. You may obtain a copy of the
+// License at
+//
+// https://opensource.org/licenses/Apache-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recommender
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/tkestack/elastic-jupyter-operator/pkg/kernel"
+)
+
+// Usage is the current usage of the containers, keyed by the names.
+type Usage map[string]v1.ResourceList
+
+// Source returns the current usage of the kernel pods.
+type Source interface {
+	Usage(ctx context.Context) (map[types.NamespacedName]Usage, error)
+}
+
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
+
+// MetricsSource reads the usage from the resource metrics API
+// (metrics.k8s.io), which is served by the metrics server.
+type MetricsSource struct {
+	// reader reads the API directly, since the metrics cannot be watched
+	// by the cache.
+	reader client.Reader
+}
+
+// NewMetricsSource creates the source of the resource metrics API.
+func NewMetricsSource(reader client.Reader) *MetricsSource {
+	return &MetricsSource{reader: reader}
+}
+
+var podMetricsList = schema.GroupVersionKind{
+	Group:   "metrics.k8s.io",
+	Version: "v1beta1",
+	Kind:    "PodMetricsList",
+}
+
+// Usage implements Source.
+func (s *MetricsSource) Usage(ctx context.Context) (map[types.NamespacedName]Usage, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(podMetricsList)
+	if err := s.reader.List(ctx, list, client.HasLabels{kernel.LabelKernel}); err != nil {
+		return nil, err
+	}
+
+	usages := make(map[types.NamespacedName]Usage, len(list.Items))
+	for _, item := range list.Items {
+		containers, _, err := unstructured.NestedSlice(item.Object, "containers")
+		if err != nil {
+			return nil, err
+		}
+		u := Usage{}
+		for _, c := range containers {
+			m, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(m, "name")
+			values, _, err := unstructured.NestedStringMap(m, "usage")
+			if err != nil {
+				return nil, err
+			}
+			l := v1.ResourceList{}
+			for k, v := range values {
+				q, err := resource.ParseQuantity(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid usage %s of %s/%s: %v",
+						k, item.GetNamespace(), item.GetName(), err)
+				}
+				l[v1.ResourceName(k)] = q
+			}
+			u[name] = l
+		}
+		usages[types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()}] = u
+	}
+	return usages, nil
+}